@@ -0,0 +1,215 @@
+package latex
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// KindAbstract is a NodeKind of the Abstract node.
+var KindAbstract = ast.NewNodeKind("Abstract")
+
+// Abstract is a block node holding the abstract section of a document: its
+// heading followed by its content. See WithAbstractSection.
+type Abstract struct {
+	ast.BaseBlock
+}
+
+// Dump implements Node.Dump.
+func (n *Abstract) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// Kind implements Node.Kind.
+func (n *Abstract) Kind() ast.NodeKind {
+	return KindAbstract
+}
+
+// NewAbstract returns a new Abstract node.
+func NewAbstract() *Abstract {
+	return &Abstract{}
+}
+
+// isAbstractHeading reports whether the heading introduces the abstract,
+// i.e. its text is "Abstract" or it has the "abstract" class.
+func isAbstractHeading(n *ast.Heading, source []byte) bool {
+	if hasClass(n, "abstract") {
+		return true
+	}
+	return strings.EqualFold(string(bytes.TrimSpace(plainText(n, source))), "abstract")
+}
+
+// hasClass reports whether the node's class attribute contains class.
+func hasClass(n ast.Node, class string) bool {
+	classes, ok := attributeString(n, "class")
+	if !ok {
+		return false
+	}
+	for _, c := range strings.Fields(classes) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// WithAbstractSection typesets the top level section whose heading is
+// "Abstract", or has the abstract class, as the abstract of the document in
+// the front matter, unless the metadata has an abstract. The heading is
+// dropped. The section is found by the AbstractSections syntax, which
+// Extension adds with this option.
+func WithAbstractSection(abstract bool) Option {
+	return func(r *Renderer) {
+		r.AbstractSection = abstract
+	}
+}
+
+type abstractTransformer struct{}
+
+// NewAbstractTransformer returns a parser.ASTTransformer wrapping the first
+// top level abstract section of documents, from its heading to the next
+// heading of the same or a higher level, in an Abstract node.
+func NewAbstractTransformer() parser.ASTTransformer {
+	return &abstractTransformer{}
+}
+
+func (t *abstractTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	for c := doc.FirstChild(); c != nil; c = c.NextSibling() {
+		heading, ok := c.(*ast.Heading)
+		if !ok || !isAbstractHeading(heading, source) {
+			continue
+		}
+		abstract := NewAbstract()
+		doc.InsertBefore(doc, heading, abstract)
+		for s := ast.Node(heading); s != nil; {
+			if h, ok := s.(*ast.Heading); ok && h != heading && h.Level <= heading.Level {
+				break
+			}
+			next := s.NextSibling()
+			abstract.AppendChild(abstract, s)
+			s = next
+		}
+		return
+	}
+}
+
+type abstractSections struct{}
+
+// AbstractSections is a goldmark extender finding the abstract section of
+// documents, see WithAbstractSection.
+var AbstractSections goldmark.Extender = &abstractSections{}
+
+func (e *abstractSections) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithASTTransformers(util.Prioritized(NewAbstractTransformer(), 500)))
+}
+
+// documentAbstract returns the abstract section of the document, nil if it
+// has none.
+func documentAbstract(doc ast.Node) *Abstract {
+	for c := doc.FirstChild(); c != nil; c = c.NextSibling() {
+		if abstract, ok := c.(*Abstract); ok {
+			return abstract
+		}
+	}
+	return nil
+}
+
+// writeSectionAbstract writes the content of the abstract section as the
+// abstract, the section being skipped where it stands.
+func (r *Renderer) writeSectionAbstract(w util.BufWriter, source []byte, abstract *Abstract) error {
+	r.abstract = abstract
+	r.writeAbstractStart(w)
+	if err := r.renderSiblings(w, source, abstract.FirstChild().NextSibling()); err != nil {
+		return err
+	}
+	r.writeAbstractEnd(w)
+	return nil
+}
+
+// renderAbstract skips the abstract section typeset in the front matter and
+// renders the others as the sections they are.
+func (r *Renderer) renderAbstract(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if node == r.abstract {
+		return ast.WalkSkipChildren, nil
+	}
+	return ast.WalkContinue, nil
+}
+
+// writeAbstractStart opens the abstract: an abstract environment or, in
+// documents divided into matters, an unnumbered chapter, since the book class
+// has no abstract environment.
+func (r *Renderer) writeAbstractStart(w util.BufWriter) {
+	if r.Matters {
+		_, _ = w.WriteString("\n\\chapter*{Abstract}\n\\addcontentsline{toc}{chapter}{Abstract}\n")
+		return
+	}
+	_, _ = w.WriteString("\n\\begin{abstract}\n")
+}
+
+// writeAbstractEnd closes the abstract.
+func (r *Renderer) writeAbstractEnd(w util.BufWriter) {
+	if !r.Matters {
+		_, _ = w.WriteString("\\end{abstract}\n")
+	}
+}
+
+// writeMetaAbstract writes the abstract found in metadata, if any, with
+// blank lines separating paragraphs.
+func (r *Renderer) writeMetaAbstract(w util.BufWriter, meta map[string]any) bool {
+	abstract, ok := metaString(meta, "abstract")
+	if !ok {
+		return false
+	}
+	r.writeAbstractStart(w)
+	r.Writer.Write(w, []byte(strings.TrimSpace(abstract)))
+	_ = w.WriteByte('\n')
+	r.writeAbstractEnd(w)
+	return true
+}
+
+// WithKeywordsCommand sets the command used to typeset the keywords listed in
+// metadata, e.g. "keywords" emits \keywords{...}. By default a bold
+// "Keywords:" line is written after the abstract.
+func WithKeywordsCommand(command string) Option {
+	return func(r *Renderer) {
+		r.KeywordsCommand = command
+	}
+}
+
+// metaKeywords returns the keywords listed under the "keywords" key, either as
+// a list or as a comma separated string.
+func metaKeywords(meta map[string]any) []string {
+	var keywords []string
+	for _, v := range metaList(meta["keywords"]) {
+		for _, k := range strings.Split(toString(v), ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				keywords = append(keywords, k)
+			}
+		}
+	}
+	return keywords
+}
+
+func (r *Renderer) writeKeywords(w util.BufWriter, keywords []string) {
+	if len(keywords) == 0 {
+		return
+	}
+	if r.KeywordsCommand != "" {
+		_, _ = w.WriteString("\n\\")
+		_, _ = w.WriteString(r.KeywordsCommand)
+		_ = w.WriteByte('{')
+	} else {
+		_, _ = w.WriteString("\n\\noindent\\textbf{Keywords:} ")
+	}
+	r.Writer.Write(w, []byte(strings.Join(keywords, ", ")))
+	if r.KeywordsCommand != "" {
+		_ = w.WriteByte('}')
+	}
+	_ = w.WriteByte('\n')
+}
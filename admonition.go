@@ -0,0 +1,292 @@
+package latex
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// admonitions lists the admonition types, those of GitHub alerts, with their
+// title, color and fontawesome5 icon.
+var admonitions = []struct {
+	name, title, color, icon string
+}{
+	{"note", "Note", "0969DA", "\\faInfoCircle"},
+	{"tip", "Tip", "1A7F37", "\\faLightbulb"},
+	{"important", "Important", "8250DF", "\\faCommentAlt"},
+	{"warning", "Warning", "9A6700", "\\faExclamationTriangle"},
+	{"caution", "Caution", "CF222E", "\\faExclamationCircle"},
+}
+
+// admonitionIndex maps admonition types to their index in admonitions.
+var admonitionIndex = func() map[string]int {
+	m := make(map[string]int, len(admonitions))
+	for i, a := range admonitions {
+		m[a.name] = i
+	}
+	return m
+}()
+
+// admonitionAliases maps the other Python-Markdown and MkDocs admonition
+// types to the admonition typesetting them.
+var admonitionAliases = func() map[string]string {
+	m := make(map[string]string)
+	for name, aliases := range map[string][]string{
+		"note":    {"abstract", "summary", "tldr", "info", "todo", "question", "help", "faq", "example", "quote", "cite"},
+		"tip":     {"hint", "success", "check", "done"},
+		"warning": {"attention"},
+		"caution": {"danger", "error", "failure", "fail", "missing", "bug"},
+	} {
+		for _, alias := range aliases {
+			m[alias] = name
+		}
+	}
+	return m
+}()
+
+// admonitionType returns the admonition typesetting the given type, note for
+// unknown types.
+func admonitionType(name string) string {
+	name = strings.ToLower(name)
+	if _, ok := admonitionIndex[name]; ok {
+		return name
+	}
+	if alias, ok := admonitionAliases[name]; ok {
+		return alias
+	}
+	return "note"
+}
+
+// KindAdmonition is the NodeKind of Admonition nodes.
+var KindAdmonition = ast.NewNodeKind("Admonition")
+
+// Admonition is a Python-Markdown admonition, a titled box of indented blocks:
+//
+//	!!! warning "Mind the gap"
+//	    Keep clear of the doors.
+type Admonition struct {
+	ast.BaseBlock
+	AdmonitionType string
+	Title          []byte
+}
+
+// Kind implements ast.Node.Kind.
+func (n *Admonition) Kind() ast.NodeKind {
+	return KindAdmonition
+}
+
+// Dump implements ast.Node.Dump.
+func (n *Admonition) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"AdmonitionType": n.AdmonitionType, "Title": string(n.Title)}, nil)
+}
+
+// NewAdmonition returns a new Admonition node.
+func NewAdmonition(admonitionType string, title []byte) *Admonition {
+	return &Admonition{AdmonitionType: admonitionType, Title: title}
+}
+
+// admonitionStart matches the first line of an admonition, !!! type "title", or
+// of a collapsible MkDocs admonition, ??? or ???+, printed alike.
+var admonitionStart = regexp.MustCompile(`^(?:!!!|\?\?\?\+?)[ \t]+([A-Za-z][\w-]*)(?:[ \t]+"(.*)")?[ \t]*$`)
+
+type admonitionParser struct{}
+
+// NewAdmonitionParser returns a parser of Python-Markdown admonitions, whose
+// content is indented by four spaces.
+func NewAdmonitionParser() parser.BlockParser {
+	return &admonitionParser{}
+}
+
+func (p *admonitionParser) Trigger() []byte {
+	return []byte{'!', '?'}
+}
+
+func (p *admonitionParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	if pc.BlockOffset() < 0 {
+		return nil, parser.NoChildren
+	}
+	m := admonitionStart.FindSubmatch(util.TrimRightSpace(util.TrimLeftSpace(line)))
+	if m == nil {
+		return nil, parser.NoChildren
+	}
+	reader.Advance(segment.Len() - 1)
+	return NewAdmonition(strings.ToLower(string(m[1])), m[2]), parser.HasChildren
+}
+
+func (p *admonitionParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	line, _ := reader.PeekLine()
+	if util.IsBlank(line) {
+		return parser.Continue | parser.HasChildren
+	}
+	if indent, _ := util.IndentWidth(line, reader.LineOffset()); indent < 4 {
+		return parser.Close
+	}
+	pos, padding := util.IndentPosition(line, reader.LineOffset(), 4)
+	reader.AdvanceAndSetPadding(pos, padding)
+	return parser.Continue | parser.HasChildren
+}
+
+func (p *admonitionParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (p *admonitionParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (p *admonitionParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+// renderAdmonition writes an admonition box, titled with the type name when
+// no title is given.
+func (r *Renderer) renderAdmonition(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		writeAdmonitionEnd(w)
+		return ast.WalkContinue, nil
+	}
+	n := node.(*Admonition)
+	title := string(n.Title)
+	if title == "" {
+		title = strings.ToUpper(n.AdmonitionType[:1]) + n.AdmonitionType[1:]
+	}
+	writeAdmonitionStart(w, admonitionType(n.AdmonitionType), r.Writer.EscapeString(title))
+	return ast.WalkContinue, nil
+}
+
+// admonitionClass returns the class of a div typeset as an admonition: the
+// first class with a built-in renderer, if an admonition type.
+func admonitionClass(div *Div) (string, bool) {
+	for _, class := range divClasses(div) {
+		if _, ok := builtinDiv(class, nil); ok { // Only the class is looked up.
+			_, admonition := admonitionIndex[class]
+			return class, admonition || admonitionAliases[class] != ""
+		}
+	}
+	return "", false
+}
+
+// renderAdmonitionDiv writes a div whose class is an admonition type, such as
+// ::: warning, as an admonition titled with its title attribute.
+func renderAdmonitionDiv(w util.BufWriter, writer Writer, div *Div, entering bool) {
+	if !entering {
+		writeAdmonitionEnd(w)
+		return
+	}
+	class, _ := admonitionClass(div)
+	title, _ := attributeString(div, "title")
+	if title == "" {
+		title = strings.ToUpper(class[:1]) + class[1:]
+	}
+	writeAdmonitionStart(w, admonitionType(class), writer.EscapeString(title))
+}
+
+type admonitionExtension struct{}
+
+// Admonitions is a goldmark extension parsing Python-Markdown and MkDocs
+// admonitions, !!! note "Title" followed by indented content, which are
+// typeset as colored boxes like GitHub alerts.
+var Admonitions goldmark.Extender = &admonitionExtension{}
+
+func (e *admonitionExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithBlockParsers(util.Prioritized(NewAdmonitionParser(), 150)))
+}
+
+// callout is the admonition of a blockquote starting with a GitHub alert
+// marker, [!NOTE], or an Obsidian callout marker, [!info]- Title.
+type callout struct {
+	// name is the admonition type as written.
+	name, title string
+	// marker is the first line, which is not rendered.
+	marker text.Segment
+}
+
+// calloutMarker matches the first line of GitHub alerts and Obsidian callouts,
+// the latter optionally folded, with + or -, and titled.
+var calloutMarker = regexp.MustCompile(`^\[!([A-Za-z][\w-]*)\][+-]?(?:[ \t]+(.*))?$`)
+
+// blockquoteCallout returns the callout of a blockquote starting with a callout marker.
+// Unknown types are typeset as notes, like Obsidian does; folding has no print
+// equivalent and is ignored.
+func blockquoteCallout(n ast.Node, source []byte) (callout, bool) {
+	p, ok := n.FirstChild().(*ast.Paragraph)
+	if n.Kind() != ast.KindBlockquote || !ok || p.Lines().Len() == 0 {
+		return callout{}, false
+	}
+	line := p.Lines().At(0)
+	m := calloutMarker.FindSubmatch(bytes.TrimSpace(line.Value(source)))
+	if m == nil {
+		return callout{}, false
+	}
+	return callout{name: strings.ToLower(string(m[1])), title: strings.TrimSpace(string(m[2])), marker: line}, true
+}
+
+// writeStart opens the callout's admonition, titled with the type name when no
+// title is given.
+func (c callout) writeStart(w util.BufWriter, writer Writer) {
+	title := c.title
+	if title == "" {
+		title = strings.ToUpper(c.name[:1]) + c.name[1:]
+	}
+	writeAdmonitionStart(w, admonitionType(c.name), writer.EscapeString(title))
+}
+
+// writeAdmonitionStart opens a colored tcolorbox with the admonition's icon and
+// title, the type's title when empty.
+func writeAdmonitionStart(w util.BufWriter, name, title string) {
+	a := admonitions[admonitionIndex[name]]
+	if title == "" {
+		title = a.title
+	}
+	color := "admonition-" + a.name
+	_, _ = w.WriteString("\n\\begin{tcolorbox}[colback=" + color + "!5!white, colframe=" + color +
+		", title={" + a.icon + "\\ " + title + "}]\n")
+}
+
+func writeAdmonitionEnd(w util.BufWriter) {
+	_, _ = w.WriteString("\\end{tcolorbox}\n")
+}
+
+// usedAdmonitions returns the admonition types used in the documents.
+func usedAdmonitions(docs ...document) map[string]bool {
+	used := make(map[string]bool)
+	for _, doc := range docs {
+		_ = ast.Walk(doc.node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+			if c, ok := blockquoteCallout(n, doc.source); entering && ok {
+				used[admonitionType(c.name)] = true
+			}
+			if n, ok := n.(*Admonition); entering && ok {
+				used[admonitionType(n.AdmonitionType)] = true
+			}
+			if n, ok := n.(*Div); entering && ok {
+				if class, ok := admonitionClass(n); ok {
+					used[admonitionType(class)] = true
+				}
+			}
+			return ast.WalkContinue, nil
+		})
+	}
+	return used
+}
+
+// addAdmonitions loads the packages and defines the colors of the admonitions
+// used in the documents.
+func addAdmonitions(b *PreambleBuilder, docs ...document) {
+	used := usedAdmonitions(docs...)
+	if len(used) == 0 {
+		return
+	}
+	b.AddPackage("xcolor")
+	b.AddPackage("tcolorbox")
+	b.AddPackage("fontawesome5")
+	for _, a := range admonitions {
+		if used[a.name] {
+			b.AddCommand("\\definecolor{admonition-" + a.name + "}{HTML}{" + a.color + "}")
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package latex_test
+
+import (
+	"strings"
+	"testing"
+
+	latex "github.com/dihedron/goldmark-latex"
+)
+
+func TestGitHubAlerts(t *testing.T) {
+	output := convert(t, "> [!WARNING]\n> Mind the *gap*.\n\n> [!note]\n>\n> Second.\n\n> Plain [!NOTE]\n> quote.\n",
+		latex.WithDynamicPreamble(true))
+	for _, want := range []string{
+		"\\begin{tcolorbox}[colback=admonition-warning!5!white, colframe=admonition-warning, title={\\faExclamationTriangle\\ Warning}]\n",
+		"Mind the \\textit{gap}.",
+		"title={\\faInfoCircle\\ Note}]",
+		"\\definecolor{admonition-warning}{HTML}{9A6700}",
+		"\\usepackage{fontawesome5}",
+		"\\begin{quote}",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "WARNING") || strings.Contains(output, "[!note]") {
+		t.Errorf("alert marker rendered:\n%s", output)
+	}
+}
+
+func TestAdmonitions(t *testing.T) {
+	output := convert(t, "!!! danger \"Don't *panic*\"\n    First paragraph.\n\n    - item\n\n??? info\n    Folded.\n\nAfter.\n")
+	for _, want := range []string{
+		"colframe=admonition-caution, title={\\faExclamationCircle\\ Don't *panic*}]\n",
+		"First paragraph.",
+		"\\begin{itemize}",
+		"title={\\faInfoCircle\\ Info}]\n",
+		"Folded.\n",
+		"\\end{tcolorbox}\n",
+		"\\definecolor{admonition-note}{HTML}",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+	if i, j := strings.LastIndex(output, "\\end{tcolorbox}"), strings.Index(output, "After."); i > j {
+		t.Errorf("expected the paragraph after the admonitions:\n%s", output)
+	}
+}
+
+func TestObsidianCallouts(t *testing.T) {
+	output := convert(t, "> [!faq]- Why *not*?\n> Because.\n>\n> > [!danger] Inner\n> > Nested.\n\n> [!custom]\n> Unknown types are notes.\n")
+	for _, want := range []string{
+		"colframe=admonition-note, title={\\faInfoCircle\\ Why *not*?}]\n",
+		"Because.",
+		"colframe=admonition-caution, title={\\faExclamationCircle\\ Inner}]\n",
+		"Nested.\n",
+		"title={\\faInfoCircle\\ Custom}]",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+	if strings.Count(output, "\\end{tcolorbox}") != 3 || strings.Contains(output, "[!") {
+		t.Errorf("unexpected callouts:\n%s", output)
+	}
+}
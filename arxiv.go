@@ -0,0 +1,80 @@
+package latex
+
+import (
+	"io"
+)
+
+// ArXiv is the preset of arXiv submissions, which arXiv's TeX Live compiles with
+// pdflatex and without -shell-escape: code is typeset with listings instead of
+// minted and the PDF is reproducible, without creation date nor \today as
+// default date. Use WriteArXiv to lay out the files.
+var ArXiv = Preset{
+	Name: "arxiv",
+	Preamble: []byte(`\pdfoutput=1
+\documentclass{article}
+
+\usepackage[utf8]{inputenc}
+\usepackage[T1]{fontenc}
+\usepackage{lmodern}
+\usepackage{graphicx}
+\usepackage{amsmath}
+\usepackage{amssymb}
+\usepackage{xcolor}
+\usepackage{listings}
+
+\lstset{
+  basicstyle=\ttfamily\small,
+  breaklines=true,
+  columns=fullflexible,
+  keepspaces=true,
+  showstringspaces=false,
+  frame=single,
+}
+
+% Reproducible PDF.
+\pdfinfoomitdate=1
+\pdftrailerid{}
+\pdfsuppressptexinfo=-1
+`),
+	Build: func(b *PreambleBuilder, writer Writer, meta map[string]any) {
+		if _, ok := metaString(meta, "date"); !ok {
+			b.AddCommand("\\date{}")
+		}
+	},
+	Options: []Option{WithMakeTitle(true), WithCodeEngine(Listings)},
+}
+
+// arXivReadme is the arXiv processing configuration naming the top level file.
+const arXivReadme = `{
+  "process": {"compiler": "pdflatex"},
+  "sources": [{"filename": "main.tex", "usage": "toplevel"}]
+}
+`
+
+// WriteArXiv writes the files of an arXiv submission of a document rendered with
+// the ArXiv preset, created by create: the document as main.tex, and the
+// 00README.json file telling arXiv's compiler to typeset it with pdflatex.
+// The files of a document split with Split must be written next to them. arXiv
+// does not run BibTeX: the .bbl file of a bibliography must be added.
+func WriteArXiv(output []byte, create func(name string) (io.WriteCloser, error)) error {
+	for _, f := range []struct {
+		name    string
+		content []byte
+	}{
+		{"main.tex", output},
+		{"00README.json", []byte(arXivReadme)},
+	} {
+		w, err := create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(f.content); err != nil {
+			w.Close()
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package latex
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/util"
+)
+
+// WithBeamer typesets the document as a beamer slide deck: level 1 headings
+// start sections, level 2 headings start frames titled with the heading and
+// deeper headings start blocks within the frame. Frames holding code are
+// fragile. The title and the table of contents get frames of their own.
+// Content must follow a level 2 heading to be shown. Paragraphs made of
+// ". . ." pause the slide, list items starting with an overlay specification,
+// <2->, or ending with {.fragment} are revealed in turn and ::: notes divs are
+// typeset as speaker notes.
+func WithBeamer(enabled bool) Option {
+	return func(r *Renderer) {
+		r.Beamer = enabled
+	}
+}
+
+// WithIncrementalLists reveals the items of the lists of beamer slides one
+// at a time.
+func WithIncrementalLists(enabled bool) Option {
+	return func(r *Renderer) {
+		r.IncrementalLists = enabled
+	}
+}
+
+// addBeamer sets the beamer class, keeping the class options. The xcolor and
+// hyperref packages, which beamer loads, get their options as class options and
+// geometry, which beamer does not support, is removed.
+func addBeamer(b *PreambleBuilder) {
+	_, options := b.Class()
+	for _, p := range []string{"xcolor", "hyperref"} {
+		if opts, ok := b.removePackage(p); ok && len(opts) > 0 {
+			options = append(options, p+"={"+strings.Join(opts, ",")+"}")
+		}
+	}
+	b.removePackage("geometry")
+	b.SetClass("beamer", options...)
+}
+
+// renderBeamerHeading writes a heading below the section level as the title of
+// a frame, level 1, or of a block.
+func (r *Renderer) renderBeamerHeading(w util.BufWriter, source []byte, n *ast.Heading, level int, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		_, _ = w.WriteString("}\n")
+		return ast.WalkContinue, nil
+	}
+	r.closeBeamerBlock(w)
+	if level > 1 {
+		_, _ = w.WriteString("\n\\begin{block}{")
+		r.beamerBlock = true
+		return ast.WalkContinue, nil
+	}
+	r.closeFrame(w)
+	var options []string
+	if isFragileFrame(n) {
+		options = append(options, "fragile")
+	}
+	if id, ok := attributeString(n, "id"); ok && labelID(id) != "" {
+		options = append(options, "label="+labelID(id))
+	}
+	_, _ = w.WriteString("\n\\begin{frame}")
+	if len(options) > 0 {
+		_, _ = w.WriteString("[" + strings.Join(options, ",") + "]")
+	}
+	_ = w.WriteByte('{')
+	r.frame = true
+	return ast.WalkContinue, nil
+}
+
+// isFragileFrame reports whether the frame of a heading holds verbatim code,
+// which beamer only typesets in fragile frames.
+func isFragileFrame(h *ast.Heading) bool {
+	fragile := false
+	for s := h.NextSibling(); s != nil && !fragile; s = s.NextSibling() {
+		if next, ok := s.(*ast.Heading); ok && next.Level <= h.Level {
+			break
+		}
+		_ = ast.Walk(s, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+			switch n.Kind() {
+			case ast.KindCodeBlock, ast.KindFencedCodeBlock:
+				fragile = true
+				return ast.WalkStop, nil
+			}
+			return ast.WalkContinue, nil
+		})
+	}
+	return fragile
+}
+
+// closeBeamerBlock closes the open block, if any.
+func (r *Renderer) closeBeamerBlock(w util.BufWriter) {
+	if r.beamerBlock {
+		_, _ = w.WriteString("\\end{block}\n")
+		r.beamerBlock = false
+	}
+}
+
+// closeFrame closes the open block and frame, if any.
+func (r *Renderer) closeFrame(w util.BufWriter) {
+	r.closeBeamerBlock(w)
+	if r.frame {
+		_, _ = w.WriteString("\\end{frame}\n")
+		r.frame = false
+	}
+}
+
+// writeFrame writes content on a frame of its own.
+func writeFrame(w util.BufWriter, title, content string) {
+	_, _ = w.WriteString("\\begin{frame}")
+	if title != "" {
+		_, _ = w.WriteString("{" + title + "}")
+	}
+	_, _ = w.WriteString("\n" + content + "\n\\end{frame}\n")
+}
+
+// isPause reports whether a paragraph is a pause marker, ". . .", which
+// reveals the rest of the slide on the next one.
+func isPause(n ast.Node, source []byte) bool {
+	return n.Kind() == ast.KindParagraph && string(bytes.TrimSpace(plainText(n, source))) == ". . ."
+}
+
+var (
+	// overlayMarker matches the overlay specification starting a list item, such as <2->.
+	overlayMarker = regexp.MustCompile(`^<[-+0-9,|. ]+>[ \t]*`)
+	// fragmentMarker matches the fragment class ending a list item.
+	fragmentMarker = regexp.MustCompile(`[ \t]*\{\.fragment\}[ \t]*$`)
+)
+
+// itemOverlay returns the overlay specification of a beamer list item, given
+// at the start of the item, <2->, or as a fragment class at its end,
+// {.fragment}, which reveals the item on the next slide. The markers are
+// not rendered.
+func (r *Renderer) itemOverlay(item ast.Node, source []byte) (string, bool) {
+	block := item.FirstChild()
+	if !r.Beamer || block == nil {
+		return "", false
+	}
+	if first, ok := block.FirstChild().(*ast.Text); ok {
+		if m := overlayMarker.Find(first.Segment.Value(source)); m != nil {
+			if first.Segment.Len() > len(m) {
+				splitText(block, first, first.Segment.Start+len(m))
+			}
+			r.skipText = append(r.skipText, first.Segment)
+			return string(bytes.TrimSpace(m)), true
+		}
+	}
+	if last, ok := block.LastChild().(*ast.Text); ok {
+		if loc := fragmentMarker.FindIndex(last.Segment.Value(source)); loc != nil {
+			if loc[0] > 0 {
+				last = splitText(block, last, last.Segment.Start+loc[0])
+			}
+			r.skipText = append(r.skipText, last.Segment)
+			return "<+->", true
+		}
+	}
+	return "", false
+}
+
+// isNotes reports whether a div holds speaker notes, ::: notes, which are
+// typeset with \note unless rendered otherwise.
+func (r *Renderer) isNotes(div *Div) bool {
+	return containsString(divClasses(div), "notes") && r.DivRenderers["notes"] == nil && r.DivEnvironments["notes"] == ""
+}
+
+// renderNotes writes a speaker notes div with \note.
+func renderNotes(w util.BufWriter, source []byte, div *Div, entering bool) {
+	if entering {
+		_, _ = w.WriteString("\n\\note{")
+	} else {
+		_, _ = w.WriteString("}\n")
+	}
+}
@@ -0,0 +1,49 @@
+package latex
+
+import (
+	"strings"
+
+	"github.com/yuin/goldmark/util"
+)
+
+// metaBibliography returns the BibTeX databases listed under the "bibliography"
+// key, without their .bib extension, and those whose name cannot be written in
+// \bibliography, holding braces, backslashes or commas.
+func metaBibliography(meta map[string]any) (files, unsafe []string) {
+	for _, e := range metaList(meta["bibliography"]) {
+		file := strings.TrimSuffix(strings.TrimSpace(toString(e)), ".bib")
+		switch {
+		case file == "":
+		case !isSafePath(file) || strings.Contains(file, ","):
+			unsafe = append(unsafe, file)
+		default:
+			files = append(files, file)
+		}
+	}
+	return files, unsafe
+}
+
+// writeBibliography writes the bibliography of the databases listed in metadata,
+// styled after the "biblio-style" metadata, the preset's style or plain. The
+// databases and styles whose name cannot be written are skipped.
+func (r *Renderer) writeBibliography(w util.BufWriter, meta map[string]any) {
+	files, unsafe := metaBibliography(meta)
+	for _, file := range unsafe {
+		r.warnAt(nil, nil, -1, DiagnosticUnsafeContent, "bibliography %s skipped, its name cannot be written", file)
+	}
+	if len(files) == 0 {
+		return
+	}
+	style, _ := metaString(meta, "biblio-style")
+	if !isSafePath(style) || strings.Contains(style, ",") {
+		r.warnAt(nil, nil, -1, DiagnosticUnsafeContent, "bibliography style %s skipped, its name cannot be written", style)
+		style = ""
+	}
+	if style == "" && r.Preset != nil {
+		style = r.Preset.BibliographyStyle
+	}
+	if style == "" {
+		style = "plain"
+	}
+	_, _ = w.WriteString("\n\\bibliographystyle{" + style + "}\n\\bibliography{" + strings.Join(files, ",") + "}\n")
+}
@@ -0,0 +1,242 @@
+package latex
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Book assembles markdown files into a single LaTeX book, each file being a chapter.
+// The chapters share a single preamble and their top level headings become chapters
+// of the book class. Links between the files, such as [setup](setup.md#install),
+// are turned into references to the linked chapter or heading.
+type Book struct {
+	// FS the chapter files are read from, the current directory if nil.
+	FS fs.FS
+	// Chapters lists the paths of the markdown files in FS, in order.
+	Chapters []string
+	// Contents describes the structure of the book, with parts and files nested
+	// in chapters, in place of Chapters. See ReadSummary.
+	Contents []Chapter
+	// Parser parses the chapters. If nil, goldmark's default parser is used with
+	// heading attributes and automatic heading IDs, needed to link headings.
+	Parser parser.Parser
+	// Options of the renderer, shared by all chapters. Metadata is taken from
+	// the options and from the first chapter.
+	Options []Option
+}
+
+// Chapter is a markdown file of a book with the files nested in it.
+type Chapter struct {
+	// Path of the markdown file in the book's FS.
+	Path string
+	// Part starts a new part of the book, with this title, before the chapter.
+	Part string
+	// Sections are the files nested in the chapter, whose headings are moved one level down.
+	Sections []Chapter
+}
+
+// bookFile is a chapter, or a file nested in one, of the book being rendered.
+type bookFile struct {
+	path  string
+	part  string
+	level int
+}
+
+// files flattens the contents of the book.
+func (b *Book) files() []bookFile {
+	var files []bookFile
+	for _, name := range b.Chapters {
+		files = append(files, bookFile{path: name})
+	}
+	var walk func(chapters []Chapter, level int)
+	walk = func(chapters []Chapter, level int) {
+		for _, c := range chapters {
+			files = append(files, bookFile{path: c.Path, part: c.Part, level: level})
+			walk(c.Sections, level+1)
+		}
+	}
+	walk(b.Contents, 0)
+	return files
+}
+
+// book is the state of a book being rendered.
+type book struct {
+	docs     []ast.Node
+	sources  [][]byte
+	paths    []string
+	parts    []string
+	chapter  int
+	labelled bool // Set once the chapter label has been written.
+}
+
+// Render renders the book as a single LaTeX document.
+func (b *Book) Render(w io.Writer) error {
+	fsys := b.FS
+	if fsys == nil {
+		fsys = os.DirFS(".")
+	}
+	p := b.Parser
+	if p == nil {
+		p = goldmark.DefaultParser()
+		p.AddOptions(parser.WithHeadingAttribute(), parser.WithAutoHeadingID())
+	}
+	bk := &book{}
+	files := b.files()
+	for _, f := range files {
+		source, err := fs.ReadFile(fsys, f.path)
+		if err != nil {
+			return err
+		}
+		bk.sources = append(bk.sources, source)
+		bk.docs = append(bk.docs, p.Parse(text.NewReader(source)))
+		bk.paths = append(bk.paths, path.Clean(f.path))
+		bk.parts = append(bk.parts, f.part)
+	}
+	if len(bk.docs) == 0 {
+		return nil
+	}
+	r := NewRenderer(b.Options...)
+	// The state of the rendering spans the chapters.
+	r.unshared = true
+	r.Chapters = true
+	r.PreambleBuilders = append(r.PreambleBuilders, func(b *PreambleBuilder) {
+		_, options := b.Class()
+		b.SetClass("book", options...)
+	})
+	r.book = bk
+	defer func() { r.book = nil }()
+	rd := renderer.NewRenderer(renderer.WithNodeRenderers(util.Prioritized(r, 1000)))
+	offset := r.HeadingLevelOffset
+	defer func() { r.HeadingLevelOffset = offset }()
+	for i := range bk.docs {
+		bk.chapter, bk.labelled = i, false
+		r.HeadingLevelOffset = offset + files[i].level
+		if err := rd.Render(w, bk.sources[i], bk.docs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// first reports whether the current chapter is the first one.
+func (b *book) first() bool {
+	return b.chapter == 0
+}
+
+// last reports whether the current chapter is the last one.
+func (b *book) last() bool {
+	return b.chapter == len(b.docs)-1
+}
+
+// source returns the source of all chapters, so that the characters of all of
+// them are declared in the preamble.
+func (b *book) source() []byte {
+	return bytes.Join(b.sources, []byte{'\n'})
+}
+
+// chapterLabel returns the label of the i-th chapter.
+func (b *book) chapterLabel(i int) string {
+	p := strings.TrimSuffix(b.paths[i], path.Ext(b.paths[i]))
+	return "chapter:" + labelID(strings.NewReplacer("/", ":", " ", "-").Replace(p))
+}
+
+// label returns the label of the current chapter, or of the heading with the given
+// ID in the current chapter.
+func (b *book) label(id string) string {
+	if id == "" {
+		return b.chapterLabel(b.chapter)
+	}
+	return b.chapterLabel(b.chapter) + ":" + labelID(id)
+}
+
+// resolve returns the label referenced by a link destination, relative to the
+// current chapter, if it points to a chapter of the book or a heading in it.
+func (b *book) resolve(destination string) (string, bool) {
+	if strings.Contains(destination, "://") || strings.HasPrefix(destination, "mailto:") {
+		return "", false
+	}
+	target, fragment, _ := strings.Cut(destination, "#")
+	chapter := b.chapter
+	if target != "" {
+		target = path.Join(path.Dir(b.paths[b.chapter]), target)
+		chapter = -1
+		for i, p := range b.paths {
+			if p == target {
+				chapter = i
+			}
+		}
+		if chapter < 0 {
+			return "", false
+		}
+	} else if fragment == "" {
+		return "", false
+	}
+	label := b.chapterLabel(chapter)
+	if fragment != "" {
+		label += ":" + labelID(fragment)
+	}
+	return label, true
+}
+
+// writeBookPart starts the part beginning with the current chapter, if any.
+func (r *Renderer) writeBookPart(w util.BufWriter) {
+	if b := r.book; b != nil && b.parts[b.chapter] != "" {
+		_, _ = w.WriteString("\n\\part{")
+		r.Writer.Write(w, []byte(b.parts[b.chapter]))
+		_, _ = w.WriteString("}\n")
+	}
+}
+
+// writeBookLabels writes the labels of a heading: its ID, if any, and the
+// chapter label after the first top level heading.
+func (r *Renderer) writeBookLabels(w util.BufWriter, n *ast.Heading, level int) {
+	b := r.book
+	if b == nil {
+		return
+	}
+	if level == 0 && !b.labelled {
+		b.labelled = true
+		_, _ = w.WriteString("\\label{" + b.label("") + "}\n")
+	}
+	if id, ok := attributeString(n, "id"); ok && id != "" {
+		_, _ = w.WriteString("\\label{" + b.label(id) + "}\n")
+	}
+}
+
+// WithChapters typesets top level headings as chapters, for the book and report
+// classes, moving the other headings one level down.
+func WithChapters(chapters bool) Option {
+	return func(r *Renderer) {
+		r.Chapters = chapters
+	}
+}
+
+// document is a parsed document with its source.
+type document struct {
+	node   ast.Node
+	source []byte
+}
+
+// documents returns the documents whose content is typeset: all the chapters
+// when rendering a book, the document otherwise.
+func (r *Renderer) documents(doc ast.Node, source []byte) []document {
+	if r.book == nil {
+		return []document{{doc, source}}
+	}
+	docs := make([]document, len(r.book.docs))
+	for i := range docs {
+		docs[i] = document{r.book.docs[i], r.book.sources[i]}
+	}
+	return docs
+}
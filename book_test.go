@@ -0,0 +1,77 @@
+package latex_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	latex "github.com/dihedron/goldmark-latex"
+)
+
+func TestBook(t *testing.T) {
+	book := &latex.Book{
+		FS: fstest.MapFS{
+			"intro.md":       {Data: []byte("# Introduction\n\nSee [the setup](guide/setup.md#install).\n")},
+			"guide/setup.md": {Data: []byte("# Setup\n\n## Install\n\nBack to [intro](../intro.md), café.\n")},
+		},
+		Chapters: []string{"intro.md", "guide/setup.md"},
+		Options: []latex.Option{
+			latex.WithUnicodeCharactersMapping(func(r rune) (string, bool) { return "?", true }),
+		},
+	}
+	var b bytes.Buffer
+	if err := book.Render(&b); err != nil {
+		t.Fatal(err)
+	}
+	output := b.String()
+	for _, expected := range []string{
+		"\\documentclass{book}\n",
+		"\\chapter{Introduction}\n\\label{chapter:intro}\n",
+		"\\hyperref[chapter:guide:setup:install]{the setup}",
+		"\\chapter{Setup}\n\\label{chapter:guide:setup}\n",
+		"\\section{Install}\n\\label{chapter:guide:setup:install}\n",
+		"\\hyperref[chapter:intro]{intro}",
+		"\\DeclareUnicodeCharacter{00e9}",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in output, got:\n%s", expected, output)
+		}
+	}
+	for _, once := range []string{"\\documentclass", "\\begin{document}", "\\end{document}"} {
+		if strings.Count(output, once) != 1 {
+			t.Errorf("expected %s once, got:\n%s", once, output)
+		}
+	}
+}
+
+func TestReadSummary(t *testing.T) {
+	fsys := fstest.MapFS{
+		"src/SUMMARY.md":       {Data: []byte("# Summary\n\n[Preface](preface.md)\n\n# User Guide\n\n- [Install](guide/install.md)\n  - [Linux](guide/linux.md)\n- [Draft]()\n")},
+		"src/preface.md":       {Data: []byte("# Preface\n")},
+		"src/guide/install.md": {Data: []byte("# Install\n")},
+		"src/guide/linux.md":   {Data: []byte("# Linux\n\n## Packages\n")},
+	}
+	contents, err := latex.ReadSummary(fsys, "src/SUMMARY.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(contents) != 2 || contents[0].Path != "src/preface.md" || contents[1].Part != "User Guide" ||
+		len(contents[1].Sections) != 1 || contents[1].Sections[0].Path != "src/guide/linux.md" {
+		t.Fatalf("unexpected contents %+v", contents)
+	}
+	var b bytes.Buffer
+	if err := (&latex.Book{FS: fsys, Contents: contents}).Render(&b); err != nil {
+		t.Fatal(err)
+	}
+	output := b.String()
+	order := []string{"\\chapter{Preface}", "\\part{User Guide}", "\\chapter{Install}", "\\section{Linux}", "\\subsection{Packages}"}
+	last := -1
+	for _, s := range order {
+		i := strings.Index(output, s)
+		if i <= last {
+			t.Fatalf("expected %q after %d, got:\n%s", s, last, output)
+		}
+		last = i
+	}
+}
@@ -0,0 +1,207 @@
+package latex
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// PreambleBuilder assembles a preamble from a document class, packages and raw
+// commands. Packages are loaded once, in the order they are first added, with
+// the options of all the calls adding them. The zero value is an empty preamble
+// without \documentclass.
+type PreambleBuilder struct {
+	class        string
+	classOptions []string
+	prelude      []string // Lines preceding \documentclass.
+	items        []preambleItem
+}
+
+// preambleItem is either a package or a raw command.
+type preambleItem struct {
+	pkg     string
+	options []string
+	comment string
+	raw     string
+}
+
+// SetClass sets the document class and its options.
+func (b *PreambleBuilder) SetClass(name string, options ...string) {
+	b.class = name
+	b.classOptions = options
+}
+
+// Class returns the document class and its options.
+func (b *PreambleBuilder) Class() (name string, options []string) {
+	return b.class, b.classOptions
+}
+
+// AddPackage loads the package with the given options. When the package has
+// already been added the options missing from the earlier call are appended to it.
+func (b *PreambleBuilder) AddPackage(name string, options ...string) {
+	b.addPackage(name, options, "")
+}
+
+func (b *PreambleBuilder) addPackage(name string, options []string, comment string) {
+	for i := range b.items {
+		item := &b.items[i]
+		if item.pkg != name {
+			continue
+		}
+		for _, option := range options {
+			if !containsString(item.options, option) {
+				item.options = append(item.options, option)
+			}
+		}
+		return
+	}
+	b.items = append(b.items, preambleItem{pkg: name, options: options, comment: comment})
+}
+
+// HasPackage reports whether the package has been added.
+func (b *PreambleBuilder) HasPackage(name string) bool {
+	for _, item := range b.items {
+		if item.pkg == name {
+			return true
+		}
+	}
+	return false
+}
+
+// removePackage removes a package and returns its options.
+func (b *PreambleBuilder) removePackage(name string) ([]string, bool) {
+	for i, item := range b.items {
+		if item.pkg == name {
+			b.items = append(b.items[:i], b.items[i+1:]...)
+			return item.options, true
+		}
+	}
+	return nil, false
+}
+
+// AddCommand appends raw LaTeX, such as a \newcommand definition, to the preamble.
+func (b *PreambleBuilder) AddCommand(raw string) {
+	b.items = append(b.items, preambleItem{raw: strings.TrimSuffix(raw, "\n")})
+}
+
+var (
+	documentClassLine = regexp.MustCompile(`^\s*\\documentclass(?:\[([^\]]*)\])?\{([^}]*)\}\s*(?:%.*)?$`)
+	usePackageLine    = regexp.MustCompile(`^\s*\\usepackage(?:\[([^\]]*)\])?\{([^}]*)\}\s*(%.*)?$`)
+)
+
+// Parse adds the content of a LaTeX preamble: the \documentclass line sets the
+// class, single line \usepackage commands add packages and any other line is
+// added as a raw command. Lines preceding \documentclass are kept before it.
+func (b *PreambleBuilder) Parse(preamble []byte) {
+	hasClass := bytes.Contains(preamble, []byte("\\documentclass"))
+	for _, line := range strings.Split(strings.TrimSuffix(string(preamble), "\n"), "\n") {
+		if hasClass {
+			if m := documentClassLine.FindStringSubmatch(line); m != nil {
+				b.SetClass(m[2], splitOptions(m[1])...)
+				hasClass = false
+			} else {
+				b.prelude = append(b.prelude, line)
+			}
+			continue
+		}
+		if m := usePackageLine.FindStringSubmatch(line); m != nil {
+			b.addPackage(m[2], splitOptions(m[1]), m[3])
+			continue
+		}
+		b.AddCommand(line)
+	}
+}
+
+// splitOptions splits a comma separated option list, ignoring the commas
+// nested in braces.
+func splitOptions(list string) []string {
+	var options []string
+	depth, start := 0, 0
+	for i := 0; i <= len(list); i++ {
+		if i < len(list) {
+			switch list[i] {
+			case '{':
+				depth++
+			case '}':
+				depth--
+			}
+			if list[i] != ',' || depth > 0 {
+				continue
+			}
+		}
+		if option := strings.TrimSpace(list[start:i]); option != "" {
+			options = append(options, option)
+		}
+		start = i + 1
+	}
+	return options
+}
+
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// writePrelude writes the lines which precede \documentclass.
+func (b *PreambleBuilder) writePrelude(w io.Writer) {
+	for _, line := range b.prelude {
+		_, _ = io.WriteString(w, line+"\n")
+	}
+}
+
+// writeClass writes the \documentclass line, if any.
+func (b *PreambleBuilder) writeClass(w io.Writer) {
+	if b.class == "" {
+		return
+	}
+	_, _ = io.WriteString(w, "\\documentclass")
+	if len(b.classOptions) > 0 {
+		_, _ = io.WriteString(w, "["+strings.Join(b.classOptions, ",")+"]")
+	}
+	_, _ = io.WriteString(w, "{"+b.class+"}\n")
+}
+
+// writeBody writes the packages and commands following \documentclass.
+func (b *PreambleBuilder) writeBody(w io.Writer) {
+	for _, item := range b.items {
+		if item.pkg == "" {
+			_, _ = io.WriteString(w, item.raw+"\n")
+			continue
+		}
+		_, _ = io.WriteString(w, "\\usepackage")
+		if len(item.options) > 0 {
+			_, _ = io.WriteString(w, "["+strings.Join(item.options, ",")+"]")
+		}
+		_, _ = io.WriteString(w, "{"+item.pkg+"}")
+		if item.comment != "" {
+			_, _ = io.WriteString(w, " "+item.comment)
+		}
+		_, _ = io.WriteString(w, "\n")
+	}
+}
+
+// Bytes returns the assembled preamble.
+func (b *PreambleBuilder) Bytes() []byte {
+	var buf bytes.Buffer
+	b.writePrelude(&buf)
+	b.writeClass(&buf)
+	b.writeBody(&buf)
+	return buf.Bytes()
+}
+
+// WithPreambleBuilder registers a function contributing packages and commands to
+// the preamble, e.g. b.AddPackage("xcolor", "table"). Contributions are added
+// after the default, dynamic or custom preamble and the lines generated from the
+// other options, but before the languages, which come last for bidi; packages
+// already loaded get the new options instead of being loaded twice. The option
+// can be given more than once.
+func WithPreambleBuilder(fn func(b *PreambleBuilder)) Option {
+	return func(r *Renderer) {
+		r.PreambleBuilders = append(r.PreambleBuilders, fn)
+	}
+}
@@ -0,0 +1,41 @@
+package latex
+
+// ArticleDefaults returns the options of a typical article: a title, a
+// preamble loading only the packages the document needs on A4 paper, and
+// numbered equations. Options given after them override them, e.g.
+//
+//	latex.New(append(latex.ArticleDefaults(), latex.WithGeometry("letter", "1in"))...)
+func ArticleDefaults() []Option {
+	return []Option{
+		WithMakeTitle(true),
+		WithDynamicPreamble(true),
+		WithGeometry("a4", "2.5cm"),
+		WithNumberedEquations(true),
+	}
+}
+
+// TechnicalDocDefaults returns the options of technical documentation: a
+// title and table of contents, code typeset with listings, which does not
+// need -shell-escape, floats placed where they fit best and comments about
+// unsupported content only.
+func TechnicalDocDefaults() []Option {
+	return []Option{
+		WithMakeTitle(true),
+		WithTableOfContents(true),
+		WithDynamicPreamble(true),
+		WithCodeEngine(Listings),
+		WithFloatPlacement("htbp"),
+		WithComments(CommentsWarnings),
+	}
+}
+
+// PrintFriendly returns the options of documents meant for paper: the URLs of
+// links are written in footnotes and links are not colored.
+func PrintFriendly() []Option {
+	return []Option{
+		WithLinkFootnotes(true),
+		WithPreambleBuilder(func(b *PreambleBuilder) {
+			b.AddCommand("\\makeatletter\n\\AtBeginDocument{\\@ifpackageloaded{hyperref}{\\hypersetup{hidelinks}}{}}\n\\makeatother")
+		}),
+	}
+}
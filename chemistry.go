@@ -0,0 +1,165 @@
+package latex
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// KindChemistry is the NodeKind of Chemistry nodes.
+var KindChemistry = ast.NewNodeKind("Chemistry")
+
+// Chemistry is an inline chemical formula or equation in mhchem syntax, e.g. \ce{H2O}.
+type Chemistry struct {
+	ast.BaseInline
+	Formula []byte
+}
+
+// Kind implements ast.Node.Kind.
+func (n *Chemistry) Kind() ast.NodeKind {
+	return KindChemistry
+}
+
+// Dump implements ast.Node.Dump.
+func (n *Chemistry) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Formula": string(n.Formula)}, nil)
+}
+
+// NewChemistry returns a new Chemistry node.
+func NewChemistry(formula []byte) *Chemistry {
+	return &Chemistry{Formula: formula}
+}
+
+// KindChemistryBlock is the NodeKind of ChemistryBlock nodes.
+var KindChemistryBlock = ast.NewNodeKind("ChemistryBlock")
+
+// ChemistryBlock is a block of chemical equations in mhchem syntax, one per line.
+type ChemistryBlock struct {
+	ast.BaseBlock
+}
+
+// Kind implements ast.Node.Kind.
+func (n *ChemistryBlock) Kind() ast.NodeKind {
+	return KindChemistryBlock
+}
+
+// IsRaw implements ast.Node.IsRaw.
+func (n *ChemistryBlock) IsRaw() bool {
+	return true
+}
+
+// Dump implements ast.Node.Dump.
+func (n *ChemistryBlock) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// NewChemistryBlock returns a new ChemistryBlock node.
+func NewChemistryBlock() *ChemistryBlock {
+	return &ChemistryBlock{}
+}
+
+var chemistryPrefix = []byte("\\ce{")
+
+type chemistryParser struct{}
+
+// NewChemistryParser returns a parser of \ce{...} formulas.
+func NewChemistryParser() parser.InlineParser {
+	return &chemistryParser{}
+}
+
+func (p *chemistryParser) Trigger() []byte {
+	return []byte{'\\'}
+}
+
+func (p *chemistryParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	if !bytes.HasPrefix(line, chemistryPrefix) {
+		return nil
+	}
+	depth := 1
+	for i := len(chemistryPrefix); i < len(line); i++ {
+		switch line[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		}
+		if depth == 0 {
+			block.Advance(i + 1)
+			return NewChemistry(line[len(chemistryPrefix):i])
+		}
+	}
+	return nil
+}
+
+type chemistryTransformer struct{}
+
+// NewChemistryTransformer returns a transformer replacing fenced code blocks
+// of the chem and mhchem languages with chemistry blocks.
+func NewChemistryTransformer() parser.ASTTransformer {
+	return &chemistryTransformer{}
+}
+
+func (t *chemistryTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	var blocks []*ast.FencedCodeBlock
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if code, ok := n.(*ast.FencedCodeBlock); ok && entering {
+			switch string(code.Language(reader.Source())) {
+			case "chem", "mhchem":
+				blocks = append(blocks, code)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	for _, code := range blocks {
+		chem := NewChemistryBlock()
+		chem.SetLines(code.Lines())
+		code.Parent().ReplaceChild(code.Parent(), code, chem)
+	}
+}
+
+// renderChemistry writes a formula with mhchem's \ce.
+func (r *Renderer) renderChemistry(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString("\\ce{")
+		_, _ = w.Write(node.(*Chemistry).Formula)
+		_ = w.WriteByte('}')
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+// renderChemistryBlock writes each line of the block as a displayed equation.
+func (r *Renderer) renderChemistryBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkSkipChildren, nil
+	}
+	lines := node.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		if value := bytes.TrimSpace(line.Value(source)); len(value) > 0 {
+			_, _ = w.WriteString("\n\\[\\ce{")
+			_, _ = w.Write(value)
+			_, _ = w.WriteString("}\\]\n")
+		}
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+type chemistryExtension struct{}
+
+// MHChem is a goldmark extension parsing chemical formulas and equations,
+// written as \ce{2H2 + O2 -> 2H2O} in text and in chem fenced code blocks,
+// which are typeset with the mhchem package. The package is loaded in the
+// preamble when the document contains chemistry.
+var MHChem goldmark.Extender = &chemistryExtension{}
+
+func (e *chemistryExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithInlineParsers(util.Prioritized(NewChemistryParser(), 500)),
+		parser.WithASTTransformers(util.Prioritized(NewChemistryTransformer(), 500)),
+	)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// watchInterval is the interval between the checks of watched files.
+const watchInterval = time.Second
+
+// isMarkdown reports whether a file is a markdown file to convert.
+func isMarkdown(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".md", ".markdown":
+		return true
+	}
+	return false
+}
+
+// sourceFiles returns the modification times of the files of a directory tree
+// converted by convertDir: the markdown files, and the assets when outputDir
+// is another directory. Hidden files and outputDir are skipped.
+func sourceFiles(dir, outputDir string) (map[string]time.Time, error) {
+	copyAssets := filepath.Clean(dir) != filepath.Clean(outputDir)
+	files := make(map[string]time.Time)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if copyAssets && filepath.Clean(path) == filepath.Clean(outputDir) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isMarkdown(path) && !copyAssets {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files[path] = info.ModTime()
+		return nil
+	})
+	return files, err
+}
+
+// convertDir converts the markdown files of a directory tree to LaTeX files
+// in outputDir, preserving the structure of the tree, and copies the other
+// files, such as images, when outputDir is another directory. Only the files
+// in changed are processed when it is not nil.
+func convertDir(dir, outputDir string, changed map[string]bool) error {
+	files, err := sourceFiles(dir, outputDir)
+	if err != nil {
+		return err
+	}
+	for path := range files {
+		if changed != nil && !changed[path] {
+			continue
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(outputDir, rel)
+		if err := os.MkdirAll(filepath.Dir(target), 0777); err != nil {
+			return err
+		}
+		if isMarkdown(path) {
+			verb("converting", path)
+			err = convertFile(path, strings.TrimSuffix(target, filepath.Ext(target))+".tex")
+		} else {
+			verb("copying", path)
+			err = copyFile(path, target)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyFile copies the file src to dst.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// watchFiles calls convert with the files changed under root, a file or a
+// directory tree converted to outputDir, whenever they change. Conversion
+// errors, starting with err, are logged without stopping.
+func watchFiles(root, outputDir string, convert func(changed map[string]bool) error, err error) error {
+	if err != nil {
+		log.Println(err)
+	}
+	list := func() (map[string]time.Time, error) {
+		if outputDir == "" {
+			info, err := os.Stat(root)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]time.Time{root: info.ModTime()}, nil
+		}
+		return sourceFiles(root, outputDir)
+	}
+	last, err := list()
+	if err != nil {
+		return err
+	}
+	log.Println("watching", root)
+	for {
+		time.Sleep(watchInterval)
+		files, err := list()
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		changed := make(map[string]bool)
+		for path, modTime := range files {
+			if t, ok := last[path]; !ok || !t.Equal(modTime) {
+				changed[path] = true
+			}
+		}
+		last = files
+		if len(changed) == 0 {
+			continue
+		}
+		verb("changed", len(changed), "files")
+		if err := convert(changed); err != nil {
+			log.Println(err)
+		}
+	}
+}
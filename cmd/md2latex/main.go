@@ -1,7 +1,10 @@
 package main
 
 import (
+	"archive/zip"
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
@@ -13,9 +16,10 @@ import (
 	"time"
 
 	latex "github.com/dihedron/goldmark-latex"
+	"github.com/dihedron/goldmark-latex/compile"
 	"github.com/yuin/goldmark"
-	"github.com/yuin/goldmark/renderer"
-	"github.com/yuin/goldmark/util"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
 )
 
 var (
@@ -24,7 +28,22 @@ var (
 	print            bool
 	unhead           bool
 	unsafe           bool
+	split            bool
+	todo             bool
+	beamer           bool
+	final            bool
+	strict           bool
+	sourceMap        bool
+	watch            bool
+	pdf              bool
+	shellEscape      bool
+	project          string
+	dumpAST          bool
+	trace            bool
 	preambleFilename string
+	presetName       string
+	comments         string
+	configFilename   string
 	outputFilename   string
 	headingOffset    int
 )
@@ -34,9 +53,24 @@ func main() {
 	flag.BoolVar(&usehtml, "html", false, "Output html")
 	flag.BoolVar(&print, "p", false, "Output to stdout")
 	flag.BoolVar(&unsafe, "unsafe", false, "Render unsafe segments of document such as links or verbatim.")
+	flag.BoolVar(&split, "split", false, "Write each top level section to its own file, next to the output file.")
 	flag.BoolVar(&unhead, "unhead", false, "No section numbering")
-	flag.StringVar(&outputFilename, "o", "", "Output filename. By default just adds .tex to input filename.")
+	flag.BoolVar(&beamer, "beamer", false, "Output beamer slides, one frame per level 2 heading.")
+	flag.BoolVar(&todo, "todo", false, "Typeset TODO and FIXME comments and paragraphs as todonotes notes.")
+	flag.BoolVar(&final, "final", false, "Remove TODO and FIXME comments and paragraphs, for final builds.")
+	flag.BoolVar(&strict, "strict", false, "Fail on unsupported or skipped content instead of writing comments.")
+	flag.BoolVar(&sourceMap, "sourcemap", false, "Mark blocks with their markdown line and write the map of output to markdown lines to the output filename with .map.json appended.")
+	flag.BoolVar(&pdf, "pdf", false, "Also compile the output to PDF with latexmk.")
+	flag.BoolVar(&shellEscape, "shell-escape", false, "Compile with shell escape, as minted needs. It lets the document run commands: trusted documents only.")
+	flag.BoolVar(&dumpAST, "ast", false, "Write the parsed markdown tree to standard error.")
+	flag.BoolVar(&trace, "trace", false, "Write the function rendering each node to standard error.")
+	flag.BoolVar(&watch, "watch", false, "Convert the markdown files again whenever they change, until interrupted.")
+	flag.StringVar(&project, "project", "", "Write a compile-ready project, with a latexmkrc, a Makefile and the images and bibliography, to this directory or .zip archive.")
+	flag.StringVar(&outputFilename, "o", "", "Output filename, or output directory when converting a directory. By default just adds .tex to input filename.")
 	flag.StringVar(&preambleFilename, "preamble", "", "Preamble filename. If not set uses a default preamble.")
+	flag.StringVar(&configFilename, "config", "", "JSON configuration file of the renderer, see latex.OptionsFromFile. It overrides the flags.")
+	flag.StringVar(&presetName, "preset", "", "Preset, the house style of a publisher or kind of document: "+strings.Join(latex.PresetNames(), ", ")+".")
+	flag.StringVar(&comments, "comments", "debug", "Comments written to the output: off, warnings or debug.")
 	flag.IntVar(&headingOffset, "headingoffset", 0, "Section heading offset. Can be negative. Results are clipped between 1 and 6.")
 	flag.Parse()
 	args := flag.Args()
@@ -52,10 +86,45 @@ func run(args []string) error {
 	}
 	verb("beginning verbose run")
 	filename := args[0]
-	input, err := readFile(filename)
+	info, err := os.Stat(filename)
 	if err != nil {
 		return err
 	}
+	if project != "" {
+		if info.IsDir() {
+			return errors.New("-project cannot be used with a directory")
+		}
+		return writeProject(filename, project)
+	}
+	if !info.IsDir() {
+		err = convertFile(filename, outputFilename)
+		if watch {
+			return watchFiles(filename, "", func(map[string]bool) error {
+				return convertFile(filename, outputFilename)
+			}, err)
+		}
+		return err
+	}
+	if print {
+		return errors.New("-p cannot be used with a directory")
+	}
+	outputDir := outputFilename
+	if outputDir == "" {
+		outputDir = filename
+	}
+	err = convertDir(filename, outputDir, nil)
+	if watch {
+		return watchFiles(filename, outputDir, func(changed map[string]bool) error {
+			return convertDir(filename, outputDir, changed)
+		}, err)
+	}
+	return err
+}
+
+// convertFile converts a markdown file to a LaTeX file, next to it when
+// outputFilename is empty.
+func convertFile(filename, outputFilename string) error {
+	input, err := readFile(filename)
 	if err != nil {
 		return err
 	}
@@ -64,6 +133,9 @@ func run(args []string) error {
 		return err
 	}
 	if print {
+		if split {
+			return errors.New("-p cannot be used with -split")
+		}
 		fmt.Println(string(output))
 		return nil
 	}
@@ -74,6 +146,32 @@ func run(args []string) error {
 	} else if outputFilename == "" {
 		outputFilename = strings.TrimSuffix(filename, ext) + ".tex"
 	}
+	if sourceMap {
+		if split {
+			return errors.New("-sourcemap cannot be used with -split")
+		}
+		b, err := json.MarshalIndent(latex.SourceMap(output), "", "\t")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(outputFilename+".map.json", b, 0666); err != nil {
+			return err
+		}
+	}
+	if split {
+		prefix := strings.TrimSuffix(filepath.Base(outputFilename), ".tex") + "-"
+		output, err = latex.Split(output, prefix, latex.CreateFiles(filepath.Dir(outputFilename)))
+		if err != nil {
+			return err
+		}
+	}
+	if pdf {
+		// The document is written by the compilation, next to its PDF.
+		verb("compiling", outputFilename)
+		name := strings.TrimSuffix(filepath.Base(outputFilename), ".tex")
+		_, err = compile.Compile(context.Background(), output, compile.Options{Dir: filepath.Dir(outputFilename), Name: name, ShellEscape: shellEscape})
+		return err
+	}
 	outfp, err := os.Create(outputFilename)
 	if err != nil {
 		return err
@@ -84,35 +182,21 @@ func run(args []string) error {
 }
 
 func renderGoldmark(input []byte) ([]byte, error) {
-	var preamble []byte
-	if preambleFilename != "" {
-		b, err := readFile(preambleFilename)
+	md := goldmark.New(goldmark.WithParserOptions(parser.WithHeadingAttribute()))
+	if usehtml {
+		verb("using html renderer")
+	} else {
+		options, err := latexOptions()
 		if err != nil {
 			return nil, err
 		}
-		verb("replacing default preamble with", preambleFilename, "of length", len(b))
-		preamble = b
+		md = latex.New(options...)
+	}
+	if dumpAST {
+		if err := latex.DumpAST(os.Stderr, input, md.Parser().Parse(text.NewReader(input))); err != nil {
+			return nil, err
+		}
 	}
-	var rd renderer.Renderer
-	if usehtml {
-		verb("using html renderer")
-		rd = goldmark.DefaultRenderer()
-	} else {
-		rd = renderer.NewRenderer(
-			renderer.WithNodeRenderers(
-				util.Prioritized(
-					latex.NewRenderer(
-						latex.WithNoHeadingNumbering(unhead),
-						latex.WithRenderUnsafeElements(unsafe),
-						latex.WithPreamble(preamble),
-						latex.WithHeadingLevelOffset(headingOffset),
-					),
-					1000,
-				),
-			),
-		)
-	}
-	md := goldmark.New(goldmark.WithRenderer(rd))
 	var b bytes.Buffer
 	verb("start rendering using goldmark")
 	start := time.Now()
@@ -121,6 +205,94 @@ func renderGoldmark(input []byte) ([]byte, error) {
 	return b.Bytes(), err
 }
 
+// latexOptions returns the renderer options set by the flags.
+func latexOptions() ([]latex.Option, error) {
+	var preamble []byte
+	if preambleFilename != "" {
+		b, err := readFile(preambleFilename)
+		if err != nil {
+			return nil, err
+		}
+		verb("replacing default preamble with", preambleFilename, "of length", len(b))
+		preamble = b
+	}
+	commentLevels := map[string]latex.Comments{"off": latex.CommentsOff, "warnings": latex.CommentsWarnings, "debug": latex.CommentsDebug}
+	commentLevel, ok := commentLevels[comments]
+	if !ok {
+		return nil, fmt.Errorf("unknown comments %q, want off, warnings or debug", comments)
+	}
+	options := []latex.Option{
+		latex.WithNoHeadingNumbering(unhead),
+		latex.WithRenderUnsafeElements(unsafe),
+		latex.WithPreamble(preamble),
+		latex.WithHeadingLevelOffset(headingOffset),
+		latex.WithSplitSections(split),
+		latex.WithBeamer(beamer),
+		latex.WithStrict(strict),
+		latex.WithComments(commentLevel),
+		latex.WithSourceLines(sourceMap),
+	}
+	if presetName != "" {
+		preset, ok := latex.LookupPreset(presetName)
+		if !ok {
+			return nil, fmt.Errorf("unknown preset %q, want one of %s", presetName, strings.Join(latex.PresetNames(), ", "))
+		}
+		verb("using preset", presetName)
+		// The preset comes first so that the flags override its options.
+		options = append([]latex.Option{latex.WithPreset(preset)}, options...)
+	}
+	if verbose {
+		options = append(options, latex.WithLogger(verbLogger{}))
+	}
+	if trace {
+		options = append(options, latex.WithTrace(os.Stderr))
+	}
+	if todo || final {
+		options = append(options, latex.WithTodoNotes(latex.TodoNotes{Markers: true, Strip: final}))
+	}
+	if configFilename != "" {
+		config, err := latex.OptionsFromFile(configFilename)
+		if err != nil {
+			return nil, err
+		}
+		verb("using configuration", configFilename)
+		// The configuration comes last as the flags have default values.
+		options = append(options, config...)
+	}
+	return options, nil
+}
+
+// writeProject writes the project of a markdown file to a zip archive, or to a
+// directory, see latex.WriteProject.
+func writeProject(filename, project string) error {
+	input, err := readFile(filename)
+	if err != nil {
+		return err
+	}
+	options, err := latexOptions()
+	if err != nil {
+		return err
+	}
+	name := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	fsys := os.DirFS(filepath.Dir(filename))
+	if filepath.Ext(project) != ".zip" {
+		return latex.WriteProject(name, input, fsys, latex.CreateFiles(project), options...)
+	}
+	f, err := os.Create(project)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	if err := latex.WriteProject(name, input, fsys, latex.ZipFiles(zw), options...); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
 // Opens, reads and closes file and returns contents.
 func readFile(filename string) ([]byte, error) {
 	verb("opening ", filename)
@@ -140,3 +312,10 @@ func verb(a ...any) {
 		log.Println(a...)
 	}
 }
+
+// verbLogger logs the renderer diagnostics in verbose runs.
+type verbLogger struct{}
+
+func (verbLogger) Warn(msg string, args ...any) {
+	verb(append([]any{"warning:", msg}, args...)...)
+}
@@ -0,0 +1,78 @@
+package latex
+
+import (
+	"sort"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/util"
+)
+
+// CodeEngine is the package typesetting code blocks.
+type CodeEngine int
+
+const (
+	// Minted, the default, highlights code with Pygments, which requires
+	// compiling with -shell-escape.
+	Minted CodeEngine = iota
+	// Listings highlights code in TeX, without external tools.
+	Listings
+)
+
+// String returns the engine's package name.
+func (e CodeEngine) String() string {
+	if e == Listings {
+		return "listings"
+	}
+	return "minted"
+}
+
+// WithCodeEngine sets the package typesetting code blocks.
+func WithCodeEngine(engine CodeEngine) Option {
+	return func(r *Renderer) {
+		r.CodeEngine = engine
+	}
+}
+
+// writeCodeStart opens a code block in the given language, if one of the
+// supported languages; code in other languages is typeset as plain text.
+func (r *Renderer) writeCodeStart(w util.BufWriter, language string) {
+	if _, supported := supportedLang[language]; !supported {
+		language = ""
+	}
+	if r.CodeEngine == Listings {
+		_, _ = w.WriteString("\\begin{lstlisting}")
+		if language != "" {
+			_, _ = w.WriteString("[language=")
+			_, _ = w.WriteString(language)
+			_ = w.WriteByte(']')
+		}
+		return
+	}
+	if language == "" {
+		// Minted requires a language.
+		language = "text"
+	}
+	_, _ = w.WriteString("\\begin{minted}{" + language + "}")
+}
+
+// writeCodeEnd closes a code block.
+func (r *Renderer) writeCodeEnd(w util.BufWriter) {
+	if r.CodeEngine == Listings {
+		_, _ = w.WriteString("\\end{lstlisting}\n")
+		return
+	}
+	_, _ = w.WriteString("\\end{minted}\n")
+}
+
+// usedPackages returns the packages needed to typeset the content of the
+// document, code being typeset with the code engine.
+func (r *Renderer) usedPackages(doc ast.Node, source []byte) []string {
+	packages := usedPackages(r.documents(doc, source)...)
+	for i, p := range packages {
+		if p == "minted" {
+			packages[i] = r.CodeEngine.String()
+		}
+	}
+	sort.Strings(packages)
+	return packages
+}
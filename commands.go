@@ -0,0 +1,173 @@
+package latex
+
+import (
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// WithAllowedLaTeXCommands lets the listed LaTeX commands, e.g. newpage, vspace
+// or index, through when raw LaTeX is not trusted: in raw LaTeX made only of
+// allowed commands and, with the LaTeXCommands extension, in the text. Any other
+// command is still escaped or skipped.
+func WithAllowedLaTeXCommands(commands []string) Option {
+	return func(r *Renderer) {
+		r.AllowedLaTeXCommands = commands
+	}
+}
+
+// isAllowedLaTeX reports whether every command of the LaTeX fragment is allowed
+// and its braces are balanced, so that it cannot close the group it is written
+// in. Control symbols, such as \\ or \%, are always allowed; ^^ notation, which
+// can hide commands, never is.
+func (r *Renderer) isAllowedLaTeX(latex string) bool {
+	if len(r.AllowedLaTeXCommands) == 0 || strings.Contains(latex, "^^") {
+		return false
+	}
+	depth := 0
+	for i := 0; i < len(latex); i++ {
+		switch latex[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth < 0 {
+				return false
+			}
+		case '\\':
+			end := i + 1
+			for end < len(latex) && isLetter(latex[end]) {
+				end++
+			}
+			if end == i+1 {
+				i++ // Control symbol.
+				continue
+			}
+			if !r.isAllowedCommand(latex[i+1 : end]) {
+				return false
+			}
+			i = end - 1
+		}
+	}
+	return depth == 0
+}
+
+// isAllowedCommand reports whether the command, without backslash, is allowed.
+func (r *Renderer) isAllowedCommand(name string) bool {
+	for _, allowed := range r.AllowedLaTeXCommands {
+		if strings.TrimPrefix(allowed, "\\") == name {
+			return true
+		}
+	}
+	return false
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// KindLaTeXCommand is the NodeKind of LaTeXCommand nodes.
+var KindLaTeXCommand = ast.NewNodeKind("LaTeXCommand")
+
+// LaTeXCommand is a LaTeX command written in the text with its arguments,
+// e.g. \vspace{1cm} or \index[idx]{term}.
+type LaTeXCommand struct {
+	ast.BaseInline
+	Command []byte
+}
+
+// Kind implements ast.Node.Kind.
+func (n *LaTeXCommand) Kind() ast.NodeKind {
+	return KindLaTeXCommand
+}
+
+// Dump implements ast.Node.Dump.
+func (n *LaTeXCommand) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Command": string(n.Command)}, nil)
+}
+
+// NewLaTeXCommand returns a new LaTeXCommand node.
+func NewLaTeXCommand(command []byte) *LaTeXCommand {
+	return &LaTeXCommand{Command: command}
+}
+
+type latexCommandParser struct{}
+
+// NewLaTeXCommandParser returns a parser of LaTeX commands followed by their
+// optional and mandatory arguments on the same line.
+func NewLaTeXCommandParser() parser.InlineParser {
+	return &latexCommandParser{}
+}
+
+func (p *latexCommandParser) Trigger() []byte {
+	return []byte{'\\'}
+}
+
+func (p *latexCommandParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	end := 1
+	for end < len(line) && isLetter(line[end]) {
+		end++
+	}
+	if end == 1 {
+		return nil
+	}
+	if end < len(line) && line[end] == '*' {
+		end++
+	}
+	for end < len(line) && (line[end] == '[' || line[end] == '{') {
+		closing := byte(']')
+		if line[end] == '{' {
+			closing = '}'
+		}
+		depth, i := 0, end
+		for ; i < len(line); i++ {
+			if line[i] == '\\' {
+				i++
+				continue
+			}
+			if line[i] == line[end] {
+				depth++
+			} else if line[i] == closing {
+				depth--
+				if depth == 0 {
+					break
+				}
+			}
+		}
+		if i >= len(line) {
+			break // Unbalanced, the argument is text.
+		}
+		end = i + 1
+	}
+	block.Advance(end)
+	return NewLaTeXCommand(line[:end])
+}
+
+// renderLaTeXCommand writes allowed commands verbatim and escapes the others.
+func (r *Renderer) renderLaTeXCommand(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*LaTeXCommand)
+	if r.isAllowedLaTeX(string(n.Command)) {
+		_, _ = w.Write(n.Command)
+	} else {
+		r.Writer.Write(w, n.Command)
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+type latexCommandsExtension struct{}
+
+// LaTeXCommands is a goldmark extension parsing LaTeX commands written in the
+// text, which are kept when allowed with WithAllowedLaTeXCommands and escaped
+// otherwise.
+var LaTeXCommands goldmark.Extender = &latexCommandsExtension{}
+
+func (e *latexCommandsExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithInlineParsers(util.Prioritized(NewLaTeXCommandParser(), 600)))
+}
@@ -0,0 +1,59 @@
+package latex
+
+import (
+	"fmt"
+
+	"github.com/yuin/goldmark/util"
+)
+
+// Comments selects the "% goldmark-latex:" comments written to the output.
+type Comments int
+
+const (
+	// CommentsDebug writes all comments, including the trace of the rendering
+	// of documents, headings and paragraphs. This is the default.
+	CommentsDebug Comments = iota
+	// CommentsWarnings writes the comments about unsupported or skipped content only.
+	CommentsWarnings
+	// CommentsOff writes no comments, for clean production output.
+	CommentsOff
+)
+
+// WithComments selects the comments written to the output, CommentsDebug by
+// default. The markers of WithSplitSections are written regardless.
+func WithComments(comments Comments) Option {
+	return func(r *Renderer) {
+		r.Comments = comments
+	}
+}
+
+// comment writes a comment tracing the rendering.
+func (r *Renderer) comment(w util.BufWriter, format string, args ...any) {
+	if r.Comments == CommentsDebug {
+		writeComment(w, format, args...)
+	}
+}
+
+// warnComment writes a comment about unsupported or skipped content.
+func (r *Renderer) warnComment(w util.BufWriter, format string, args ...any) {
+	if r.Comments != CommentsOff {
+		writeComment(w, format, args...)
+	}
+}
+
+// debugComments reports whether the comments tracing the rendering are
+// written, for callers to skip building their text otherwise.
+func (r *Renderer) debugComments() bool {
+	return r.Comments == CommentsDebug
+}
+
+// writeComment writes a comment, formatting it only if there are arguments.
+func writeComment(w util.BufWriter, format string, args ...any) {
+	_, _ = w.WriteString("% goldmark-latex: ")
+	if len(args) == 0 {
+		_, _ = w.WriteString(format)
+	} else {
+		_, _ = fmt.Fprintf(w, format, args...)
+	}
+	_ = w.WriteByte('\n')
+}
@@ -0,0 +1,101 @@
+package latex
+
+import (
+	"bytes"
+	"reflect"
+	"sync"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Node kinds of the third party goldmark math extensions whose nodes are rendered as math:
+// goldmark-mathjax's InlineMath and MathBlock, and the PassthroughInline and PassthroughBlock
+// nodes of Hugo's passthrough extension, whose content, delimiters included, is written verbatim.
+// Their packages are not imported: the kinds are looked up by name when registering.
+var compatKinds = map[string]func(r *Renderer) renderer.NodeRendererFunc{
+	"InlineMath":        func(r *Renderer) renderer.NodeRendererFunc { return r.renderInlineMath },
+	"MathBlock":         func(r *Renderer) renderer.NodeRendererFunc { return r.renderDisplayMath },
+	"PassthroughInline": func(r *Renderer) renderer.NodeRendererFunc { return r.renderPassthrough },
+	"PassthroughBlock":  func(r *Renderer) renderer.NodeRendererFunc { return r.renderPassthrough },
+}
+
+// registerCompatKinds registers the renderers of the third party node kinds in compatKinds.
+func (r *Renderer) registerCompatKinds(reg renderer.NodeRendererFuncRegisterer) {
+	for i, name := range kindNames() {
+		kind := ast.NodeKind(i)
+		if fn, ok := compatKinds[name]; ok && kind != KindInlineMath && kind != KindDisplayMath {
+			reg.Register(kind, fn(r))
+		}
+	}
+}
+
+var (
+	kindNamesOnce sync.Once
+	kindNamesList []string
+)
+
+// kindNames returns the names of the node kinds, by kind. They are listed
+// once, when the first renderer is set up, extensions creating their kinds
+// when their packages are initialized.
+func kindNames() []string {
+	kindNamesOnce.Do(func() {
+		for kind := ast.NodeKind(0); ; kind++ {
+			name, ok := kindName(kind)
+			if !ok {
+				return
+			}
+			kindNamesList = append(kindNamesList, name)
+		}
+	})
+	return kindNamesList
+}
+
+// kindName returns the name of a node kind, or false if no such kind exists,
+// ast.NodeKind.String panicking then.
+func kindName(kind ast.NodeKind) (name string, ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	return kind.String(), true
+}
+
+// renderPassthrough writes the content of a passthrough node verbatim: the lines of
+// blocks and the Segment field of inlines. Unsafe content, see isSafeMath, is
+// skipped, or written as text for inlines.
+func (r *Renderer) renderPassthrough(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	if node.Type() == ast.TypeBlock {
+		if !r.safeBlock(w, source, node, "passthrough") {
+			return ast.WalkSkipChildren, nil
+		}
+		_ = w.WriteByte('\n')
+		lines := node.Lines()
+		for i := 0; i < lines.Len(); i++ {
+			line := lines.At(i)
+			_, _ = w.Write(bytes.TrimRight(line.Value(source), "\r\n"))
+			_ = w.WriteByte('\n')
+		}
+		return ast.WalkSkipChildren, nil
+	}
+	v := reflect.ValueOf(node)
+	if v.Kind() == reflect.Pointer && v.Elem().Kind() == reflect.Struct {
+		if f := v.Elem().FieldByName("Segment"); f.IsValid() && f.CanInterface() {
+			if segment, ok := f.Interface().(text.Segment); ok {
+				if value := segment.Value(source); r.isSafeMath(value) {
+					_, _ = w.Write(value)
+				} else {
+					r.warn(source, node, DiagnosticUnsafeContent, "passthrough written as text: %s", value)
+					r.Writer.Write(w, value)
+				}
+			}
+		}
+	}
+	return ast.WalkSkipChildren, nil
+}
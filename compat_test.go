@@ -0,0 +1,58 @@
+package latex_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	latex "github.com/dihedron/goldmark-latex"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Nodes mimicking those of goldmark-mathjax and Hugo's passthrough extension.
+var (
+	kindMathBlock         = ast.NewNodeKind("MathBlock")
+	kindPassthroughInline = ast.NewNodeKind("PassthroughInline")
+)
+
+type mathBlock struct{ ast.BaseBlock }
+
+func (n *mathBlock) Kind() ast.NodeKind            { return kindMathBlock }
+func (n *mathBlock) IsRaw() bool                   { return true }
+func (n *mathBlock) Dump(source []byte, level int) { ast.DumpHelper(n, source, level, nil, nil) }
+
+type passthroughInline struct {
+	ast.BaseInline
+	Segment text.Segment
+}
+
+func (n *passthroughInline) Kind() ast.NodeKind { return kindPassthroughInline }
+func (n *passthroughInline) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+func TestCompatKinds(t *testing.T) {
+	source := []byte("x^2\n\\(y_1\\)")
+	doc := ast.NewDocument()
+	block := &mathBlock{}
+	block.Lines().Append(text.NewSegment(0, 4))
+	doc.AppendChild(doc, block)
+	paragraph := ast.NewParagraph()
+	paragraph.AppendChild(paragraph, &passthroughInline{Segment: text.NewSegment(4, len(source))})
+	doc.AppendChild(doc, paragraph)
+
+	r := renderer.NewRenderer(renderer.WithNodeRenderers(util.Prioritized(latex.NewRenderer(), 1000)))
+	var b bytes.Buffer
+	if err := r.Render(&b, source, doc); err != nil {
+		t.Fatal(err)
+	}
+	output := b.String()
+	for _, expected := range []string{"\\[\nx^2\n\\]\n", "\\(y_1\\)"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in output, got:\n%s", expected, output)
+		}
+	}
+}
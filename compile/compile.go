@@ -0,0 +1,164 @@
+// Package compile compiles the LaTeX documents generated by goldmark-latex to
+// PDF with latexmk, tectonic or another command, so that markdown can be
+// turned into PDF in one call.
+package compile
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	latex "github.com/dihedron/goldmark-latex"
+)
+
+// Commands known to Compile, besides custom commands.
+const (
+	// Latexmk runs the TeX engine as many times as needed, with biber or bibtex.
+	Latexmk = "latexmk"
+	// Tectonic is a self-contained engine downloading the packages it needs.
+	Tectonic = "tectonic"
+)
+
+// Options configures the compilation.
+type Options struct {
+	// Command compiling the document, Latexmk when empty, Tectonic, or another
+	// command which is given Args followed by the .tex file and must write the
+	// PDF next to it.
+	Command string
+	Args    []string
+	// Engine run by latexmk.
+	Engine latex.Engine
+	// ShellEscape allows the engine to run external programs, as minted needs.
+	// It is never enabled from the content of documents: a document compiled
+	// with shell escape can run any command, e.g. with \write18, so only set
+	// it for trusted documents.
+	ShellEscape bool
+	// Dir is the directory where the document is written and compiled, so that
+	// its relative image paths are resolved, and where the auxiliary files are
+	// left. A temporary directory is used, and removed, when empty.
+	Dir string
+	// Name of the .tex file, without extension, document when empty.
+	Name string
+}
+
+// Error is the error of a failed compilation, holding its output and log.
+type Error struct {
+	Command string
+	Err     error
+	// Output is the standard and error output of the command.
+	Output []byte
+	// Log is the TeX log file, if any.
+	Log []byte
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("compile: %s: %v", e.Command, e.Err)
+	if first := FirstError(e.Log); first != "" {
+		msg += ": " + first
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// FirstError returns the first error message of a TeX log, such as
+// "Undefined control sequence.", with its line number when known.
+func FirstError(log []byte) string {
+	s := bufio.NewScanner(bytes.NewReader(log))
+	var msg string
+	for s.Scan() {
+		line := s.Text()
+		if msg == "" {
+			if m, ok := strings.CutPrefix(line, "! "); ok {
+				msg = m
+			}
+			continue
+		}
+		if m := logLine.FindStringSubmatch(line); m != nil {
+			return "line " + m[1] + ": " + msg
+		}
+	}
+	return msg
+}
+
+// logLine matches the line of the TeX log locating an error, l.42 ...
+var logLine = regexp.MustCompile(`^l\.(\d+) `)
+
+// Compile compiles a LaTeX document and returns the PDF.
+func Compile(ctx context.Context, tex []byte, options Options) ([]byte, error) {
+	dir := options.Dir
+	if dir == "" {
+		tmp, err := os.MkdirTemp("", "goldmark-latex-")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(tmp)
+		dir = tmp
+	}
+	name := options.Name
+	if name == "" {
+		name = "document"
+	}
+	file := filepath.Join(dir, name+".tex")
+	if err := os.WriteFile(file, tex, 0666); err != nil {
+		return nil, err
+	}
+	command, args := options.command()
+	cmd := exec.CommandContext(ctx, command, append(args, name+".tex")...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		log, _ := os.ReadFile(filepath.Join(dir, name+".log"))
+		return nil, &Error{Command: command, Err: err, Output: output, Log: log}
+	}
+	pdf, err := os.ReadFile(filepath.Join(dir, name+".pdf"))
+	if err != nil {
+		return nil, &Error{Command: command, Err: err, Output: output}
+	}
+	return pdf, nil
+}
+
+// Convert converts markdown to LaTeX with the renderer options, see
+// latex.Convert, and compiles it to PDF.
+func Convert(ctx context.Context, source []byte, options Options, latexOptions ...latex.Option) ([]byte, error) {
+	var tex bytes.Buffer
+	if err := latex.ConvertContext(ctx, source, &tex, latexOptions...); err != nil {
+		return nil, err
+	}
+	return Compile(ctx, tex.Bytes(), options)
+}
+
+// command returns the command line compiling the document, without the file.
+func (o Options) command() (string, []string) {
+	switch o.Command {
+	case "", Latexmk:
+		args := []string{"-interaction=nonstopmode", "-halt-on-error"}
+		switch o.Engine {
+		case latex.XeLaTeX:
+			args = append(args, "-xelatex")
+		case latex.LuaLaTeX:
+			args = append(args, "-lualatex")
+		default:
+			args = append(args, "-pdf")
+		}
+		if o.ShellEscape {
+			args = append(args, "-shell-escape")
+		}
+		return Latexmk, append(args, o.Args...)
+	case Tectonic:
+		args := []string{"--keep-logs"}
+		if o.ShellEscape {
+			args = append(args, "-Z", "shell-escape")
+		}
+		return Tectonic, append(args, o.Args...)
+	}
+	return o.Command, o.Args
+}
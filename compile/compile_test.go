@@ -0,0 +1,55 @@
+package compile_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dihedron/goldmark-latex/compile"
+)
+
+func TestConvert(t *testing.T) {
+	// The "PDF" is a copy of the .tex file.
+	options := compile.Options{Command: "sh", Args: []string{"-c", `cp "$1" "${1%.tex}.pdf"`, "sh"}}
+	pdf, err := compile.Convert(context.Background(), []byte("# Title\n"), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(pdf), "\\section{Title}") {
+		t.Errorf("got %s", pdf)
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	log := "This is pdfTeX\n! Undefined control sequence.\nl.12 \\foo\n"
+	options := compile.Options{Command: "sh", Args: []string{"-c", `printf '` + log + `' > "${1%.tex}.log"; exit 1`, "sh"}}
+	_, err := compile.Compile(context.Background(), []byte("\\foo"), options)
+	var compileErr *compile.Error
+	if !errors.As(err, &compileErr) || !strings.HasSuffix(err.Error(), ": line 12: Undefined control sequence.") {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestNoShellEscapeFromContent(t *testing.T) {
+	// A fake latexmk writes its arguments to the PDF.
+	bin := t.TempDir()
+	script := "#!/bin/sh\nfor last; do :; done\necho \"$@\" > \"${last%.tex}.pdf\"\n"
+	if err := os.WriteFile(filepath.Join(bin, "latexmk"), []byte(script), 0777); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	tex := []byte("\\usepackage{minted}\n\\begin{verbatim}\\usepackage{minted}\\end{verbatim}\n")
+	for _, shellEscape := range []bool{false, true} {
+		pdf, err := compile.Compile(context.Background(), tex, compile.Options{ShellEscape: shellEscape})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := bytes.Contains(pdf, []byte("-shell-escape")); got != shellEscape {
+			t.Errorf("got arguments %q with ShellEscape %v", pdf, shellEscape)
+		}
+	}
+}
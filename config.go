@@ -0,0 +1,225 @@
+package latex
+
+import (
+	"io"
+	"io/fs"
+	"reflect"
+	"sync"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+)
+
+// Config is the configuration of a Renderer, which embeds it, set with
+// options. Its fields can also be set by name with goldmark's
+// renderer.WithOption, e.g. renderer.WithOption("TableOfContents", true).
+type Config struct {
+	// Writes the text of documents, see WithWriter.
+	Writer Writer
+	// Functions rendering node kinds in place of the built-in ones, by kind.
+	NodeRendererOverrides map[ast.NodeKind]renderer.NodeRendererFunc
+	// Functions called around the rendering of nodes, see WithNodeHook.
+	NodeHooks []NodeHook
+	// File system in which images are looked up, see WithImageFS.
+	ImageFS fs.FS
+	// Fail on diagnostics, see WithStrict.
+	Strict bool
+	// Logger of the diagnostics, see WithLogger.
+	Logger Logger
+	// Comments written to the output, see WithComments.
+	Comments Comments
+	// Mark top level blocks with their markdown line, see WithSourceLines.
+	SourceLines bool
+	// Writers the output is also written to, see WithTee.
+	TeeWriters []io.Writer
+	// teeLock serializes the writes to TeeWriters of concurrent renderings.
+	teeLock *sync.Mutex
+	// Writer of the trace of the rendering, see WithTrace.
+	Trace io.Writer
+	// Flush the output after each top level block, see WithStreaming.
+	Streaming bool
+	// Number of goroutines rendering top level sections, see WithParallelSections.
+	ParallelSections int
+	// Limits of the rendering of untrusted documents, nil for none, see WithLimits.
+	Limits *Limits
+	// Increase heading levels: if the offset is 1, \section (1) becomes \subsection (2) etc.
+	// Negative offset is also valid.
+	// Resulting levels are clipped between 1 and 6.
+	HeadingLevelOffset int
+	// Deepest heading level, deeper headings being typeset at this level, or 0.
+	MaxHeadingLevel int
+	// Commands typesetting the heading levels, without backslash, see WithHeadingCommands.
+	HeadingCommands []string
+	// Generates the default preamble from the packages needed by the document.
+	DynamicPreamble bool
+	// Executes the preamble as a text/template with PreambleData.
+	PreambleTemplate bool
+	// Removes section numbering.
+	NoHeadingNumbering bool
+	// Renders the document body only, without preamble and document
+	// environment, to be included in another document with \input.
+	Fragment bool
+	// Raw LaTeX written at the beginning of the document body, after the title
+	// and at the end of the document body.
+	BodyPrefix, AfterTitle, BodySuffix []byte
+	// Replace the default preamble by setting this to a non-nil byte slice.
+	// Should NOT end with \begin{document}, this is added automatically.
+	Preamble []byte
+	// File holding the preamble, read from PreambleFS, or from the operating
+	// system's file system when nil, when the first document is rendered.
+	PreambleFile string
+	PreambleFS   fs.FS
+	// Functions contributing packages and commands to the preamble.
+	PreambleBuilders []func(*PreambleBuilder)
+	// Categories of possibly unsafe content to render, see UnsafeOptions.
+	UnsafeLinks, UnsafeRawLaTeX, UnsafeCodeContent, UnsafeIncludes bool
+	// Prefix of the HTML comments holding raw LaTeX, "latex:" when empty.
+	RawLaTeXPrefix string
+	// LaTeX commands, such as newpage, allowed when raw LaTeX is not trusted.
+	AllowedLaTeXCommands []string
+	// Environments typesetting the fenced and HTML divs of a class, by class name.
+	DivEnvironments map[string]string
+	// Functions rendering the fenced divs of a class, by class name.
+	DivRenderers map[string]DivRenderFunc
+	// Typesetting of TODO and FIXME comments, nil to skip them as other comments.
+	TodoNotes *TodoNotes
+	// Declares all used unicode characters in the preamble
+	// and replaces them with the result of this function.
+	DeclareUnicode func(rune) (raw string, isReplaced bool)
+	// Cache of the unicode declarations of sources, see WithUnicodeCache.
+	UnicodeCache *UnicodeCache
+	// TeX engine targeted by the default preamble.
+	Engine Engine
+	// Document languages, the first being the main one.
+	Languages []string
+	// Sets the main text direction to right to left.
+	RightToLeft bool
+	// Page size and margins, nil to keep the preamble's layout.
+	Geometry *Geometry
+	// Line spacing factor, 0 to keep the preamble's setting.
+	LineSpacing float64
+	// Paragraph separation style.
+	ParagraphStyle ParagraphStyle
+	// Running headers and footers, nil to keep the preamble's page style.
+	Headers *Headers
+	// Page numbering style, nil to keep the document class default.
+	PageNumbering *PageNumbering
+	// Text printed diagonally across pages, such as DRAFT.
+	Watermark string
+	// Text stamped at the bottom of pages, such as CONFIDENTIAL.
+	Stamp string
+	// PDF/A conformance level, such as a-2b, enabling archival output with pdfx.
+	PDFA string
+	// Produces a tagged PDF aiming at PDF/UA accessibility, with alternative
+	// text for images taken from the markdown image description.
+	TaggedPDF bool
+	// Build information, see WithBuildInfo.
+	BuildInfo *BuildInfo
+	// Default document metadata (title, author, date...), overridden by
+	// metadata stored in the document node.
+	Metadata map[string]any
+	// Typesets the abstract section of documents as the abstract, see WithAbstractSection.
+	AbstractSection bool
+	// Command used to typeset metadata keywords, without backslash.
+	// If empty a "Keywords:" line is written after the abstract.
+	KeywordsCommand string
+	// Emits \appendix before the first heading whose text matches this string.
+	// Headings with the "appendix" class or an <!-- appendix --> HTML comment
+	// also start the appendix.
+	AppendixHeading string
+	// Title customization, see WithTitlePage.
+	TitlePage *TitlePage
+	// Inserts a table of contents at the beginning of the document.
+	TableOfContents bool
+	// Order of the title, abstract and table of contents, nil for the default order.
+	FrontMatterOrder []FrontMatterItem
+	// Command typesetting footnotes, without backslash, footnote when empty.
+	FootnoteCommand string
+	// Writes the URLs of links in footnotes, see WithLinkFootnotes.
+	LinkFootnotes bool
+	// Typesets top level headings as chapters, moving the other headings one level down.
+	Chapters bool
+	// Divides the document into front, main and back matter, see WithMatters.
+	Matters bool
+	// Class typesetting the entries of a curriculum vitae, see WithCV.
+	CV string
+	// Style of a publisher or kind of document, see WithPreset.
+	Preset *Preset
+	// Placement of figures and tables, such as !t; h for figures and htbp for
+	// tables when empty.
+	FloatPlacement string
+	// Typesets the figures of the margin class with marginfigure, as defined by the Tufte classes.
+	MarginFigures bool
+	// Writes the captions of tables above them.
+	TableCaptionsAbove bool
+	// Package typesetting code blocks.
+	CodeEngine CodeEngine
+	// Typesets the document as beamer slides, see WithBeamer.
+	Beamer bool
+	// Reveals the items of the lists of beamer slides one at a time.
+	IncrementalLists bool
+	// File system and parser of the files included with <!-- include: path -->, see WithIncludes.
+	IncludeFS     fs.FS
+	IncludeParser parser.Parser
+	// Typesets display math in numbered equation environments.
+	NumberedEquations bool
+	// Marks top level sections so that they can be moved to files of their own, see Split.
+	SplitSections bool
+	// makeTitle determines whether a \maketitle will be injected at the beginning of the document.
+	makeTitle bool
+}
+
+// NewConfig returns a Config with default values.
+func NewConfig() Config {
+	return Config{Writer: DefaultWriter}
+}
+
+// Names of the options understood by SetOption besides the Config field names.
+const (
+	// optUnsafe renders all the categories of possibly unsafe content, as
+	// goldmark's html.WithUnsafe option.
+	optUnsafe renderer.OptionName = "Unsafe"
+	// optOptions holds the Options given to goldmark's renderer.
+	optOptions renderer.OptionName = "LaTeXOptions"
+)
+
+// SetOption implements renderer.SetOptioner: it sets the Config field named
+// name, if value is assignable to it, and understands the Unsafe option of
+// goldmark's HTML renderer. Other options are ignored.
+func (c *Config) SetOption(name renderer.OptionName, value any) {
+	if name == optUnsafe {
+		if unsafe, ok := value.(bool); ok {
+			c.UnsafeLinks, c.UnsafeRawLaTeX, c.UnsafeCodeContent, c.UnsafeIncludes = unsafe, unsafe, unsafe, unsafe
+		}
+		return
+	}
+	field := reflect.ValueOf(c).Elem().FieldByName(string(name))
+	if !field.IsValid() || !field.CanSet() {
+		return
+	}
+	if v := reflect.ValueOf(value); v.IsValid() && v.Type().AssignableTo(field.Type()) {
+		field.Set(v)
+	}
+}
+
+// SetOption implements renderer.SetOptioner, applying the Options given to
+// goldmark's renderer, see Option.SetConfig, and setting the Config fields.
+func (r *Renderer) SetOption(name renderer.OptionName, value any) {
+	if options, ok := value.([]Option); ok && name == optOptions {
+		for _, option := range options {
+			option(r)
+		}
+		return
+	}
+	r.Config.SetOption(name, value)
+}
+
+// SetConfig implements renderer.Option, so that options can be given to
+// goldmark's renderer, e.g. with goldmark.WithRendererOptions. They are applied
+// in order after the options given to NewRenderer, when the first document is
+// rendered.
+func (o Option) SetConfig(c *renderer.Config) {
+	options, _ := c.Options[optOptions].([]Option)
+	c.Options[optOptions] = append(options, o)
+}
@@ -0,0 +1,129 @@
+package latex
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// OptionsFromConfig returns the options setting the fields of cfg which are
+// not zero values, so that they can be combined with other options.
+func OptionsFromConfig(cfg Config) []Option {
+	return configOptions(cfg, func(name string, value reflect.Value) bool {
+		return !value.IsZero()
+	})
+}
+
+// configOptions returns the options setting the exported fields of cfg
+// selected by set, by name and value.
+func configOptions(cfg Config, set func(name string, value reflect.Value) bool) []Option {
+	var options []Option
+	v := reflect.ValueOf(cfg)
+	for i := 0; i < v.NumField(); i++ {
+		i, field := i, v.Field(i)
+		if !v.Type().Field(i).IsExported() || !set(v.Type().Field(i).Name, field) {
+			continue
+		}
+		options = append(options, func(r *Renderer) {
+			reflect.ValueOf(&r.Config).Elem().Field(i).Set(field)
+		})
+	}
+	return options
+}
+
+// OptionsFromFile returns the options of a JSON configuration file, so that
+// build pipelines can change the rendering without recompiling. Its keys are
+// the names of the Config fields holding data, e.g.
+//
+//	{
+//		"Preset": "thesis",
+//		"PreambleFile": "preamble.tex",
+//		"Engine": "xelatex",
+//		"CodeEngine": "listings",
+//		"Geometry": {"Paper": "a4", "Margins": ["2cm"]},
+//		"TableOfContents": true
+//	}
+//
+// Preset is the name of a registered preset, see RegisterPreset, applied
+// before the other fields, which are set even to zero values, e.g.
+// "TableCaptionsAbove": false turns off the setting of the preset. A relative
+// PreambleFile is relative to the directory of the configuration file.
+func OptionsFromFile(path string) ([]Option, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("latex: config: %w", err)
+	}
+	// The preset name shadows the Preset field of Config.
+	var file struct {
+		Config
+		Preset string
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("latex: config %s: %w", path, err)
+	}
+	// The keys set the fields they name, matched as encoding/json does.
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("latex: config %s: %w", path, err)
+	}
+	if file.PreambleFile != "" && !filepath.IsAbs(file.PreambleFile) {
+		file.PreambleFile = filepath.Join(filepath.Dir(path), file.PreambleFile)
+	}
+	var options []Option
+	if file.Preset != "" {
+		preset, ok := LookupPreset(file.Preset)
+		if !ok {
+			return nil, fmt.Errorf("latex: config %s: unknown preset %q", path, file.Preset)
+		}
+		options = append(options, WithPreset(preset))
+	}
+	return append(options, configOptions(file.Config, func(name string, value reflect.Value) bool {
+		if name == "Preset" {
+			return false // Set above.
+		}
+		for key := range keys {
+			if strings.EqualFold(key, name) {
+				return true
+			}
+		}
+		return false
+	})...), nil
+}
+
+// MarshalText returns the engine's command name.
+func (e Engine) MarshalText() ([]byte, error) {
+	return []byte(e.String()), nil
+}
+
+// UnmarshalText sets the engine from its command name, such as xelatex.
+func (e *Engine) UnmarshalText(text []byte) error {
+	for _, engine := range []Engine{PDFLaTeX, XeLaTeX, LuaLaTeX} {
+		if string(text) == engine.String() {
+			*e = engine
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown engine %q", text)
+}
+
+// MarshalText returns the engine's package name.
+func (e CodeEngine) MarshalText() ([]byte, error) {
+	return []byte(e.String()), nil
+}
+
+// UnmarshalText sets the engine from its package name, such as listings.
+func (e *CodeEngine) UnmarshalText(text []byte) error {
+	for _, engine := range []CodeEngine{Minted, Listings} {
+		if string(text) == engine.String() {
+			*e = engine
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown code engine %q", text)
+}
@@ -0,0 +1,47 @@
+package latex
+
+import (
+	"context"
+	"io"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// ConvertContext is like Convert, rendering being aborted with the error of
+// ctx, such as context.DeadlineExceeded, once ctx is done. Cancellation is
+// checked before each block, so that large conversions can be timed out.
+func ConvertContext(ctx context.Context, source []byte, w io.Writer, options ...Option) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	options = append(options[:len(options):len(options)], func(r *Renderer) {
+		r.ctx = ctx
+	})
+	return New(options...).Convert(source, w)
+}
+
+// RenderNodeContext is like RenderNode, rendering being aborted with the
+// error of ctx once ctx is done.
+func (r *Renderer) RenderNodeContext(ctx context.Context, w io.Writer, source []byte, n ast.Node) error {
+	return r.renderNodeContext(ctx, w, source, n)
+}
+
+// contextRegisterer registers node rendering functions checking the
+// cancellation of the rendering context before each block.
+type contextRegisterer struct {
+	renderer.NodeRendererFuncRegisterer
+	r *Renderer
+}
+
+func (c *contextRegisterer) Register(kind ast.NodeKind, fn renderer.NodeRendererFunc) {
+	c.NodeRendererFuncRegisterer.Register(kind, func(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering && c.r.ctx != nil && n.Type() == ast.TypeBlock {
+			if err := c.r.ctx.Err(); err != nil {
+				return ast.WalkStop, err
+			}
+		}
+		return fn(w, source, n, entering)
+	})
+}
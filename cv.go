@@ -0,0 +1,291 @@
+package latex
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/util"
+)
+
+// CV classes supported by WithCV.
+const (
+	ModernCVClass  = "moderncv"
+	AwesomeCVClass = "awesome-cv"
+)
+
+// WithCV typesets the top level lists of a curriculum vitae with the commands
+// of a CV class, ModernCVClass or AwesomeCVClass. The items of a list in which
+// any item has a date attribute at the end of its first line, such as
+//
+//   - **Software Engineer**, ACME, Rome {date="2019--2023"}
+//
+// are entries, \cventry, whose comma separated fields are the title, the
+// employer or institution, the city and the grade, the item's other blocks
+// being the description. The items of a list whose items are all labeled, such
+// as "Languages: Go, Python", are \cvitem, or \cvskill for awesome-cv. Other
+// lists are typeset as usual. Use the ModernCV and AwesomeCV presets.
+func WithCV(class string) Option {
+	return func(r *Renderer) {
+		r.CV = class
+	}
+}
+
+// cvDate matches the date attribute ending the first line of an entry.
+var cvDate = regexp.MustCompile(`\s*\{\s*date\s*=\s*"?([^"}]*)"?\s*\}\s*$`)
+
+// cvItem is an item of a CV list.
+type cvItem struct {
+	// text is the plain text of the item's first block, without the date attribute.
+	text string
+	date string
+	// dated is set when the item has a date attribute.
+	dated bool
+	// description is the first block following the item's first line.
+	description ast.Node
+}
+
+// fields returns the n comma separated fields of the item, the last one
+// holding the remaining fields.
+func (c *cvItem) fields(writer Writer, n int) []string {
+	fields := strings.SplitN(c.text, ",", n)
+	for i := range fields {
+		fields[i] = writer.EscapeString(strings.TrimSpace(fields[i]))
+	}
+	for len(fields) < n {
+		fields = append(fields, "")
+	}
+	return fields
+}
+
+// label returns the label and the value of a "label: value" item.
+func (c *cvItem) label(writer Writer) (label, value string, ok bool) {
+	label, value, ok = strings.Cut(c.text, ":")
+	label, value = strings.TrimSpace(label), strings.TrimSpace(value)
+	return writer.EscapeString(label), writer.EscapeString(value), ok && label != "" && c.description == nil
+}
+
+// cvList returns the items of a top level list of a curriculum vitae and
+// whether they are entries. It returns nil if the list is typeset as usual.
+func (r *Renderer) cvList(n *ast.List, source []byte) (items []cvItem, entries bool) {
+	if r.CV == "" {
+		return nil, false
+	}
+	for p := n.Parent(); p != nil; p = p.Parent() {
+		if p.Kind() == ast.KindListItem {
+			return nil, false
+		}
+	}
+	labeled := true
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		var item cvItem
+		if block := c.FirstChild(); block != nil {
+			text := bytes.TrimSpace(plainText(block, source))
+			if m := cvDate.FindSubmatchIndex(text); m != nil {
+				item.date, item.dated = string(bytes.TrimSpace(text[m[2]:m[3]])), true
+				text = text[:m[0]]
+			}
+			item.text = string(text)
+			item.description = block.NextSibling()
+		}
+		entries = entries || item.dated
+		if _, _, ok := item.label(r.Writer); !ok {
+			labeled = false
+		}
+		items = append(items, item)
+	}
+	if !entries && !labeled {
+		return nil, false
+	}
+	return items, entries
+}
+
+// renderCVList writes the entries or labeled items of a curriculum vitae.
+func (r *Renderer) renderCVList(w util.BufWriter, source []byte, items []cvItem, entries bool) error {
+	awesome := r.CV == AwesomeCVClass
+	switch {
+	case entries && awesome:
+		_, _ = w.WriteString("\n\\begin{cventries}\n")
+	case awesome:
+		_, _ = w.WriteString("\n\\begin{cvskills}\n")
+	default:
+		_ = w.WriteByte('\n')
+	}
+	for _, item := range items {
+		if !entries {
+			label, value, _ := item.label(r.Writer)
+			if awesome {
+				_, _ = w.WriteString("\\cvskill{" + label + "}{" + value + "}\n")
+			} else {
+				_, _ = w.WriteString("\\cvitem{" + label + "}{" + value + "}\n")
+			}
+			continue
+		}
+		date := r.Writer.EscapeString(item.date)
+		if awesome {
+			f := item.fields(r.Writer, 4)
+			organization := f[1]
+			if f[3] != "" {
+				organization += ", " + f[3]
+			}
+			_, _ = w.WriteString("\\cventry{" + f[0] + "}{" + organization + "}{" + f[2] + "}{" + date + "}{")
+		} else {
+			_, _ = w.WriteString("\\cventry{" + date + "}{" + strings.Join(item.fields(r.Writer, 4), "}{") + "}{")
+		}
+		if err := r.renderCVDescription(w, source, item.description); err != nil {
+			return err
+		}
+		_, _ = w.WriteString("}\n")
+	}
+	switch {
+	case entries && awesome:
+		_, _ = w.WriteString("\\end{cventries}\n")
+	case awesome:
+		_, _ = w.WriteString("\\end{cvskills}\n")
+	}
+	return nil
+}
+
+// renderCVDescription writes the description of an entry: its blocks, the
+// lists of awesome-cv descriptions being cvitems environments.
+func (r *Renderer) renderCVDescription(w util.BufWriter, source []byte, first ast.Node) error {
+	if r.CV != AwesomeCVClass {
+		return r.renderSiblings(w, source, first)
+	}
+	for n := first; n != nil; n = n.NextSibling() {
+		if n.Kind() != ast.KindList {
+			if err := r.renderNode(w, source, n); err != nil {
+				return err
+			}
+			continue
+		}
+		_, _ = w.WriteString("\n\\begin{cvitems}\n")
+		for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+			_, _ = w.WriteString("\\item {")
+			if err := r.renderSiblings(w, source, c.FirstChild()); err != nil {
+				return err
+			}
+			_, _ = w.WriteString("}\n")
+		}
+		_, _ = w.WriteString("\\end{cvitems}\n")
+	}
+	return nil
+}
+
+// cvHeading returns the command starting an awesome-cv section or subsection.
+func (r *Renderer) cvHeading(level int) ([]byte, bool) {
+	if r.CV != AwesomeCVClass || level > 1 {
+		return nil, false
+	}
+	if level == 0 {
+		return []byte("\\cvsection{"), true
+	}
+	return []byte("\\cvsubsection{"), true
+}
+
+// cvName returns the first and last name of the person of a curriculum vitae,
+// given by the "name" metadata or the first author.
+func cvName(writer Writer, meta map[string]any) (first, last string) {
+	name, _ := metaString(meta, "name")
+	if authors := metaAuthors(meta); name == "" && len(authors) > 0 {
+		name = authors[0].Name
+	}
+	name = strings.TrimSpace(name)
+	if i := strings.LastIndexByte(name, ' '); i >= 0 {
+		return writer.EscapeString(name[:i]), writer.EscapeString(name[i+1:])
+	}
+	return "", writer.EscapeString(name)
+}
+
+// addCVDetails adds the personal details found in metadata, such as "email" or
+// "github", with the commands of the CV class: commands maps the metadata keys
+// to the commands, whose %s verb is replaced by the escaped value.
+func addCVDetails(b *PreambleBuilder, writer Writer, meta map[string]any, commands [][2]string) {
+	first, last := cvName(writer, meta)
+	b.AddCommand("\\name{" + first + "}{" + last + "}")
+	for _, c := range commands {
+		if value, ok := metaString(meta, c[0]); ok && value != "" {
+			b.AddCommand(strings.Replace(c[1], "%s", writer.EscapeString(value), 1))
+		}
+	}
+}
+
+// ModernCV is the preset of curricula vitae typeset with the moderncv class, in
+// the style and color given by the "cv-style" and "cv-color" metadata, classic
+// and blue by default. The name, from the "name" metadata or the first author,
+// and the "position", "address", "phone", "email", "homepage", "github",
+// "linkedin" and "photo" metadata make the header. See WithCV for the layout of
+// the entries.
+var ModernCV = Preset{
+	Name: "moderncv",
+	Preamble: []byte(`\documentclass[11pt,a4paper,sans]{moderncv}
+
+\usepackage[utf8]{inputenc}
+\usepackage[scale=0.8]{geometry}
+`),
+	Build: func(b *PreambleBuilder, writer Writer, meta map[string]any) {
+		style, _ := metaString(meta, "cv-style")
+		if style == "" {
+			style = "classic"
+		}
+		color, _ := metaString(meta, "cv-color")
+		if color == "" {
+			color = "blue"
+		}
+		b.AddCommand("\\moderncvstyle{" + style + "}")
+		b.AddCommand("\\moderncvcolor{" + color + "}")
+		addCVDetails(b, writer, meta, [][2]string{
+			{"position", "\\title{%s}"},
+			{"address", "\\address{%s}{}{}"},
+			{"phone", "\\phone[mobile]{%s}"},
+			{"email", "\\email{%s}"},
+			{"homepage", "\\homepage{%s}"},
+			{"github", "\\social[github]{%s}"},
+			{"linkedin", "\\social[linkedin]{%s}"},
+			{"photo", "\\photo[64pt][0.4pt]{%s}"},
+		})
+	},
+	Conflicts: []string{"hyperref"},
+	Authors:   func(w util.BufWriter, writer Writer, authors []Author) {},
+	Options: []Option{
+		WithCV(ModernCVClass),
+		WithTitlePage(TitlePage{Template: "\\makecvtitle"}),
+	},
+}
+
+// AwesomeCV is the preset of curricula vitae typeset with the awesome-cv class,
+// in the color given by the "cv-color" metadata, one of the awesome colors
+// such as red, the default, or skyblue. Top level headings are \cvsection and
+// the header is made from the same metadata as ModernCV. See WithCV for the
+// layout of the entries.
+var AwesomeCV = Preset{
+	Name: "awesome-cv",
+	Preamble: []byte(`\documentclass[11pt,a4paper]{awesome-cv}
+
+\geometry{left=1.4cm,top=.8cm,right=1.4cm,bottom=1.8cm,footskip=.5cm}
+`),
+	Build: func(b *PreambleBuilder, writer Writer, meta map[string]any) {
+		color, _ := metaString(meta, "cv-color")
+		if color == "" {
+			color = "red"
+		}
+		b.AddCommand("\\colorlet{awesome}{awesome-" + color + "}")
+		addCVDetails(b, writer, meta, [][2]string{
+			{"position", "\\position{%s}"},
+			{"address", "\\address{%s}"},
+			{"phone", "\\mobile{%s}"},
+			{"email", "\\email{%s}"},
+			{"homepage", "\\homepage{%s}"},
+			{"github", "\\github{%s}"},
+			{"linkedin", "\\linkedin{%s}"},
+			{"photo", "\\photo[circle,noedge,left]{%s}"},
+		})
+	},
+	Conflicts: []string{"geometry", "hyperref", "xcolor"},
+	Authors:   func(w util.BufWriter, writer Writer, authors []Author) {},
+	Options: []Option{
+		WithCV(AwesomeCVClass),
+		WithTitlePage(TitlePage{Template: "\\makecvheader"}),
+	},
+}
@@ -0,0 +1,109 @@
+package latex
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// DumpAST writes the tree of a node parsed from source to w, one node per
+// line with its kind, source position, main properties and attributes, e.g.
+//
+//	Heading 3:1 Level=2 id="usage"
+//	  Text 3:4 "Usage"
+func DumpAST(w io.Writer, source []byte, n ast.Node) error {
+	return ast.Walk(n, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		line := nodeLine(source, n)
+		for _, property := range nodeProperties(source, n) {
+			line += " " + property
+		}
+		for _, a := range n.Attributes() {
+			value, _ := attributeString(n, string(a.Name))
+			line += fmt.Sprintf(" %s=%q", a.Name, value)
+		}
+		_, err := io.WriteString(w, line+"\n")
+		return ast.WalkContinue, err
+	})
+}
+
+// nodeLine returns the kind and position of a node, indented by its depth.
+func nodeLine(source []byte, n ast.Node) string {
+	depth := 0
+	for p := n.Parent(); p != nil; p = p.Parent() {
+		depth++
+	}
+	line := strings.Repeat("  ", depth) + n.Kind().String()
+	if pos := position(source, nodeOffset(n)); pos != "" && n.Kind() != ast.KindDocument {
+		line += " " + pos
+	}
+	return line
+}
+
+// position returns the line:column of a source offset, or "" if unknown.
+func position(source []byte, offset int) string {
+	if offset < 0 || offset > len(source) {
+		return ""
+	}
+	line := bytes.Count(source[:offset], []byte{'\n'}) + 1
+	column := offset - bytes.LastIndexByte(source[:offset], '\n')
+	return fmt.Sprintf("%d:%d", line, column)
+}
+
+// nodeProperties returns the main properties of the nodes of goldmark's kinds.
+func nodeProperties(source []byte, n ast.Node) []string {
+	switch n := n.(type) {
+	case *ast.Heading:
+		return []string{fmt.Sprintf("Level=%d", n.Level)}
+	case *ast.List:
+		return []string{fmt.Sprintf("Ordered=%t Marker=%q Tight=%t", n.IsOrdered(), n.Marker, n.IsTight)}
+	case *ast.Link:
+		return []string{fmt.Sprintf("Destination=%q", n.Destination)}
+	case *ast.Image:
+		return []string{fmt.Sprintf("Destination=%q", n.Destination)}
+	case *ast.FencedCodeBlock:
+		return []string{fmt.Sprintf("Language=%q", n.Language(source))}
+	case *ast.Text:
+		return []string{strconv.Quote(string(n.Segment.Value(source)))}
+	case *ast.String:
+		return []string{strconv.Quote(string(n.Value))}
+	}
+	return nil
+}
+
+// WithTrace writes a line to w for each node rendered, with its kind, source
+// position and the name of the function rendering it, such as renderHeading,
+// renderFallback or an override, to find out which renderer produced the output.
+func WithTrace(w io.Writer) Option {
+	return func(r *Renderer) {
+		r.Trace = w
+	}
+}
+
+// traceRegisterer registers node rendering functions writing the trace of the
+// rendering first.
+type traceRegisterer struct {
+	renderer.NodeRendererFuncRegisterer
+	w io.Writer
+}
+
+func (t *traceRegisterer) Register(kind ast.NodeKind, fn renderer.NodeRendererFunc) {
+	name := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
+	name = strings.TrimSuffix(name[strings.LastIndexByte(name, '.')+1:], "-fm")
+	t.NodeRendererFuncRegisterer.Register(kind, func(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			_, _ = io.WriteString(t.w, nodeLine(source, n)+" "+name+"\n")
+		}
+		return fn(w, source, n, entering)
+	})
+}
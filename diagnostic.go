@@ -0,0 +1,211 @@
+package latex
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+)
+
+// Codes of the diagnostics reported by the renderer.
+const (
+	// DiagnosticUnsupportedHTML reports HTML which is not rendered.
+	DiagnosticUnsupportedHTML = "unsupported-html"
+	// DiagnosticUnsupportedNode reports a node of a kind unknown to the renderer.
+	DiagnosticUnsupportedNode = "unsupported-node"
+	// DiagnosticUnsafeContent reports content skipped as possibly unsafe, see UnsafeOptions.
+	DiagnosticUnsafeContent = "unsafe-content"
+	// DiagnosticUnknownLanguage reports a code block language the code engine does not know.
+	DiagnosticUnknownLanguage = "unknown-language"
+	// DiagnosticMissingImage reports an image not found in the image file system, see WithImageFS.
+	DiagnosticMissingImage = "missing-image"
+)
+
+// Diagnostic is a warning about content which is not rendered faithfully.
+type Diagnostic struct {
+	// Code identifies the problem, such as DiagnosticUnsupportedHTML.
+	Code    string
+	Message string
+	// Node is the node at fault, nil if unknown.
+	Node ast.Node
+	// Line and Column locate the content in the source, from 1, or are 0 when unknown.
+	Line, Column int
+}
+
+// String returns the diagnostic prefixed with its position, if known.
+func (d Diagnostic) String() string {
+	if d.Line == 0 {
+		return d.Message + " (" + d.Code + ")"
+	}
+	return fmt.Sprintf("%d:%d: %s (%s)", d.Line, d.Column, d.Message, d.Code)
+}
+
+// Diagnostics returns the diagnostics of the last rendering: unsupported HTML
+// and nodes, content skipped as unsafe, unknown code languages and missing
+// images. Renderers rendering documents concurrently get the diagnostics of
+// each rendering with ConvertDiagnostics instead.
+func (r *Renderer) Diagnostics() []Diagnostic {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.diagnostics
+}
+
+// ConvertDiagnostics converts source to w with md, whose renderer includes r,
+// as md.Convert does, and returns the diagnostics of this rendering, which
+// other renderings by r, concurrent or not, do not affect.
+func (r *Renderer) ConvertDiagnostics(md goldmark.Markdown, source []byte, w io.Writer, opts ...parser.ParseOption) ([]Diagnostic, error) {
+	// The session of the rendering is found by the writer goldmark renders to,
+	// which is bw as it is already buffered.
+	bw := bufio.NewWriter(w)
+	r.results.Store(bw, []Diagnostic(nil))
+	defer r.results.Delete(bw)
+	err := md.Convert(source, bw, opts...)
+	if err == nil {
+		err = bw.Flush()
+	}
+	diagnostics, _ := r.results.Load(bw)
+	return diagnostics.([]Diagnostic), err
+}
+
+// WithImageFS reports the images which are not found in fsys as diagnostics.
+// Images without extension are looked up with the extensions \includegraphics
+// tries. Remote images are not checked.
+func WithImageFS(fsys fs.FS) Option {
+	return func(r *Renderer) {
+		r.ImageFS = fsys
+	}
+}
+
+// Logger receives the diagnostics as they are reported, with the code, line
+// and column as key-value pairs. A *slog.Logger satisfies it.
+type Logger interface {
+	Warn(msg string, args ...any)
+}
+
+// WithLogger sends the diagnostics to logger, e.g. to route them into the
+// logging or telemetry of an application, in addition to Diagnostics.
+func WithLogger(logger Logger) Option {
+	return func(r *Renderer) {
+		r.Logger = logger
+	}
+}
+
+// warn reports a diagnostic about a node.
+func (r *Renderer) warn(source []byte, n ast.Node, code, format string, args ...any) {
+	r.warnAt(source, n, nodeOffset(n), code, format, args...)
+}
+
+// warnAt reports a diagnostic about content at the given source offset, -1 if unknown.
+func (r *Renderer) warnAt(source []byte, n ast.Node, offset int, code, format string, args ...any) {
+	d := Diagnostic{Code: code, Message: fmt.Sprintf(format, args...), Node: n}
+	if offset >= 0 && offset <= len(source) {
+		d.Line = bytes.Count(source[:offset], []byte{'\n'}) + 1
+		d.Column = offset - bytes.LastIndexByte(source[:offset], '\n')
+	}
+	r.report(d)
+}
+
+// report records a diagnostic and sends it to the Logger.
+func (r *Renderer) report(d Diagnostic) {
+	if !r.Streaming || len(r.diagnostics) < maxStreamingDiagnostics {
+		r.diagnostics = append(r.diagnostics, d)
+	}
+	if r.Logger != nil {
+		r.Logger.Warn(d.Message, "code", d.Code, "line", d.Line, "column", d.Column)
+	}
+}
+
+// nodeOffset returns the source offset where the node starts, or -1 if unknown.
+func nodeOffset(n ast.Node) int {
+	if n == nil {
+		return -1
+	}
+	switch n := n.(type) {
+	case *ast.Text:
+		return n.Segment.Start
+	case *ast.RawHTML:
+		if n.Segments.Len() > 0 {
+			return n.Segments.At(0).Start
+		}
+	}
+	if n.Type() == ast.TypeBlock && n.Lines().Len() > 0 {
+		return n.Lines().At(0).Start
+	}
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if offset := nodeOffset(c); offset >= 0 {
+			return offset
+		}
+	}
+	return -1
+}
+
+// imageExtensions are the extensions \includegraphics tries for paths without extension.
+var imageExtensions = []string{".pdf", ".png", ".jpg", ".jpeg", ".eps"}
+
+// checkImage reports an image missing from the image file system.
+func (r *Renderer) checkImage(source []byte, n ast.Node, name string) {
+	if r.ImageFS == nil || strings.Contains(name, "://") {
+		return
+	}
+	if _, ok := findImage(r.ImageFS, name); !ok {
+		r.warn(source, n, DiagnosticMissingImage, "image %s not found", strings.TrimPrefix(path.Clean(name), "/"))
+	}
+}
+
+// findImage returns the path of an image in fsys, with the extension
+// \includegraphics finds when the name has none.
+func findImage(fsys fs.FS, name string) (string, bool) {
+	name = strings.TrimPrefix(path.Clean(name), "/")
+	candidates := []string{name}
+	if path.Ext(name) == "" {
+		candidates = nil
+		for _, ext := range imageExtensions {
+			candidates = append(candidates, name+ext)
+		}
+	}
+	for _, candidate := range candidates {
+		if _, err := fs.Stat(fsys, candidate); err == nil {
+			return candidate, true
+		}
+	}
+	return name, false
+}
+
+// WithStrict makes rendering fail with a *StrictError when the document has
+// diagnostics, instead of writing skip comments only, for pipelines where a
+// lossy conversion must not go unnoticed.
+func WithStrict(strict bool) Option {
+	return func(r *Renderer) {
+		r.Strict = strict
+	}
+}
+
+// StrictError is the error returned in strict mode, see WithStrict.
+type StrictError struct {
+	Diagnostics []Diagnostic
+}
+
+func (e *StrictError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "latex: strict mode: %d diagnostics", len(e.Diagnostics))
+	for _, d := range e.Diagnostics {
+		b.WriteString("\n\t")
+		b.WriteString(d.String())
+	}
+	return b.String()
+}
+
+// strictError returns a *StrictError in strict mode if there are diagnostics.
+func (r *Renderer) strictError() error {
+	if !r.Strict || len(r.diagnostics) == 0 {
+		return nil
+	}
+	return &StrictError{Diagnostics: append([]Diagnostic(nil), r.diagnostics...)}
+}
@@ -0,0 +1,124 @@
+package latex
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/util"
+)
+
+// rtlLanguages are the languages written right to left.
+var rtlLanguages = map[string]bool{
+	"arabic":  true,
+	"farsi":   true,
+	"hebrew":  true,
+	"persian": true,
+	"syriac":  true,
+	"urdu":    true,
+	"yiddish": true,
+}
+
+// WithRightToLeft sets the main text direction of the document to right to left,
+// regardless of the document language.
+func WithRightToLeft(rtl bool) Option {
+	return func(r *Renderer) {
+		r.RightToLeft = rtl
+	}
+}
+
+// isRTL reports whether the rune belongs to a right to left script.
+func isRTL(c rune) bool {
+	return unicode.In(c, unicode.Hebrew, unicode.Arabic, unicode.Syriac, unicode.Thaana, unicode.Nko)
+}
+
+// textDirection returns the direction of the first strongly directional
+// character found in the node's text: rtl is true for right to left
+// scripts and ok is false if the text has no letters.
+func textDirection(n ast.Node, source []byte) (rtl bool, ok bool) {
+	found := false
+	_ = ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		var text []byte
+		switch c := c.(type) {
+		case *ast.Text:
+			text = c.Segment.Value(source)
+		case *ast.String:
+			text = c.Value
+		case *ast.CodeSpan, *ast.CodeBlock, *ast.FencedCodeBlock:
+			return ast.WalkSkipChildren, nil
+		default:
+			return ast.WalkContinue, nil
+		}
+		for len(text) > 0 {
+			char, size := utf8.DecodeRune(text)
+			text = text[size:]
+			if unicode.IsLetter(char) {
+				rtl, found = isRTL(char), true
+				return ast.WalkStop, nil
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return rtl, found
+}
+
+// contextRTL returns the direction of the block containing n: that of the
+// nearest enclosing list, or the document's main direction.
+func (r *Renderer) contextRTL(n ast.Node, source []byte) bool {
+	for p := n.Parent(); p != nil; p = p.Parent() {
+		if p.Kind() == ast.KindList {
+			if rtl, ok := textDirection(p, source); ok {
+				return rtl
+			}
+		}
+	}
+	return r.rtl
+}
+
+// writeDirectionStart opens an RTL or LTR environment around the block if
+// its direction differs from the surrounding text. It reports whether an
+// environment was opened. Only Unicode engines, where polyglossia loads
+// the bidi support, get direction environments.
+func (r *Renderer) writeDirectionStart(w util.BufWriter, source []byte, n ast.Node) bool {
+	env, ok := r.directionEnvironment(n, source)
+	if ok {
+		_, _ = w.WriteString("\n\\begin{")
+		_, _ = w.WriteString(env)
+		_, _ = w.WriteString("}")
+	}
+	return ok
+}
+
+func (r *Renderer) writeDirectionEnd(w util.BufWriter, source []byte, n ast.Node) {
+	if env, ok := r.directionEnvironment(n, source); ok {
+		_, _ = w.WriteString("\\end{")
+		_, _ = w.WriteString(env)
+		_, _ = w.WriteString("}\n")
+	}
+}
+
+func (r *Renderer) directionEnvironment(n ast.Node, source []byte) (string, bool) {
+	if !r.bidi || !r.Engine.IsUnicode() {
+		return "", false
+	}
+	rtl, ok := textDirection(n, source)
+	if !ok || rtl == r.contextRTL(n, source) {
+		return "", false
+	}
+	if rtl {
+		return "RTL", true
+	}
+	return "LTR", true
+}
+
+// setDirection sets the text direction of the document from its languages.
+func (r *Renderer) setDirection(languages []string) {
+	r.rtl = r.RightToLeft || (len(languages) > 0 && rtlLanguages[languages[0]])
+	r.bidi = r.rtl
+	for _, language := range languages {
+		r.bidi = r.bidi || rtlLanguages[language]
+	}
+}
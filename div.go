@@ -0,0 +1,378 @@
+package latex
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// KindDiv is the NodeKind of Div nodes.
+var KindDiv = ast.NewNodeKind("Div")
+
+// Div is a Pandoc style fenced div, a container of blocks with attributes:
+//
+//	::: {.theorem #thm:euclid title="Euclid"}
+//	There are infinitely many primes.
+//	:::
+//
+// Divs with a raw attribute, ::: {=latex}, keep their lines verbatim.
+type Div struct {
+	ast.BaseBlock
+	// Format of the raw content, set for raw divs.
+	Format string
+	closed bool // Set when the closing fence has been read.
+}
+
+// Kind implements ast.Node.Kind.
+func (n *Div) Kind() ast.NodeKind {
+	return KindDiv
+}
+
+// Dump implements ast.Node.Dump.
+func (n *Div) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// NewDiv returns a new Div node.
+func NewDiv() *Div {
+	return &Div{}
+}
+
+type divParser struct{}
+
+// NewDivParser returns a parser of fenced divs. A div opens with a line of at least
+// three colons followed by attributes in braces, or a single class name, and closes
+// with a line of at least three colons. Divs can be nested.
+func NewDivParser() parser.BlockParser {
+	return &divParser{}
+}
+
+func (p *divParser) Trigger() []byte {
+	return []byte{':'}
+}
+
+// divFence returns the text following the fence of a line of at least three
+// colons, without trailing colons, or false if the line is not a fence.
+func divFence(line []byte) ([]byte, bool) {
+	line = util.TrimRightSpace(util.TrimLeftSpace(line))
+	i := 0
+	for i < len(line) && line[i] == ':' {
+		i++
+	}
+	if i < 3 {
+		return nil, false
+	}
+	return bytes.TrimSpace(bytes.TrimRight(line[i:], ":")), true
+}
+
+func (p *divParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	if pc.BlockOffset() < 0 {
+		return nil, parser.NoChildren
+	}
+	info, ok := divFence(line)
+	if !ok || len(info) == 0 {
+		return nil, parser.NoChildren
+	}
+	n := NewDiv()
+	if format, ok := rawFormat(info); ok {
+		n.Format = format
+		reader.Advance(segment.Len() - 1)
+		return n, parser.NoChildren
+	}
+	if info[0] == '{' {
+		attributes, ok := parser.ParseAttributes(text.NewReader(info))
+		if !ok {
+			return nil, parser.NoChildren
+		}
+		for _, a := range attributes {
+			n.SetAttribute(a.Name, a.Value)
+		}
+	} else if bytes.IndexAny(info, " \t") < 0 {
+		n.SetAttributeString("class", info)
+	} else {
+		return nil, parser.NoChildren
+	}
+	reader.Advance(segment.Len() - 1)
+	return n, parser.HasChildren
+}
+
+func (p *divParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	n := node.(*Div)
+	line, segment := reader.PeekLine()
+	if info, ok := divFence(line); ok && len(info) == 0 {
+		if inner, ok := n.LastChild().(*Div); ok && !inner.closed {
+			return parser.Continue | parser.HasChildren // The fence closes a nested div.
+		}
+		reader.Advance(segment.Len() - 1)
+		n.closed = true
+		return parser.Close
+	}
+	if n.Format != "" {
+		n.Lines().Append(segment)
+		reader.Advance(segment.Len() - 1)
+		return parser.Continue | parser.NoChildren
+	}
+	return parser.Continue | parser.HasChildren
+}
+
+func (p *divParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {
+	node.(*Div).closed = true
+}
+
+func (p *divParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (p *divParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+// divClasses returns the classes of a node.
+func divClasses(n ast.Node) []string {
+	classes, _ := attributeString(n, "class")
+	return strings.Fields(classes)
+}
+
+// DivRenderFunc writes the LaTeX opening, when entering, or closing a fenced div
+// around its content.
+type DivRenderFunc func(w util.BufWriter, source []byte, div *Div, entering bool)
+
+// WithDivRenderer renders the fenced divs of a class with fn, which takes
+// precedence over the environment map and the built-in div classes. The option
+// can be given once per class.
+func WithDivRenderer(class string, fn DivRenderFunc) Option {
+	return func(r *Renderer) {
+		if r.DivRenderers == nil {
+			r.DivRenderers = make(map[string]DivRenderFunc)
+		}
+		r.DivRenderers[class] = fn
+	}
+}
+
+// builtinDiv returns the function rendering the built-in div class, writing
+// text with writer: theorem-like environments, admonitions, columns, verse,
+// epigraphs and speaker notes.
+func builtinDiv(class string, writer Writer) (DivRenderFunc, bool) {
+	if _, ok := theoremTitles[class]; ok {
+		return func(w util.BufWriter, source []byte, div *Div, entering bool) {
+			writeTheorem(w, writer, div, class, entering)
+		}, true
+	}
+	if _, ok := admonitionIndex[class]; ok || admonitionAliases[class] != "" {
+		return func(w util.BufWriter, source []byte, div *Div, entering bool) {
+			renderAdmonitionDiv(w, writer, div, entering)
+		}, true
+	}
+	switch class {
+	case "columns":
+		return renderColumns, true
+	case "column":
+		return renderColumn, true
+	case "verse":
+		return renderVerse, true
+	case "epigraph":
+		return func(w util.BufWriter, source []byte, div *Div, entering bool) {
+			renderEpigraph(w, writer, div, entering)
+		}, true
+	case "notes":
+		return renderNotes, true
+	}
+	return nil, false
+}
+
+// divRenderer returns the function rendering a div with the given classes,
+// looking up the classes in order in the div renderers, the environment map and
+// the built-in classes.
+func (r *Renderer) divRenderer(classes []string) (DivRenderFunc, bool) {
+	for _, class := range classes {
+		if fn, ok := r.DivRenderers[class]; ok {
+			return fn, true
+		}
+		if env, ok := r.DivEnvironments[class]; ok && env != "" {
+			return r.environmentDiv(env), true
+		}
+		if fn, ok := builtinDiv(class, r.Writer); ok {
+			return fn, true
+		}
+	}
+	return nil, false
+}
+
+// environmentDiv returns the function rendering divs as the environment, which
+// gets the div's attributes other than id and class as options, and its id as label.
+func (r *Renderer) environmentDiv(env string) DivRenderFunc {
+	return func(w util.BufWriter, source []byte, div *Div, entering bool) {
+		if !entering {
+			_, _ = w.WriteString("\\end{" + env + "}\n")
+			return
+		}
+		_, _ = w.WriteString("\n\\begin{" + env + "}" + r.divOptions(div) + "\n")
+		if id, ok := attributeString(div, "id"); ok && labelID(id) != "" {
+			_, _ = w.WriteString("\\label{" + labelID(id) + "}\n")
+		}
+	}
+}
+
+// divOptions returns the attributes of a div other than id and class as
+// environment options, e.g. [title={Notes}, breakable]. Only the attributes
+// named with letters and dashes are kept, and their values are escaped.
+func (r *Renderer) divOptions(n ast.Node) string {
+	var options []string
+	for _, a := range n.Attributes() {
+		name := string(a.Name)
+		if name == "id" || name == "class" || !isOptionName(name) {
+			continue
+		}
+		value, _ := attributeString(n, name)
+		if value == "" {
+			options = append(options, name)
+		} else {
+			options = append(options, name+"={"+r.Writer.EscapeString(value)+"}")
+		}
+	}
+	if len(options) == 0 {
+		return ""
+	}
+	return "[" + strings.Join(options, ", ") + "]"
+}
+
+// renderDiv writes the environment matching the div's classes, if any, around its content.
+func (r *Renderer) renderDiv(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*Div)
+	if !r.Beamer && r.isNotes(n) {
+		return ast.WalkSkipChildren, nil // Speaker notes are only typeset on slides.
+	}
+	if n.Format != "" {
+		if entering {
+			r.writeRawBlock(w, source, n.Format, n.Lines())
+		}
+		return ast.WalkSkipChildren, nil
+	}
+	if fn, ok := r.divRenderer(divClasses(n)); ok {
+		fn(w, source, n, entering)
+	}
+	return ast.WalkContinue, nil
+}
+
+// isVerse reports whether the node is in a verse div, whose lines are kept.
+func isVerse(n ast.Node) bool {
+	for p := n.Parent(); p != nil; p = p.Parent() {
+		if p.Kind() == KindDiv && containsString(divClasses(p), "verse") {
+			return true
+		}
+	}
+	return false
+}
+
+// renderVerse writes a verse div as a verse environment, labeled with its id.
+func renderVerse(w util.BufWriter, source []byte, div *Div, entering bool) {
+	if !entering {
+		_, _ = w.WriteString("\\end{verse}\n")
+		return
+	}
+	_, _ = w.WriteString("\n\\begin{verse}\n")
+	if id, ok := attributeString(div, "id"); ok && labelID(id) != "" {
+		_, _ = w.WriteString("\\label{" + labelID(id) + "}\n")
+	}
+}
+
+// renderEpigraph writes an epigraph div with the epigraph package, attributed to
+// its author or source attribute.
+func renderEpigraph(w util.BufWriter, writer Writer, div *Div, entering bool) {
+	if entering {
+		_, _ = w.WriteString("\n\\epigraph{")
+		return
+	}
+	author, ok := attributeString(div, "author")
+	if !ok {
+		author, _ = attributeString(div, "source")
+	}
+	_, _ = w.WriteString("}{" + writer.EscapeString(author) + "}\n")
+}
+
+// renderColumns writes the content of a columns div, whose column divs are set
+// side by side in minipages.
+func renderColumns(w util.BufWriter, source []byte, div *Div, entering bool) {
+	if entering {
+		_, _ = w.WriteString("\n\\noindent\n")
+	} else {
+		_, _ = w.WriteString("\n")
+	}
+}
+
+// renderColumn writes a column as a minipage, as wide as its width attribute, a
+// percentage of the text width, or sharing the width with its sibling columns.
+func renderColumn(w util.BufWriter, source []byte, div *Div, entering bool) {
+	if !entering {
+		_, _ = w.WriteString("\\end{minipage}")
+		if div.NextSibling() != nil {
+			_, _ = w.WriteString("\\hfill")
+		}
+		_, _ = w.WriteString("\n")
+		return
+	}
+	width := ""
+	if value, ok := attributeString(div, "width"); ok {
+		value = strings.TrimSpace(value)
+		percent := strings.TrimSuffix(value, "%")
+		switch {
+		case percent != value && isNumber(percent):
+			number, _ := strconv.ParseFloat(percent, 64)
+			width = strconv.FormatFloat(number/100, 'f', -1, 64) + "\\textwidth"
+		case isNumber(value):
+			width = value + "\\textwidth"
+		case isLength(value):
+			width = value
+		}
+	}
+	if width == "" {
+		columns := 0
+		for c := div.Parent().FirstChild(); c != nil; c = c.NextSibling() {
+			if c.Kind() == KindDiv && containsString(divClasses(c), "column") {
+				columns++
+			}
+		}
+		// Leave room between columns.
+		width = strconv.FormatFloat(0.96/float64(max(columns, 1)), 'f', 2, 64) + "\\textwidth"
+	}
+	_, _ = w.WriteString("\\begin{minipage}[t]{" + width + "}\n")
+}
+
+// WithDivEnvironmentMap typesets the fenced divs and HTML <div> elements of the
+// given classes with LaTeX environments, e.g. {"warning": "warning"} turns
+// ::: warning into \begin{warning}...\end{warning}. The div's attributes other
+// than id and class are passed as options and its id as label. Environments
+// must be defined in the preamble. The map takes precedence over the built-in
+// div classes.
+func WithDivEnvironmentMap(environments map[string]string) Option {
+	return func(r *Renderer) {
+		r.DivEnvironments = environments
+	}
+}
+
+// divEnvironment returns the environment mapped to the first mapped class.
+func (r *Renderer) divEnvironment(classes []string) (string, bool) {
+	for _, class := range classes {
+		if env, ok := r.DivEnvironments[class]; ok && env != "" {
+			return env, true
+		}
+	}
+	return "", false
+}
+
+type divExtension struct{}
+
+// Divs is a goldmark extension parsing Pandoc style fenced divs, which are
+// typeset as environments, e.g. theorems, depending on their classes.
+var Divs goldmark.Extender = &divExtension{}
+
+func (e *divExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithBlockParsers(util.Prioritized(NewDivParser(), 150)))
+}
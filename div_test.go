@@ -0,0 +1,149 @@
+package latex_test
+
+import (
+	"strings"
+	"testing"
+
+	latex "github.com/dihedron/goldmark-latex"
+	"github.com/yuin/goldmark/util"
+)
+
+func TestTheorems(t *testing.T) {
+	markdown := `::: {.theorem #thm:primes title="Euclid"}
+There are infinitely many primes.
+
+::: proof
+Suppose there are finitely many.
+:::
+:::
+
+::: definition
+A prime has two divisors.
+:::
+
+After.
+`
+	output := convert(t, markdown)
+	for _, expected := range []string{
+		"\\usepackage{amsthm}\n\\theoremstyle{plain}\n\\newtheorem{theorem}{Theorem}\n\\theoremstyle{definition}\n\\newtheorem{definition}{Definition}\n",
+		"\\begin{theorem}[Euclid]\n\\label{thm:primes}\n",
+		"\\begin{proof}\n",
+		"Suppose there are finitely many.\n",
+		"\\end{proof}\n",
+		"\\begin{definition}\n",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in output, got:\n%s", expected, output)
+		}
+	}
+	if i, j := strings.Index(output, "\\end{proof}"), strings.Index(output, "\\end{theorem}"); i < 0 || j < i {
+		t.Errorf("expected the proof nested in the theorem, got:\n%s", output)
+	}
+	if i, j := strings.Index(output, "\\end{definition}"), strings.Index(output, "After."); i < 0 || j < i {
+		t.Errorf("expected the definition closed before the last paragraph, got:\n%s", output)
+	}
+}
+
+func TestDivEnvironmentMap(t *testing.T) {
+	output := convert(t, "::: warning\nMind the *gap*.\n:::\n\n<div class=\"note big\">\n\nA note.\n\n</div>\n\n<div class=\"other\">Plain</div>\n",
+		latex.WithDivEnvironmentMap(map[string]string{"warning": "warningbox", "note": "notebox"}))
+	for _, want := range []string{
+		"\\begin{warningbox}\n",
+		"Mind the \\textit{gap}.",
+		"\\end{warningbox}\n",
+		"\\begin{notebox}\n",
+		"A note.",
+		"\\end{notebox}\n",
+		"\nPlain\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestDivContainers(t *testing.T) {
+	markdown := `::: {.box #box:one title="Notes" breakable=""}
+Boxed.
+:::
+
+::: {.warning title="Careful"}
+Admonition.
+:::
+
+:::: columns
+::: {.column width="40%"}
+Left.
+:::
+::: column
+Right.
+:::
+::::
+
+::: custom
+Custom.
+:::
+`
+	output := convert(t, markdown,
+		latex.WithDivEnvironmentMap(map[string]string{"box": "tcolorbox"}),
+		latex.WithDivRenderer("custom", func(w util.BufWriter, source []byte, div *latex.Div, entering bool) {
+			if entering {
+				_, _ = w.WriteString("\\custom{")
+			} else {
+				_, _ = w.WriteString("}\n")
+			}
+		}))
+	for _, want := range []string{
+		"\\begin{tcolorbox}[title={Notes}, breakable]\n\\label{box:one}\n",
+		"title={\\faExclamationTriangle\\ Careful}]",
+		"\\noindent\n\\begin{minipage}[t]{0.4\\textwidth}\n",
+		"\\end{minipage}\\hfill\n\\begin{minipage}[t]{0.48\\textwidth}",
+		"\\custom{",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestVerseAndEpigraph(t *testing.T) {
+	output := convert(t, "::: verse\nThe woods are lovely,\ndark and *deep*.\n\nBut I have promises\nto keep.\n:::\n\n"+
+		"::: {.epigraph author=\"R. Frost\"}\nTwo roads diverged.\n:::\n", latex.WithDynamicPreamble(true))
+	for _, want := range []string{
+		"\\begin{verse}\n",
+		"The woods are lovely,\\\\\ndark and \\textit{deep}.\n",
+		"But I have promises\\\\\nto keep.\n",
+		"\\end{verse}\n",
+		"\\epigraph{",
+		"Two roads diverged.",
+		"}{R. Frost}\n",
+		"\\usepackage{epigraph}",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestDivInjection(t *testing.T) {
+	markdown := "::: {.box #box:one title=\"}\\write18{z}\"}\nBoxed.\n:::\n\n" +
+		":::: columns\n::: {.column width=\"1pt}\\input{w}\"}\nLeft.\n:::\n::::\n"
+	output := convert(t, markdown, latex.WithDivEnvironmentMap(map[string]string{"box": "tcolorbox"}))
+	for _, unexpected := range []string{"\\input", "\\write18"} {
+		if strings.Contains(output, unexpected) {
+			t.Errorf("unexpected %q in output, got:\n%s", unexpected, output)
+		}
+	}
+	for _, want := range []string{"\\begin{tcolorbox}[title={\\}", "\\label{box:one}", "\\begin{minipage}[t]{0.96\\textwidth}"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestTheoremLabel(t *testing.T) {
+	output := convert(t, "::: {.theorem id=\"thm:a}\\input{x\"}\nTrue.\n:::\n")
+	if !strings.Contains(output, "\\label{thm:ainputx}\n") {
+		t.Errorf("expected a sanitized label, got:\n%s", output)
+	}
+}
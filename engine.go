@@ -0,0 +1,53 @@
+package latex
+
+// Engine is the TeX engine the generated document is meant to be compiled with.
+type Engine int
+
+const (
+	// PDFLaTeX is the default engine, using inputenc and fontenc for encodings.
+	PDFLaTeX Engine = iota
+	// XeLaTeX is a Unicode engine with system font support through fontspec.
+	XeLaTeX
+	// LuaLaTeX is a Unicode engine with system font support through fontspec.
+	LuaLaTeX
+)
+
+// String returns the engine's command name.
+func (e Engine) String() string {
+	switch e {
+	case XeLaTeX:
+		return "xelatex"
+	case LuaLaTeX:
+		return "lualatex"
+	default:
+		return "pdflatex"
+	}
+}
+
+// IsUnicode reports whether the engine reads UTF-8 natively, in which case
+// inputenc, fontenc and \DeclareUnicodeCharacter must not be used.
+func (e Engine) IsUnicode() bool {
+	return e == XeLaTeX || e == LuaLaTeX
+}
+
+// WithEngine sets the TeX engine the generated preamble targets.
+// Unicode engines load fontspec instead of inputenc and fontenc and
+// disable the declarations of WithUnicodeCharactersMapping.
+func WithEngine(engine Engine) Option {
+	return func(r *Renderer) {
+		r.Engine = engine
+	}
+}
+
+// addFonts adds the encoding and font setup of the default preamble.
+func (e Engine) addFonts(b *PreambleBuilder) {
+	if e.IsUnicode() {
+		b.AddPackage("fontspec")
+		b.AddCommand("\\setsansfont{TeX Gyre Heros}\n\\renewcommand{\\familydefault}{\\sfdefault}")
+		return
+	}
+	b.AddPackage("inputenc", "utf8")
+	b.AddPackage("fontenc", "T1")
+	b.AddCommand("\\renewcommand{\\familydefault}{\\sfdefault}")
+	b.AddPackage("helvet", "scaled=1")
+}
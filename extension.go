@@ -0,0 +1,67 @@
+package latex
+
+import (
+	"io"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+type latexExtension struct {
+	options []Option
+}
+
+// Extension returns a goldmark extender rendering documents to LaTeX with a
+// Renderer configured with options. It also adds the syntaxes of this package,
+// Math, Units, MHChem, Divs, RawAttributes, LaTeXCommands, Spans,
+// Admonitions and Attributions, footnotes and heading attributes, and
+// AbstractSections with WithAbstractSection. E.g.
+//
+//	md := goldmark.New(goldmark.WithExtensions(latex.Extension(latex.WithTableOfContents(true))))
+//	err := md.Convert(markdown, output)
+func Extension(options ...Option) goldmark.Extender {
+	return &latexExtension{options: options}
+}
+
+func (e *latexExtension) Extend(m goldmark.Markdown) {
+	r := NewRenderer(e.options...)
+	for _, syntax := range []goldmark.Extender{Math, Units, MHChem, Divs, RawAttributes, LaTeXCommands, Spans, Admonitions, Attributions} {
+		syntax.Extend(m)
+	}
+	if r.AbstractSection {
+		AbstractSections.Extend(m)
+	}
+	// The footnote parsers only: the extension also registers its HTML renderer.
+	m.Parser().AddOptions(
+		parser.WithHeadingAttribute(),
+		parser.WithBlockParsers(util.Prioritized(extension.NewFootnoteBlockParser(), 999)),
+		parser.WithInlineParsers(util.Prioritized(extension.NewFootnoteParser(), 101)),
+		parser.WithASTTransformers(util.Prioritized(extension.NewFootnoteASTTransformer(), 999)),
+	)
+	m.SetRenderer(renderer.NewRenderer(
+		renderer.WithNodeRenderers(util.Prioritized(r, 1000)),
+	))
+}
+
+// New returns a goldmark converter to LaTeX, with the syntaxes of Extension
+// and a Renderer configured with options.
+func New(options ...Option) goldmark.Markdown {
+	return goldmark.New(goldmark.WithExtensions(Extension(options...)))
+}
+
+// Convert converts the markdown source to LaTeX, written to w, with the syntaxes
+// of Extension and a Renderer configured with options.
+func Convert(source []byte, w io.Writer, options ...Option) error {
+	return New(options...).Convert(source, w)
+}
+
+// ConvertWithParserOptions is like Convert, the parser being also configured
+// with parserOptions, e.g. to add the block parsers of other extensions.
+func ConvertWithParserOptions(source []byte, w io.Writer, parserOptions []parser.Option, options ...Option) error {
+	md := New(options...)
+	md.Parser().AddOptions(parserOptions...)
+	return md.Convert(source, w)
+}
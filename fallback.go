@@ -0,0 +1,70 @@
+package latex
+
+import (
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// kindRecorder records the node kinds registered by the renderer.
+type kindRecorder struct {
+	renderer.NodeRendererFuncRegisterer
+	kinds map[ast.NodeKind]bool
+}
+
+func (k *kindRecorder) Register(kind ast.NodeKind, fn renderer.NodeRendererFunc) {
+	k.kinds[kind] = true
+	k.NodeRendererFuncRegisterer.Register(kind, fn)
+}
+
+// registerFallback registers renderFallback for the node kinds, see kindNames,
+// not registered by the renderer, which goldmark would fail to render. Node
+// renderers registered after the LaTeX renderer, having a lower priority
+// value, override the fallback.
+func (r *Renderer) registerFallback(reg renderer.NodeRendererFuncRegisterer, registered map[ast.NodeKind]bool) {
+	for i := range kindNames() {
+		if kind := ast.NodeKind(i); !registered[kind] {
+			reg.Register(kind, r.renderFallback)
+		}
+	}
+}
+
+// renderFallback renders the nodes of kinds unknown to the renderer, such as
+// the nodes of third party extensions, so that they degrade gracefully: their
+// children are rendered and the lines of leaf blocks are written as text.
+// Other nodes are replaced by a warning comment.
+func (r *Renderer) renderFallback(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	block := node.Type() == ast.TypeBlock
+	r.warn(source, node, DiagnosticUnsupportedNode, "unsupported node %s", node.Kind())
+	if node.HasChildren() {
+		if block {
+			_ = w.WriteByte('\n')
+			r.warnComment(w, "unsupported node %s, rendering its content", node.Kind())
+		}
+		return ast.WalkContinue, nil
+	}
+	_ = w.WriteByte('\n')
+	switch {
+	case block && node.Lines().Len() > 0:
+		r.warnComment(w, "unsupported node %s, writing its text", node.Kind())
+		r.writeLines(w, source, node)
+	default:
+		r.warnComment(w, "unsupported node %s skipped", node.Kind())
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+// WithNodeRendererOverride renders the nodes of a kind, such as
+// ast.KindImage, with fn instead of the built-in function, e.g. to customize
+// the rendering of images or block quotes without writing a renderer.
+func WithNodeRendererOverride(kind ast.NodeKind, fn renderer.NodeRendererFunc) Option {
+	return func(r *Renderer) {
+		if r.NodeRendererOverrides == nil {
+			r.NodeRendererOverrides = make(map[ast.NodeKind]renderer.NodeRendererFunc)
+		}
+		r.NodeRendererOverrides[kind] = fn
+	}
+}
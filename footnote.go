@@ -0,0 +1,67 @@
+package latex
+
+import (
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/util"
+)
+
+// WithFootnoteCommand sets the command typesetting the footnotes of goldmark's
+// footnote extension, without backslash, e.g. sidenote; footnote by default.
+func WithFootnoteCommand(command string) Option {
+	return func(r *Renderer) {
+		r.FootnoteCommand = command
+	}
+}
+
+// footnote returns the footnote referenced by a link, found in the footnote
+// list ending the document.
+func footnote(link *east.FootnoteLink) *east.Footnote {
+	doc := link.OwnerDocument()
+	if doc == nil {
+		return nil
+	}
+	for list := doc.LastChild(); list != nil; list = list.PreviousSibling() {
+		if list.Kind() != east.KindFootnoteList {
+			continue
+		}
+		for c := list.FirstChild(); c != nil; c = c.NextSibling() {
+			if fn := c.(*east.Footnote); fn.Index == link.Index {
+				return fn
+			}
+		}
+	}
+	return nil
+}
+
+// renderFootnoteLink writes the footnote referenced by a link in place, with
+// the footnote command. Footnotes referenced more than once are repeated.
+func (r *Renderer) renderFootnoteLink(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	fn := footnote(node.(*east.FootnoteLink))
+	if fn == nil {
+		return ast.WalkContinue, nil
+	}
+	command := r.FootnoteCommand
+	if command == "" {
+		command = "footnote"
+	}
+	_, _ = w.WriteString("\\" + command + "{")
+	content := ast.Node(fn)
+	if fn.ChildCount() == 1 && fn.FirstChild().Kind() == ast.KindParagraph {
+		content = fn.FirstChild() // Written inline.
+	}
+	if err := r.renderSiblings(w, source, content.FirstChild()); err != nil {
+		return ast.WalkStop, err
+	}
+	_ = w.WriteByte('}')
+	return ast.WalkContinue, nil
+}
+
+// renderFootnoteList skips the footnote list, whose footnotes are written
+// where they are referenced, and the backlinks of the footnotes.
+func (r *Renderer) renderFootnoteList(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	return ast.WalkSkipChildren, nil
+}
@@ -0,0 +1,299 @@
+package latex
+
+import (
+	"strings"
+
+	"github.com/yuin/goldmark/util"
+)
+
+// Headers configures running page headers and footers set with fancyhdr.
+// Each field is plain text, escaped for LaTeX, which may contain the
+// following placeholders:
+//
+//	{title}      the document title found in metadata
+//	{subtitle}   the document subtitle found in metadata
+//	{author}     the document authors found in metadata
+//	{date}       the document date found in metadata
+//	{section}    the name of the current section
+//	{page}       the current page number
+//	{pages}      the total number of pages
+//	{version}    the build version, see WithBuildInfo
+//	{commit}     the build commit, see WithBuildInfo
+//	{builddate}  the build date, see WithBuildInfo
+//	{buildinfo}  version, commit and build date, separated by dashes
+type Headers struct {
+	HeadLeft, HeadCenter, HeadRight string
+	FootLeft, FootCenter, FootRight string
+}
+
+// WithHeaders sets the running headers and footers of the document's pages,
+// e.g. Headers{HeadLeft: "{title}", HeadRight: "{section}", FootCenter: "Page {page} of {pages}"}.
+func WithHeaders(headers Headers) Option {
+	return func(r *Renderer) {
+		r.Headers = &headers
+	}
+}
+
+// placeholders returns the LaTeX replacing each placeholder supported in headers and footers.
+func (r *Renderer) placeholders(meta map[string]any) map[string]string {
+	p := map[string]string{
+		"section": "{\\leftmark}",
+		"page":    "{\\thepage}",
+		"pages":   "\\pageref{LastPage}",
+	}
+	for _, key := range []string{"title", "subtitle", "date"} {
+		value, _ := metaString(meta, key)
+		p[key] = r.Writer.EscapeString(value)
+	}
+	if b := r.BuildInfo; b != nil {
+		p["version"] = r.Writer.EscapeString(b.Version)
+		p["commit"] = r.Writer.EscapeString(b.Commit)
+		p["builddate"] = r.Writer.EscapeString(b.Date)
+		p["buildinfo"] = b.latex(r.Writer)
+	}
+	var names []string
+	for _, a := range metaAuthors(meta) {
+		names = append(names, a.Name)
+	}
+	p["author"] = r.Writer.EscapeString(strings.Join(names, ", "))
+	return p
+}
+
+// expandPlaceholders escapes text and replaces the {name} placeholders it contains.
+// Unknown placeholders are kept as text.
+func (r *Renderer) expandPlaceholders(text string, placeholders map[string]string) string {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(text, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(text[start:], '}')
+		if end < 0 {
+			break
+		}
+		end += start
+		b.WriteString(r.Writer.EscapeString(text[:start]))
+		if value, ok := placeholders[text[start+1:end]]; ok {
+			b.WriteString(value)
+		} else {
+			b.WriteString(r.Writer.EscapeString(text[start : end+1]))
+		}
+		text = text[end+1:]
+	}
+	b.WriteString(r.Writer.EscapeString(text))
+	return b.String()
+}
+
+// writeHeaders writes the fancyhdr page style configuration.
+func (r *Renderer) writeHeaders(w util.BufWriter, meta map[string]any) {
+	h := r.Headers
+	if h == nil {
+		return
+	}
+	fields := []struct {
+		command, position, text string
+	}{
+		{"fancyhead", "L", h.HeadLeft},
+		{"fancyhead", "C", h.HeadCenter},
+		{"fancyhead", "R", h.HeadRight},
+		{"fancyfoot", "L", h.FootLeft},
+		{"fancyfoot", "C", h.FootCenter},
+		{"fancyfoot", "R", h.FootRight},
+	}
+	_, _ = w.WriteString("\\usepackage{fancyhdr}\n")
+	pages, head := false, false
+	for _, f := range fields {
+		pages = pages || strings.Contains(f.text, "{pages}")
+		head = head || (f.command == "fancyhead" && f.text != "")
+	}
+	if pages {
+		_, _ = w.WriteString("\\usepackage{lastpage}\n")
+	}
+	_, _ = w.WriteString("\\pagestyle{fancy}\n\\fancyhf{}\n")
+	placeholders := r.placeholders(meta)
+	for _, f := range fields {
+		if f.text == "" {
+			continue
+		}
+		_, _ = w.WriteString("\\" + f.command + "[" + f.position + "]{")
+		_, _ = w.WriteString(r.expandPlaceholders(f.text, placeholders))
+		_, _ = w.WriteString("}\n")
+	}
+	if !head {
+		_, _ = w.WriteString("\\renewcommand{\\headrulewidth}{0pt}\n")
+	}
+}
+
+// PageNumbering configures the style of page numbers.
+type PageNumbering struct {
+	// Style of page numbers: arabic, roman, Roman, alph or Alph.
+	// Empty keeps the document class default.
+	Style string
+	// FrontMatterStyle numbers the pages before the first heading, such as the
+	// title page, abstract and table of contents, with this style, e.g. roman.
+	// Numbering restarts at 1 in Style, or arabic, on a new page at the first heading.
+	FrontMatterStyle string
+	// NoTitlePageNumber suppresses the page number on the title page.
+	NoTitlePageNumber bool
+	// ResetAtTopHeadings starts a new page and restarts numbering at every top level heading,
+	// as usually done at chapter starts.
+	ResetAtTopHeadings bool
+}
+
+// WithPageNumbering sets the page numbering style.
+func WithPageNumbering(numbering PageNumbering) Option {
+	return func(r *Renderer) {
+		r.PageNumbering = &numbering
+	}
+}
+
+// mainStyle returns the page numbering style of the main matter.
+func (p *PageNumbering) mainStyle() string {
+	if p.Style == "" && p.FrontMatterStyle != "" {
+		return "arabic"
+	}
+	return p.Style
+}
+
+// writePageNumberingStart sets page numbering at the beginning of the document.
+func (r *Renderer) writePageNumberingStart(w util.BufWriter) {
+	p := r.PageNumbering
+	if p == nil {
+		return
+	}
+	if p.FrontMatterStyle != "" {
+		_, _ = w.WriteString("\\pagenumbering{" + p.FrontMatterStyle + "}\n")
+	} else if p.Style != "" {
+		_, _ = w.WriteString("\\pagenumbering{" + p.Style + "}\n")
+	}
+}
+
+// writePageNumberingTitle removes the page number from the title page.
+func (r *Renderer) writePageNumberingTitle(w util.BufWriter) {
+	if r.PageNumbering != nil && r.PageNumbering.NoTitlePageNumber {
+		_, _ = w.WriteString("\\thispagestyle{empty}\n")
+	}
+}
+
+// writePageNumberingHeading switches from front matter to main matter numbering at the
+// first heading and resets numbering at top level headings if requested.
+func (r *Renderer) writePageNumberingHeading(w util.BufWriter, level int) {
+	p := r.PageNumbering
+	if p == nil {
+		return
+	}
+	first := !r.headingSeen
+	r.headingSeen = true
+	if (first && p.FrontMatterStyle != "") || (level == 0 && p.ResetAtTopHeadings) {
+		style := p.mainStyle()
+		if style == "" {
+			style = "arabic"
+		}
+		_, _ = w.WriteString("\n\\clearpage\n\\pagenumbering{" + style + "}\n")
+	}
+}
+
+// WithWatermark prints text diagonally across every page using the
+// draftwatermark package, e.g. WithWatermark("DRAFT").
+func WithWatermark(text string) Option {
+	return func(r *Renderer) {
+		r.Watermark = text
+	}
+}
+
+// WithStamp prints text in red at the bottom of every page, independently of
+// the page headers and footers.
+func WithStamp(text string) Option {
+	return func(r *Renderer) {
+		r.Stamp = text
+	}
+}
+
+// WithConfidentialStamp marks every page as CONFIDENTIAL. It is a shortcut for WithStamp("CONFIDENTIAL").
+func WithConfidentialStamp() Option {
+	return WithStamp("CONFIDENTIAL")
+}
+
+// writeWatermark writes the watermark and stamp configuration.
+func (r *Renderer) writeWatermark(w util.BufWriter) {
+	if r.Watermark != "" {
+		_, _ = w.WriteString("\\usepackage{draftwatermark}\n\\SetWatermarkText{")
+		r.Writer.Write(w, []byte(r.Watermark))
+		_, _ = w.WriteString("}\n\\SetWatermarkScale{1}\n")
+	}
+	if r.Stamp != "" {
+		_, _ = w.WriteString("\\usepackage{xcolor}\n\\usepackage{eso-pic}\n")
+		_, _ = w.WriteString("\\AddToShipoutPictureFG{\\AtPageLowerLeft{\\makebox[\\paperwidth]{\\raisebox{0.5cm}{\\color{red}\\bfseries ")
+		r.Writer.Write(w, []byte(r.Stamp))
+		_, _ = w.WriteString("}}}}\n")
+	}
+}
+
+// BuildInfo identifies the build which produced the document.
+type BuildInfo struct {
+	Version, Commit, Date string
+	// Colophon writes the build information at the end of the document.
+	Colophon bool
+}
+
+// WithBuildInfo records the version, commit and date of the build producing the document,
+// so that they can be referenced in headers and footers through placeholders, or in
+// LaTeX through the \buildversion, \buildcommit, \builddate and \buildinfo commands.
+func WithBuildInfo(version, commit, buildDate string) Option {
+	return func(r *Renderer) {
+		colophon := r.BuildInfo != nil && r.BuildInfo.Colophon
+		r.BuildInfo = &BuildInfo{Version: version, Commit: commit, Date: buildDate, Colophon: colophon}
+	}
+}
+
+// WithColophon writes the build information set with WithBuildInfo at the end of the document.
+func WithColophon(colophon bool) Option {
+	return func(r *Renderer) {
+		if r.BuildInfo == nil {
+			r.BuildInfo = &BuildInfo{}
+		}
+		r.BuildInfo.Colophon = colophon
+	}
+}
+
+// String returns the non empty build information fields separated by em dashes,
+// e.g. "v1.4.2 — abc1234 — 2024-05-01".
+func (b *BuildInfo) String() string {
+	var parts []string
+	for _, part := range []string{b.Version, b.Commit, b.Date} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " — ")
+}
+
+// latex returns the build information escaped by writer, with --- ligatures for em dashes.
+func (b *BuildInfo) latex(writer Writer) string {
+	return strings.ReplaceAll(writer.EscapeString(b.String()), " — ", " --- ")
+}
+
+// writeBuildInfo defines the build information commands in the preamble.
+func (r *Renderer) writeBuildInfo(w util.BufWriter) {
+	b := r.BuildInfo
+	if b == nil {
+		return
+	}
+	for _, c := range []struct{ name, value string }{
+		{"buildversion", b.Version},
+		{"buildcommit", b.Commit},
+		{"builddate", b.Date},
+	} {
+		_, _ = w.WriteString("\\providecommand{\\" + c.name + "}{" + r.Writer.EscapeString(c.value) + "}\n")
+	}
+	_, _ = w.WriteString("\\providecommand{\\buildinfo}{" + b.latex(r.Writer) + "}\n")
+}
+
+// writeColophon writes the build information at the end of the document.
+func (r *Renderer) writeColophon(w util.BufWriter) {
+	if r.BuildInfo == nil || !r.BuildInfo.Colophon {
+		return
+	}
+	_, _ = w.WriteString("\n\\vfill\n\\begin{center}\n\\small Built from \\buildinfo\n\\end{center}\n")
+}
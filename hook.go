@@ -0,0 +1,43 @@
+package latex
+
+import (
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// NodeHook is called around the rendering of nodes, see WithNodeHook.
+type NodeHook func(w util.BufWriter, n ast.Node, entering bool)
+
+// WithNodeHook calls hook before each node is rendered, entering, and after it
+// is rendered, leaving, for cross-cutting additions such as wrapping sections
+// in environments, injecting index entries or instrumentation. Hooks are
+// called in order of addition, and for the nodes rendered by this renderer only.
+func WithNodeHook(hook NodeHook) Option {
+	return func(r *Renderer) {
+		r.NodeHooks = append(r.NodeHooks, hook)
+	}
+}
+
+// hookRegisterer registers node rendering functions wrapped with the node hooks.
+type hookRegisterer struct {
+	renderer.NodeRendererFuncRegisterer
+	hooks []NodeHook
+}
+
+func (h *hookRegisterer) Register(kind ast.NodeKind, fn renderer.NodeRendererFunc) {
+	h.NodeRendererFuncRegisterer.Register(kind, func(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			for _, hook := range h.hooks {
+				hook(w, n, true)
+			}
+		}
+		status, err := fn(w, source, n, entering)
+		if !entering {
+			for _, hook := range h.hooks {
+				hook(w, n, false)
+			}
+		}
+		return status, err
+	})
+}
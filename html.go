@@ -0,0 +1,448 @@
+package latex
+
+import (
+	"bytes"
+	stdhtml "html"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/util"
+)
+
+// htmlTag is an HTML start or end tag.
+type htmlTag struct {
+	name        string
+	end         bool // </name>
+	selfClosing bool // <name/>
+	attributes  map[string]string
+}
+
+// parseHTMLTag parses a single HTML start or end tag. Names are lower cased and
+// attribute values unescaped.
+func parseHTMLTag(raw []byte) (htmlTag, bool) {
+	var t htmlTag
+	s := strings.TrimSpace(string(raw))
+	if len(s) < 3 || s[0] != '<' || s[len(s)-1] != '>' {
+		return t, false
+	}
+	s = s[1 : len(s)-1]
+	if strings.HasPrefix(s, "/") {
+		t.end, s = true, s[1:]
+	}
+	if strings.HasSuffix(s, "/") {
+		t.selfClosing, s = true, s[:len(s)-1]
+	}
+	i := strings.IndexAny(s, " \t\r\n")
+	if i < 0 {
+		i = len(s)
+	}
+	t.name, s = strings.ToLower(s[:i]), s[i:]
+	if t.name == "" || strings.ContainsAny(t.name, "<>!\"'=") {
+		return t, false
+	}
+	t.attributes = make(map[string]string)
+	for {
+		s = strings.TrimLeft(s, " \t\r\n")
+		if s == "" {
+			return t, true
+		}
+		i := strings.IndexAny(s, "= \t\r\n")
+		if i < 0 {
+			t.attributes[strings.ToLower(s)] = ""
+			return t, true
+		}
+		name := strings.ToLower(s[:i])
+		s = strings.TrimLeft(s[i:], " \t\r\n")
+		if !strings.HasPrefix(s, "=") {
+			t.attributes[name] = ""
+			continue
+		}
+		s = strings.TrimLeft(s[1:], " \t\r\n")
+		var value string
+		if s != "" && (s[0] == '"' || s[0] == '\'') {
+			end := strings.IndexByte(s[1:], s[0])
+			if end < 0 {
+				return t, false
+			}
+			value, s = s[1:end+1], s[end+2:]
+		} else {
+			end := strings.IndexAny(s, " \t\r\n")
+			if end < 0 {
+				end = len(s)
+			}
+			value, s = s[:end], s[end:]
+		}
+		t.attributes[name] = stdhtml.UnescapeString(value)
+	}
+}
+
+// inlineHTMLCommands maps the supported inline HTML elements to the LaTeX
+// written for their start tags; end tags close the group with a brace.
+var inlineHTMLCommands = map[string]string{
+	"b":      "\\textbf{",
+	"strong": "\\textbf{",
+	"i":      "\\textit{",
+	"em":     "\\textit{",
+	"u":      "\\underline{",
+	"s":      "\\sout{",
+	"del":    "\\sout{",
+	"strike": "\\sout{",
+	"sub":    "\\textsubscript{",
+	"sup":    "\\textsuperscript{",
+	"code":   "\\texttt{",
+	"small":  "{\\small ",
+}
+
+// rawHTML returns the raw content of an inline HTML node.
+func rawHTML(n *ast.RawHTML, source []byte) []byte {
+	var b []byte
+	for i := 0; i < n.Segments.Len(); i++ {
+		segment := n.Segments.At(i)
+		b = append(b, segment.Value(source)...)
+	}
+	return b
+}
+
+// writeInlineHTML converts the supported inline HTML tags to LaTeX, keeping track
+// of the open elements so that their groups are closed exactly once. It reports
+// whether the tag is supported.
+func (r *Renderer) writeInlineHTML(w util.BufWriter, raw []byte) bool {
+	t, ok := parseHTMLTag(raw)
+	if !ok {
+		return false
+	}
+	if t.name == "br" {
+		if !t.end {
+			_, _ = w.WriteString("\\newline{}")
+		}
+		return true
+	}
+	command, ok := inlineHTMLCommands[t.name]
+	if t.name == "a" || t.name == "span" {
+		ok = true
+	}
+	if !ok || t.selfClosing {
+		return false
+	}
+	if t.end {
+		for i := len(r.inlineHTML) - 1; i >= 0; i-- {
+			if r.inlineHTML[i].name == t.name {
+				// Close the element and any element left open in it.
+				r.closeInlineHTMLFrom(w, i)
+				return true
+			}
+		}
+		return false
+	}
+	end := "}"
+	switch t.name {
+	case "a":
+		href := t.attributes["href"]
+		_, _ = w.WriteString("\\href{")
+		if r.UnsafeLinks || !html.IsDangerousURL([]byte(href)) {
+			r.Writer.Write(w, []byte(href))
+		}
+		command = "}{"
+	case "span":
+		commands := spanCommands(strings.Fields(t.attributes["class"]), t.attributes)
+		command, end = strings.Join(commands, ""), strings.Repeat("}", len(commands))
+	}
+	_, _ = w.WriteString(command)
+	r.inlineHTML = append(r.inlineHTML, htmlContainer{t.name, end})
+	return true
+}
+
+// inlineContainers are the kinds of the nodes closing the inline HTML elements
+// left open in them, such as <b> in a heading, so that braces are balanced.
+var inlineContainers = map[ast.NodeKind]bool{
+	ast.KindParagraph:      true,
+	ast.KindHeading:        true,
+	ast.KindTextBlock:      true,
+	ast.KindLink:           true,
+	ast.KindEmphasis:       true,
+	east.KindStrikethrough: true,
+	east.KindTableCell:     true,
+	east.KindFootnote:      true,
+	KindSpan:               true,
+	KindAttribution:        true,
+}
+
+// inlineHTMLRegisterer registers node rendering functions closing the inline
+// HTML elements left open in inline containers before their end is written.
+type inlineHTMLRegisterer struct {
+	renderer.NodeRendererFuncRegisterer
+	r *Renderer
+}
+
+func (h *inlineHTMLRegisterer) Register(kind ast.NodeKind, fn renderer.NodeRendererFunc) {
+	if !inlineContainers[kind] {
+		h.NodeRendererFuncRegisterer.Register(kind, fn)
+		return
+	}
+	h.NodeRendererFuncRegisterer.Register(kind, func(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+		r := h.r
+		if entering {
+			r.inlineDepths = append(r.inlineDepths, len(r.inlineHTML))
+		} else if i := len(r.inlineDepths) - 1; i >= 0 {
+			r.closeInlineHTMLFrom(w, min(r.inlineDepths[i], len(r.inlineHTML)))
+			r.inlineDepths = r.inlineDepths[:i]
+		}
+		return fn(w, source, n, entering)
+	})
+}
+
+// closeInlineHTML closes the inline HTML elements left open at the end of a block.
+func (r *Renderer) closeInlineHTML(w util.BufWriter) {
+	r.closeInlineHTMLFrom(w, 0)
+}
+
+// closeInlineHTMLFrom closes the inline HTML elements open from the given depth.
+func (r *Renderer) closeInlineHTMLFrom(w util.BufWriter, depth int) {
+	for i := len(r.inlineHTML) - 1; i >= depth; i-- {
+		_, _ = w.WriteString(r.inlineHTML[i].end)
+	}
+	r.inlineHTML = r.inlineHTML[:depth]
+}
+
+// htmlToken is either a tag or a run of text of an HTML fragment.
+type htmlToken struct {
+	text  string
+	tag   htmlTag
+	isTag bool
+	raw   string
+}
+
+// htmlTokens splits an HTML fragment into tags and text, dropping comments.
+func htmlTokens(s string) []htmlToken {
+	var tokens []htmlToken
+	for s != "" {
+		if strings.HasPrefix(s, "<!--") {
+			end := strings.Index(s, "-->")
+			if end < 0 {
+				break
+			}
+			s = s[end+3:]
+			continue
+		}
+		if s[0] == '<' {
+			if end := strings.IndexByte(s, '>'); end > 0 {
+				if t, ok := parseHTMLTag([]byte(s[:end+1])); ok {
+					tokens = append(tokens, htmlToken{tag: t, isTag: true, raw: s[:end+1]})
+					s = s[end+1:]
+					continue
+				}
+			}
+		}
+		end := strings.IndexByte(s[1:], '<') + 1
+		if end == 0 {
+			end = len(s)
+		}
+		tokens = append(tokens, htmlToken{text: s[:end]})
+		s = s[end:]
+	}
+	return tokens
+}
+
+// writeHTMLText writes the text and the supported inline tags of an HTML fragment,
+// collapsing white space. Unknown tags are dropped and line breaks become spaces
+// when breaks is false.
+func (r *Renderer) writeHTMLText(w util.BufWriter, tokens []htmlToken, breaks bool) {
+	for _, t := range tokens {
+		if !t.isTag {
+			text := stdhtml.UnescapeString(t.text)
+			fields := strings.Fields(text)
+			if len(fields) == 0 {
+				if text != "" {
+					_ = w.WriteByte(' ')
+				}
+				continue
+			}
+			if strings.TrimLeft(text, " \t\r\n") != text {
+				_ = w.WriteByte(' ')
+			}
+			r.Writer.Write(w, []byte(strings.Join(fields, " ")))
+			if strings.TrimRight(text, " \t\r\n") != text {
+				_ = w.WriteByte(' ')
+			}
+			continue
+		}
+		if t.tag.name == "br" && !breaks {
+			_ = w.WriteByte(' ')
+			continue
+		}
+		r.writeInlineHTML(w, []byte(t.raw))
+	}
+	r.closeInlineHTML(w)
+}
+
+// htmlContainer is an open HTML element with the LaTeX closing it.
+type htmlContainer struct {
+	name, end string
+}
+
+// firstHTMLTag returns the first tag of an HTML block which starts with a tag.
+func firstHTMLTag(block []byte) (htmlTag, bool) {
+	for _, t := range htmlTokens(string(block)) {
+		if !t.isTag {
+			if strings.TrimSpace(t.text) != "" {
+				return htmlTag{}, false
+			}
+			continue
+		}
+		return t.tag, true
+	}
+	return htmlTag{}, false
+}
+
+// isHTMLDetails reports whether an HTML block opens or closes a <details> element.
+func isHTMLDetails(block []byte) bool {
+	t, ok := firstHTMLTag(block)
+	return ok && t.name == "details"
+}
+
+// writeHTMLContainers writes the <details> and <div> elements starting an HTML
+// block, which may span several blocks with markdown content between their start
+// and end tags. Details, which have no collapsible equivalent in print, become a
+// tcolorbox titled with their <summary>; divs become the environment their class
+// is mapped to, if any. It reports whether the block was such a block.
+func (r *Renderer) writeHTMLContainers(w util.BufWriter, block []byte) bool {
+	if t, ok := firstHTMLTag(block); !ok || (t.name != "details" && t.name != "div") {
+		return false
+	}
+	tokens := htmlTokens(string(block))
+	var text []htmlToken
+	flush := func() {
+		if len(bytes.TrimSpace([]byte(tokensText(text)))) > 0 {
+			_ = w.WriteByte('\n')
+			r.writeHTMLText(w, text, true)
+			_ = w.WriteByte('\n')
+		}
+		text = nil
+	}
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.isTag && t.tag.name == "details" && !t.tag.end:
+			flush()
+			var summary []htmlToken
+			j := i + 1
+			for j < len(tokens) && !tokens[j].isTag && strings.TrimSpace(tokens[j].text) == "" {
+				j++
+			}
+			if j < len(tokens) && tokens[j].isTag && tokens[j].tag.name == "summary" && !tokens[j].tag.end {
+				for j++; j < len(tokens) && !(tokens[j].isTag && tokens[j].tag.name == "summary"); j++ {
+					summary = append(summary, tokens[j])
+				}
+				i = j
+			}
+			_, _ = w.WriteString("\n\\begin{tcolorbox}")
+			if len(summary) > 0 {
+				_, _ = w.WriteString("[title={")
+				r.writeHTMLText(w, summary, false)
+				_, _ = w.WriteString("}]")
+			}
+			_ = w.WriteByte('\n')
+			r.htmlContainers = append(r.htmlContainers, htmlContainer{"details", "\\end{tcolorbox}\n"})
+		case t.isTag && t.tag.name == "div" && !t.tag.end:
+			flush()
+			c := htmlContainer{name: "div"}
+			if env, ok := r.divEnvironment(strings.Fields(t.tag.attributes["class"])); ok {
+				_, _ = w.WriteString("\n\\begin{" + env + "}\n")
+				c.end = "\\end{" + env + "}\n"
+			}
+			r.htmlContainers = append(r.htmlContainers, c)
+		case t.isTag && (t.tag.name == "details" || t.tag.name == "div"):
+			flush()
+			for j := len(r.htmlContainers) - 1; j >= 0; j-- {
+				if r.htmlContainers[j].name == t.tag.name {
+					r.closeHTMLContainers(w, j)
+					break
+				}
+			}
+		case t.isTag && t.tag.name == "summary":
+		default:
+			text = append(text, t)
+		}
+	}
+	flush()
+	return true
+}
+
+// closeHTMLContainers closes the HTML elements open from the given depth, such as
+// those left open at the end of the document.
+func (r *Renderer) closeHTMLContainers(w util.BufWriter, depth int) {
+	for i := len(r.htmlContainers) - 1; i >= depth; i-- {
+		_, _ = w.WriteString(r.htmlContainers[i].end)
+	}
+	r.htmlContainers = r.htmlContainers[:depth]
+}
+
+// tokensText returns the raw text of tokens.
+func tokensText(tokens []htmlToken) string {
+	var b strings.Builder
+	for _, t := range tokens {
+		b.WriteString(t.text + t.raw)
+	}
+	return b.String()
+}
+
+// htmlImages returns the images of an HTML block made only of images and their
+// wrappers, such as <p align="center"><img src="logo.png"></p>.
+func htmlImages(block []byte) ([]htmlTag, bool) {
+	var images []htmlTag
+	for _, t := range htmlTokens(string(block)) {
+		if !t.isTag {
+			if strings.TrimSpace(t.text) != "" {
+				return nil, false
+			}
+			continue
+		}
+		switch t.tag.name {
+		case "img":
+			if !t.tag.end {
+				images = append(images, t.tag)
+			}
+		case "p", "div", "center", "a", "span", "picture", "source", "br":
+		default:
+			return nil, false
+		}
+	}
+	return images, len(images) > 0
+}
+
+// writeHTMLImage writes an <img> element as a figure, like markdown images. Its
+// width is a percentage of the text width or a number of pixels, other widths
+// being ignored, the title is
+// used as caption, the id as label and the margin or fullwidth class as figure
+// class, unless they are set as query parameters of the source.
+func (r *Renderer) writeHTMLImage(w util.BufWriter, t htmlTag) {
+	path, attributes := r.imageAttributes(w, t.attributes["src"])
+	if width := strings.TrimSpace(t.attributes["width"]); width != "" {
+		number := strings.TrimSuffix(strings.TrimSuffix(width, "%"), "px")
+		if !isNumber(number) {
+			r.warnAt(nil, nil, -1, DiagnosticUnsafeContent, "image %s width %s ignored", path, width)
+		} else if strings.HasSuffix(width, "%") {
+			percent, _ := strconv.ParseFloat(number, 64)
+			attributes["width"] = strconv.FormatFloat(percent/100, 'f', -1, 64)
+		} else {
+			attributes["width"] = number + "px"
+		}
+	}
+	if _, ok := attributes["caption"]; !ok && t.attributes["title"] != "" {
+		attributes["caption"] = r.Writer.EscapeString(t.attributes["title"])
+	}
+	if _, ok := attributes["label"]; !ok && t.attributes["id"] != "" {
+		attributes["label"] = t.attributes["id"]
+	}
+	for _, class := range strings.Fields(t.attributes["class"]) {
+		if _, ok := attributes["class"]; !ok && (class == "margin" || class == "fullwidth") {
+			attributes["class"] = class
+		}
+	}
+	r.writeFigure(w, path, attributes, []byte(t.attributes["alt"]))
+	putAttributes(attributes)
+}
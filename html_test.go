@@ -0,0 +1,211 @@
+package latex_test
+
+import (
+	"strings"
+	"testing"
+
+	latex "github.com/dihedron/goldmark-latex"
+)
+
+func TestInlineHTML(t *testing.T) {
+	output := convert(t, "H<sub>2</sub>O, x<sup>2</sup>, <b>bold <i>both</i></b>, <s>old</s>, <code>go</code>,\n"+
+		"<u>under</u>, <small>fine</small>, <span>kept</span> and <a href=\"https://example.com/?a=1&amp;b=2\">link</a><br>\n"+
+		"<b>unclosed\n\n<a href=\"javascript:alert(1)\">bad</a>\n", latex.WithDynamicPreamble(true))
+	for _, want := range []string{
+		"H\\textsubscript{2}O, x\\textsuperscript{2}, \\textbf{bold \\textit{both}}, \\sout{old}, \\texttt{go},",
+		"\\underline{under}, {\\small fine}, ",
+		"kept",
+		"\\href{https://example.com/?a=1\\&b=2}{link}\\newline{}",
+		"\\textbf{unclosed}\n",
+		"\\href{}{bad}",
+		"\\usepackage[normalem]{ulem}",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "<span>") {
+		t.Errorf("unknown tag written:\n%s", output)
+	}
+}
+
+func TestInlineHTMLClosed(t *testing.T) {
+	output := convert(t, "# Title <b>bold\n\n- <i>x\n- y\n\nA [<b>link](https://example.com) and <u>text.\n")
+	for _, want := range []string{
+		"{Title \\textbf{bold}}{Title bold}}",
+		"\\textit{x}\n",
+		"\\textbf{link}}",
+		"\\underline{text.}\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestHTMLTable(t *testing.T) {
+	output := convert(t, `<table>
+<caption>Results &amp; totals</caption>
+<tr><th>Name</th><th align="right">Score</th><th>Notes</th></tr>
+<tr><td rowspan="2">Alice</td><td align="right">10</td><td><b>good</b></td></tr>
+<tr><td style="text-align: right">12</td><td>50%</td></tr>
+<tr><td colspan="2" align="center">Total</td><td>22</td></tr>
+</table>
+`)
+	for _, want := range []string{
+		"\\begin{table}[htbp]",
+		"\\begin{tabular}{|l|r|l|}",
+		"\\textbf{Name} & \\textbf{Score} & \\textbf{Notes} \\\\\n\\hline\n",
+		"\\multirow{2}{*}{Alice} & 10 & \\textbf{good} \\\\\n\\cline{2-3}\n",
+		" & 12 & 50\\% \\\\\n\\hline\n",
+		"\\multicolumn{2}{|c|}{Total} & 22 \\\\\n",
+		"\\caption{Results \\& totals}",
+		"\\usepackage{multirow}",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "unsupported") {
+		t.Errorf("table skipped:\n%s", output)
+	}
+}
+
+func TestHTMLTableColspan(t *testing.T) {
+	output := convert(t, "<table>\n<tr><td colspan=\"100000000\">Wide</td></tr>\n</table>\n")
+	if !strings.Contains(output, "\\multicolumn{1000}{|l|}{Wide}") {
+		t.Errorf("expected the span bounded, got:\n%s", output)
+	}
+}
+
+func TestHTMLDetails(t *testing.T) {
+	output := convert(t, "<details>\n<summary>Show <b>more</b></summary>\n\nHidden *content*.\n\n</details>\n\n"+
+		"<details><summary>Inline</summary>Plain &amp; simple</details>\n")
+	for _, want := range []string{
+		"\\begin{tcolorbox}[title={Show \\textbf{more}}]\n",
+		"Hidden \\textit{content}.",
+		"\\end{tcolorbox}\n",
+		"\\begin{tcolorbox}[title={Inline}]\n\nPlain \\& simple\n\\end{tcolorbox}\n",
+		"\\usepackage{tcolorbox}",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestHTMLImage(t *testing.T) {
+	output := convert(t, "<p align=\"center\">\n<img src=\"logo.png?label=fig:logo\" alt=\"Logo\" width=\"50%\" title=\"The logo\">\n</p>\n\n"+
+		"Inline <img src=\"icon.png\" width=\"32\"> icon.\n", latex.WithDynamicPreamble(true))
+	for _, want := range []string{
+		"\\includegraphics[width=0.5\\textwidth]{logo.png}\n\t\\caption{The logo}\n\t\\label {fig:logo}",
+		"\\includegraphics[width=32px]{icon.png}",
+		"\\usepackage{graphicx}",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestHTMLImageInjection(t *testing.T) {
+	output := convert(t, "<img src=\"a.png\" width=\"1pt]{a}\\immediate\\write18{touch /tmp/pwn}\\iffalse\" id=\"x}\\input{/etc/passwd}\">\n\n"+
+		"<img src=\"b.png\" width=\"auto\">\n")
+	for _, unexpected := range []string{"\\write18", "\\input", "auto"} {
+		if strings.Contains(output, unexpected) {
+			t.Errorf("unexpected %q in output, got:\n%s", unexpected, output)
+		}
+	}
+	for _, want := range []string{"\\label {xinputetcpasswd}", "\\includegraphics[width=\\textwidth]{b.png}"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestRawLaTeXComments(t *testing.T) {
+	markdown := "Before <!-- latex: \\textsc{small caps} --> after.\n\n<!-- latex: \\vspace{1cm} -->\n\n<!-- tex: \\hfill -->\n"
+	output := convert(t, markdown)
+	if strings.Contains(output, "\\textsc") || strings.Contains(output, "\\vspace") {
+		t.Errorf("raw LaTeX written without unsafe rendering:\n%s", output)
+	}
+	output = convert(t, markdown, latex.WithRenderUnsafeElements(true))
+	for _, want := range []string{"Before \\textsc{small caps} after.", "\n\\vspace{1cm}\n"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+	output = convert(t, markdown, latex.WithRenderUnsafeElements(true), latex.WithRawLaTeXPrefix("tex:"))
+	if !strings.Contains(output, "\n\\hfill\n") || strings.Contains(output, "\\vspace") {
+		t.Errorf("custom prefix not used:\n%s", output)
+	}
+}
+
+func TestRawAttributes(t *testing.T) {
+	markdown := "A `\\LaTeX`{=latex} logo, `<b>x</b>`{=html} and `code`.\n\n" +
+		"```{=latex}\n\\begin{center}\n$x$ & y\n\\end{center}\n```\n\n" +
+		"::: {=latex}\n\\vspace{2cm}\n\n\\noindent\n:::\n"
+	output := convert(t, markdown, latex.WithRenderUnsafeElements(true))
+	for _, want := range []string{
+		"A \\LaTeX logo,  and \\texttt{code}.",
+		"\n\\begin{center}\n$x$ & y\n\\end{center}\n",
+		"\n\\vspace{2cm}\n\n\\noindent\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "<b>") || strings.Contains(output, "minted") {
+		t.Errorf("raw content misrendered:\n%s", output)
+	}
+	if output := convert(t, markdown); strings.Contains(output, "\\vspace") || strings.Contains(output, "\\LaTeX") {
+		t.Errorf("raw LaTeX written without unsafe rendering:\n%s", output)
+	}
+}
+
+func TestAllowedLaTeXCommands(t *testing.T) {
+	markdown := "See \\index{term} and \\input{/etc/passwd}, \\vspace{\\input{x}} here.\n\n" +
+		"<!-- latex: \\newpage -->\n\n<!-- latex: \\write18{rm} -->\n\n<!-- latex: }} -->\n"
+	output := convert(t, markdown, latex.WithAllowedLaTeXCommands([]string{"index", "\\newpage", "vspace"}))
+	for _, want := range []string{
+		"See \\index{term} and \\textbackslash",
+		"\n\\newpage\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+	for _, unwanted := range []string{"\\input{", "\\write18", "\n}}\n"} {
+		if strings.Contains(output, unwanted) {
+			t.Errorf("output contains %q:\n%s", unwanted, output)
+		}
+	}
+}
+
+func TestSpans(t *testing.T) {
+	output := convert(t, "A [small *caps*]{.smallcaps} word, [blue]{color=blue}, [hex]{color=\"#f00\"}, [not a span] and\n"+
+		"<span style=\"color: red; font-weight: bold\">red bold</span> or <span class=\"smallcaps\">sc</span>.\n",
+		latex.WithDynamicPreamble(true))
+	for _, want := range []string{
+		"A \\textsc{small \\textit{caps}} word, \\textcolor{blue}{blue}, \\textcolor[HTML]{FF0000}{hex}, [not a span] and",
+		"\\textcolor{red}{\\textbf{red bold}} or \\textsc{sc}.",
+		"\\usepackage{xcolor}",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestMarginNotes(t *testing.T) {
+	output := convert(t, "Text[A *margin* note.]{.margin} and[Aside.]{.sidenote} <span class=\"marginpar\">par</span>.\n")
+	for _, want := range []string{
+		"Text\\marginnote{A \\textit{margin} note.} and\\sidenote{Aside.} \\marginpar{par}.",
+		"\\usepackage{marginnote}",
+		"\\usepackage{sidenotes}",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+}
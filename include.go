@@ -0,0 +1,90 @@
+package latex
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// WithIncludes enables the <!-- include: path --> directive, which renders the
+// markdown file found at path in fsys in place of the comment. Paths are relative
+// to the including file, the root of fsys for the document itself, and may not
+// leave the including file's directory unless includes are trusted, see
+// UnsafeOptions. Included files
+// are parsed with p, or goldmark's default parser with heading attributes if nil,
+// and rendered by this renderer only.
+func WithIncludes(fsys fs.FS, p parser.Parser) Option {
+	return func(r *Renderer) {
+		r.IncludeFS = fsys
+		r.IncludeParser = p
+	}
+}
+
+// include renders the markdown file at name, relative to the file being rendered.
+func (r *Renderer) include(w util.BufWriter, name string) error {
+	if r.IncludeFS == nil {
+		return nil
+	}
+	dir := "."
+	if len(r.including) > 0 {
+		dir = path.Dir(r.including[len(r.including)-1])
+	}
+	name = path.Join(dir, name)
+	if !r.UnsafeIncludes && dir != "." && !strings.HasPrefix(name, dir+"/") {
+		return fmt.Errorf("latex: include: %s is outside %s", name, dir)
+	}
+	for _, including := range r.including {
+		if including == name {
+			return fmt.Errorf("latex: include cycle: %s -> %s", strings.Join(r.including, " -> "), name)
+		}
+	}
+	source, err := fs.ReadFile(r.IncludeFS, name)
+	if err != nil {
+		return fmt.Errorf("latex: include: %w", err)
+	}
+	p := r.IncludeParser
+	if p == nil {
+		p = goldmark.DefaultParser()
+		p.AddOptions(parser.WithHeadingAttribute())
+	}
+	doc := p.Parse(text.NewReader(source))
+	r.including = append(r.including, name)
+	defer func() { r.including = r.including[:len(r.including)-1] }()
+	r.comment(w, "include %s", name)
+	// The included document's content is rendered without the document itself,
+	// which would write another preamble.
+	return r.renderSiblings(w, source, doc.FirstChild())
+}
+
+// renderSiblings renders a node and the siblings following it out of the
+// document walk.
+func (r *Renderer) renderSiblings(w util.BufWriter, source []byte, first ast.Node) error {
+	for n := first; n != nil; n = n.NextSibling() {
+		if err := r.renderNode(w, source, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderNode renders a node out of the document walk.
+func (r *Renderer) renderNode(w util.BufWriter, source []byte, n ast.Node) error {
+	if r.nodeRenderer == nil {
+		r.nodeRenderer = renderer.NewRenderer(renderer.WithNodeRenderers(util.Prioritized(r, 1000)))
+	}
+	return r.nodeRenderer.Render(w, source, n)
+}
+
+// includeDirective returns the path of an <!-- include: path --> directive.
+func includeDirective(directive string) (string, bool) {
+	name, ok := strings.CutPrefix(directive, "include:")
+	return strings.TrimSpace(name), ok && strings.TrimSpace(name) != ""
+}
@@ -0,0 +1,162 @@
+package latex
+
+import (
+	"strings"
+
+	"github.com/yuin/goldmark/util"
+)
+
+// WithLanguage sets the document language, followed by any other languages
+// used in the document. Languages are babel/polyglossia names such as
+// "english" or "german". Babel is used with pdfLaTeX and polyglossia with
+// Unicode engines, so hyphenation, quotes and generated names such as
+// "Figure" match the document language.
+func WithLanguage(main string, others ...string) Option {
+	return func(r *Renderer) {
+		r.Languages = append([]string{main}, others...)
+	}
+}
+
+// languageNames maps ISO 639-1 codes, as found in the "lang" metadata, to babel/polyglossia names.
+var languageNames = map[string]string{
+	"ar": "arabic",
+	"ca": "catalan",
+	"cs": "czech",
+	"da": "danish",
+	"de": "german",
+	"el": "greek",
+	"en": "english",
+	"es": "spanish",
+	"fa": "persian",
+	"fi": "finnish",
+	"fr": "french",
+	"he": "hebrew",
+	"hu": "hungarian",
+	"it": "italian",
+	"ja": "japanese",
+	"nl": "dutch",
+	"no": "norsk",
+	"pl": "polish",
+	"pt": "portuguese",
+	"ro": "romanian",
+	"ru": "russian",
+	"sv": "swedish",
+	"tr": "turkish",
+	"uk": "ukrainian",
+	"ur": "urdu",
+	"yi": "yiddish",
+}
+
+// languageName returns the babel name of a language given as a name
+// or as a language tag such as "de" or "en-US".
+func languageName(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	code, _, _ := strings.Cut(strings.ReplaceAll(lang, "_", "-"), "-")
+	if name, ok := languageNames[code]; ok {
+		return name
+	}
+	return lang
+}
+
+// languageTag returns the language tag of the document, as found in the "lang"
+// metadata or derived from the main language name. A "lang" value which is
+// not a BCP 47 tag is ignored, since it is written in key=value lists.
+func (r *Renderer) languageTag(meta map[string]any) string {
+	if lang, ok := metaString(meta, "lang"); ok && isLanguageTag(lang) {
+		return lang
+	}
+	languages := r.documentLanguages(meta)
+	if len(languages) == 0 {
+		return ""
+	}
+	for code, name := range languageNames {
+		if name == languages[0] {
+			return code
+		}
+	}
+	return ""
+}
+
+// documentLanguages returns the configured languages or, if none, the
+// language found under the "lang" metadata key, without the names which
+// cannot be language names, see checkedLanguages.
+func (r *Renderer) documentLanguages(meta map[string]any) []string {
+	languages, _ := r.checkedLanguages(meta)
+	return languages
+}
+
+// checkedLanguages returns the languages of the document and the names which
+// are dropped since they are not made of lowercase letters, and could inject
+// LaTeX in the options of babel.
+func (r *Renderer) checkedLanguages(meta map[string]any) (languages, unsafe []string) {
+	names := r.Languages
+	if len(names) == 0 {
+		if lang, ok := metaString(meta, "lang", "language"); ok && lang != "" {
+			names = []string{languageName(lang)}
+		}
+	}
+	for _, name := range names {
+		if isLanguageName(name) {
+			languages = append(languages, name)
+		} else {
+			unsafe = append(unsafe, name)
+		}
+	}
+	return languages, unsafe
+}
+
+// writeLanguages loads babel or polyglossia for the given languages, the first
+// being the main one, and the bidi support of right to left text.
+func (r *Renderer) writeLanguages(w util.BufWriter, languages []string) {
+	if r.bidi && !r.Engine.IsUnicode() {
+		r.warnComment(w, "right to left languages require XeLaTeX or LuaLaTeX for proper support")
+	}
+	defer r.writeBidi(w, languages)
+	if len(languages) == 0 {
+		return
+	}
+	if r.Engine.IsUnicode() {
+		_, _ = w.WriteString("\\usepackage{polyglossia}\n\\setdefaultlanguage{")
+		_, _ = w.WriteString(languages[0])
+		_, _ = w.WriteString("}\n")
+		if len(languages) > 1 {
+			_, _ = w.WriteString("\\setotherlanguages{")
+			_, _ = w.WriteString(strings.Join(languages[1:], ","))
+			_, _ = w.WriteString("}\n")
+		}
+	} else {
+		// Babel makes the last language the main one.
+		_, _ = w.WriteString("\\usepackage[")
+		for _, language := range languages[1:] {
+			_, _ = w.WriteString(language)
+			_ = w.WriteByte(',')
+		}
+		_, _ = w.WriteString("main=")
+		_, _ = w.WriteString(languages[0])
+		_, _ = w.WriteString("]{babel}\n")
+	}
+	_, _ = w.WriteString("\\usepackage[autostyle]{csquotes}\n")
+}
+
+// writeBidi loads the bidi support of Unicode engines, when the document has
+// right to left text but no right to left language for polyglossia to load it,
+// and sets the main direction to right to left if the main language does not.
+func (r *Renderer) writeBidi(w util.BufWriter, languages []string) {
+	if !r.bidi || !r.Engine.IsUnicode() {
+		return
+	}
+	loaded := false
+	for _, language := range languages {
+		loaded = loaded || rtlLanguages[language]
+	}
+	if !loaded {
+		if r.Engine == LuaLaTeX {
+			_, _ = w.WriteString("\\usepackage{luabidi}\n")
+		} else {
+			_, _ = w.WriteString("\\usepackage{bidi}\n")
+		}
+	}
+	if r.rtl && (len(languages) == 0 || !rtlLanguages[languages[0]]) {
+		_, _ = w.WriteString("\\AtBeginDocument{\\setRTL}\n")
+	}
+}
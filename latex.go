@@ -1,43 +1,96 @@
 package latex
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	_ "embed"
 	"fmt"
 	"io"
-	"log"
+	"io/fs"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"unicode"
 	"unicode/utf8"
 
 	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
 	"github.com/yuin/goldmark/renderer"
 	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
 	"github.com/yuin/goldmark/util"
 )
 
 // Renderer is a LaTeX renderer implementation for extending
-// goldmark to generate .tex files.
+// goldmark to generate .tex files. Once configured, it can render documents
+// concurrently, each document being rendered by a session of its own.
 type Renderer struct {
-	// Increase heading levels: if the offset is 1, \section (1) becomes \subsection (2) etc.
-	// Negative offset is also valid.
-	// Resulting levels are clipped between 1 and 6.
-	HeadingLevelOffset int
-	// Removes section numbering.
-	NoHeadingNumbering bool
-	// Replace the default preamble by setting this to a non-nil byte slice.
-	// Should NOT end with \begin{document}, this is added automatically.
-	Preamble []byte
-	// If set renderer will render possibly unsafe elements, such as links and
-	// code block raw content.
-	Unsafe bool
-	// Declares all used unicode characters in the preamble
-	// and replaces them with the result of this function.
-	DeclareUnicode func(rune) (raw string, isReplaced bool)
-	// makeTitle determines whether a \maketitle will be injected at the beginning of the document.
-	makeTitle bool
+	Config
+	renderState
+	// unshared is set for the renderers keeping the state of their renderings
+	// themselves: the sessions of a shared renderer and the renderers of books.
+	unshared bool
+	// sessions holds the renderers of the documents being rendered, by writer.
+	sessions sync.Map
+	// results holds the diagnostics of the renderings of ConvertDiagnostics,
+	// by writer.
+	results sync.Map
+	// funcs are the node rendering functions of a session, by kind.
+	funcs []renderer.NodeRendererFunc
+	// mu guards the Config, lazily completed by loadPreamble, and the
+	// diagnostics of the last rendering of a shared renderer.
+	mu sync.Mutex
+}
+
+// renderState is the state of a rendering, which the sessions of a Renderer
+// hold so that it can render documents concurrently.
+type renderState struct {
+	// rtl is set when the current document is mainly written right to left
+	// and bidi when it uses any right to left language.
+	rtl, bidi bool
+	// headingSeen is set once the first heading of the current document has
+	// been rendered, switching to the page numbering of the main matter.
+	headingSeen bool
+	// abstract is the abstract section written in the front matter.
+	abstract *Abstract
+	// inAppendix is set once \appendix has been emitted in the current document.
+	inAppendix bool
+	// matter is the matter of the current document being rendered.
+	matter matter
+	// book is the book being rendered, if any.
+	book *book
+	// including is the stack of the files being included.
+	including []string
+	// nodeRenderer renders nodes out of the document walk: the content of
+	// included files and footnotes.
+	nodeRenderer renderer.Renderer
+	// inlineHTML is the stack of the inline HTML elements open in the current block.
+	inlineHTML []htmlContainer
+	// inlineDepths is the stack of the depths of inlineHTML at the start of the
+	// inline containers being rendered.
+	inlineDepths []int
+	// quoteDepth is the number of blockquotes being rendered.
+	quoteDepth int
+	// skipText is the source of the text which is not rendered, such as alert markers.
+	skipText []text.Segment
+	// htmlContainers is the stack of the open HTML elements spanning blocks.
+	htmlContainers []htmlContainer
+	// sections counts the top level sections of the current document marked for splitting.
+	sections int
+	// frame and beamerBlock are set while a beamer frame and block are open.
+	frame, beamerBlock bool
+	// diagnostics are the warnings about the document being rendered.
+	diagnostics []Diagnostic
+	// ctx is the context of the rendering, see ConvertContext.
+	ctx context.Context
+	// line is the source line of lineOffset, see WithSourceLines.
+	line, lineOffset int
+	// tee writes the output to the writers of WithTee as well.
+	tee *teeWriter
+	// limited counts the output, see WithLimits.
+	limited *limitWriter
 }
 
 // Option is the type for functional options.
@@ -55,13 +108,44 @@ type Option func(*Renderer)
 //	md := goldmark.New(goldmark.WithRenderer(r))
 //	md.Convert(markdown, LaTeXoutput)
 func NewRenderer(options ...Option) *Renderer {
-	r := &Renderer{}
+	r := &Renderer{Config: NewConfig()}
 	for _, option := range options {
 		option(r)
 	}
 	return r
 }
 
+// WithStandalone controls whether a complete document is generated. When false,
+// only the translated body is written, without preamble and document environment,
+// so that the output can be included in an existing LaTeX project with \input.
+func WithStandalone(standalone bool) Option {
+	return func(r *Renderer) {
+		r.Fragment = !standalone
+	}
+}
+
+// WithBodyPrefix inserts raw LaTeX right after \begin{document}.
+func WithBodyPrefix(prefix []byte) Option {
+	return func(r *Renderer) {
+		r.BodyPrefix = prefix
+	}
+}
+
+// WithAfterTitle inserts raw LaTeX after the title, e.g. acknowledgements or
+// \tableofcontents. It is only written when the title is.
+func WithAfterTitle(latex []byte) Option {
+	return func(r *Renderer) {
+		r.AfterTitle = latex
+	}
+}
+
+// WithBodySuffix inserts raw LaTeX before \end{document}, e.g. \printbibliography.
+func WithBodySuffix(suffix []byte) Option {
+	return func(r *Renderer) {
+		r.BodySuffix = suffix
+	}
+}
+
 func WithMakeTitle(value bool) Option {
 	return func(r *Renderer) {
 		r.makeTitle = value
@@ -74,40 +158,117 @@ func WithHeadingLevelOffset(offset int) Option {
 	}
 }
 
+// WithMaxHeadingLevel limits the depth of headings, deeper headings being
+// typeset at the given level, from 1 for \section to 6, as some classes
+// require.
+func WithMaxHeadingLevel(level int) Option {
+	return func(r *Renderer) {
+		r.MaxHeadingLevel = level
+	}
+}
+
+// WithHeadingCommands sets the commands typesetting the heading levels, without
+// backslash, from the top level down, e.g. "chapter", "section", for classes
+// with their own sectioning commands. Unnumbered headings use the starred
+// commands. The levels without command are typeset as usual.
+func WithHeadingCommands(commands ...string) Option {
+	return func(r *Renderer) {
+		r.HeadingCommands = commands
+	}
+}
+
 func WithNoHeadingNumbering(nonumbering bool) Option {
 	return func(r *Renderer) {
 		r.NoHeadingNumbering = nonumbering
 	}
 }
 
+// WithAppendix sets the text of the heading that starts the appendix: from
+// that heading on, sections are lettered A, B, C and so on.
+func WithAppendix(heading string) Option {
+	return func(r *Renderer) {
+		r.AppendixHeading = heading
+	}
+}
+
+// WithPreamble replaces the default preamble, and the preamble file set by an
+// earlier option.
 func WithPreamble(preamble []byte) Option {
 	return func(r *Renderer) {
 		r.Preamble = preamble
+		r.PreambleFile, r.PreambleFS = "", nil
 	}
 }
 
+// WithPreambleFile replaces the default preamble, and the preamble set by an
+// earlier option, with the content of a file, read when the first document is
+// rendered: failing to read it makes the rendering fail.
 func WithPreambleFile(path string) Option {
 	return func(r *Renderer) {
-		var p *os.File
-		var err error
-		if p, err = os.Open(path); err != nil {
-			// TODO: do not panic
-			log.Fatalf("error opening preamble file: %v", err)
-		}
-		defer p.Close()
+		r.Preamble, r.PreambleFS = nil, nil
+		r.PreambleFile = path
+	}
+}
 
-		preamble, err := io.ReadAll(p)
-		if err != nil {
-			// TODO: do not panic
-			log.Fatalf("error reading preamble file: %v", err)
-		}
-		r.Preamble = preamble
+// WithPreambleFS replaces the default preamble, and the preamble set by an
+// earlier option, with the content of a file of a file system, such as an
+// embed.FS, read when the first document is rendered.
+func WithPreambleFS(fsys fs.FS, path string) Option {
+	return func(r *Renderer) {
+		r.Preamble = nil
+		r.PreambleFS = fsys
+		r.PreambleFile = path
 	}
 }
 
+// loadPreamble reads the preamble file, if any and not read yet.
+func (r *Renderer) loadPreamble() error {
+	if r.PreambleFile == "" || r.Preamble != nil {
+		return nil
+	}
+	var preamble []byte
+	var err error
+	if r.PreambleFS != nil {
+		preamble, err = fs.ReadFile(r.PreambleFS, r.PreambleFile)
+	} else {
+		preamble, err = os.ReadFile(r.PreambleFile)
+	}
+	if err != nil {
+		return fmt.Errorf("latex: preamble: %w", err)
+	}
+	r.Preamble = preamble
+	return nil
+}
+
+// WithRenderUnsafeElements renders all the categories of possibly unsafe
+// content, see WithUnsafe.
 func WithRenderUnsafeElements(unsafe bool) Option {
+	return WithUnsafe(UnsafeOptions{Links: unsafe, RawLaTeX: unsafe, CodeContent: unsafe, Includes: unsafe})
+}
+
+// UnsafeOptions selects the categories of possibly unsafe content which are
+// rendered, since trusting raw LaTeX and trusting links are different decisions.
+type UnsafeOptions struct {
+	// Links keeps link destinations with dangerous URLs, such as javascript:.
+	Links bool
+	// RawLaTeX writes raw LaTeX from HTML comments and raw attributes, and
+	// math using commands which read or write files, run programs or
+	// redefine TeX, such as \input or \write18.
+	RawLaTeX bool
+	// CodeContent writes code block lines containing \end, which could close
+	// the verbatim environment.
+	CodeContent bool
+	// Includes lets included files include files outside their directory.
+	Includes bool
+}
+
+// WithUnsafe sets the categories of possibly unsafe content to render.
+func WithUnsafe(unsafe UnsafeOptions) Option {
 	return func(r *Renderer) {
-		r.Unsafe = unsafe
+		r.UnsafeLinks = unsafe.Links
+		r.UnsafeRawLaTeX = unsafe.RawLaTeX
+		r.UnsafeCodeContent = unsafe.CodeContent
+		r.UnsafeIncludes = unsafe.Includes
 	}
 }
 
@@ -118,7 +279,48 @@ func WithUnicodeCharactersMapping(mapping func(rune) (raw string, isReplaced boo
 }
 
 // RegisterFuncs implements goldmark's renderer.NodeRenderer interface.
-func (r *Renderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+func (r *Renderer) RegisterFuncs(registerer renderer.NodeRendererFuncRegisterer) {
+	if r.unshared {
+		r.registerFuncs(registerer)
+		return
+	}
+	// The functions bound to r render the nodes out of documents.
+	direct := &funcTable{}
+	r.registerFuncs(direct)
+	for kind, fn := range direct.funcs {
+		if fn != nil {
+			registerer.Register(ast.NodeKind(kind), r.dispatch(ast.NodeKind(kind), fn))
+		}
+	}
+}
+
+// registerFuncs registers the node rendering functions bound to r.
+func (r *Renderer) registerFuncs(registerer renderer.NodeRendererFuncRegisterer) {
+	// The functions of the registerers set up first are called first.
+	if r.Streaming {
+		// First, to flush what the other functions wrote.
+		registerer = &streamRegisterer{registerer, r}
+	}
+	if r.Limits != nil {
+		// Before tee, which must write to the limited output.
+		registerer = &limitRegisterer{registerer, r}
+	}
+	if len(r.TeeWriters) > 0 {
+		registerer = &teeRegisterer{registerer, r}
+	}
+	if len(r.NodeHooks) > 0 {
+		registerer = &hookRegisterer{registerer, r.NodeHooks}
+	}
+	if r.SourceLines {
+		registerer = &lineRegisterer{registerer, r}
+	}
+	registerer = &contextRegisterer{registerer, r}
+	registerer = &inlineHTMLRegisterer{registerer, r}
+	if r.Trace != nil {
+		// Last, to get the functions before they are wrapped.
+		registerer = &traceRegisterer{registerer, r.Trace}
+	}
+	reg := &kindRecorder{registerer, make(map[ast.NodeKind]bool)}
 	// blocks
 	reg.Register(ast.KindDocument, r.renderDocument)
 	reg.Register(ast.KindHeading, r.renderHeading)
@@ -131,6 +333,12 @@ func (r *Renderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
 	reg.Register(ast.KindParagraph, r.renderParagraph)
 	reg.Register(ast.KindTextBlock, r.renderTextBlock)
 	reg.Register(ast.KindThematicBreak, r.renderThematicBreak)
+	reg.Register(KindAbstract, r.renderAbstract)
+	reg.Register(KindDisplayMath, r.renderDisplayMath)
+	reg.Register(KindChemistryBlock, r.renderChemistryBlock)
+	reg.Register(KindDiv, r.renderDiv)
+	reg.Register(KindAdmonition, r.renderAdmonition)
+	reg.Register(KindAttribution, r.renderAttribution)
 
 	// inlines
 	reg.Register(ast.KindAutoLink, r.renderAutoLink)
@@ -141,62 +349,169 @@ func (r *Renderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
 	reg.Register(ast.KindRawHTML, r.renderRawHTML)
 	reg.Register(ast.KindText, r.renderText)
 	reg.Register(ast.KindString, r.renderString)
+	reg.Register(east.KindStrikethrough, r.renderStrikethrough)
+	reg.Register(KindInlineMath, r.renderInlineMath)
+	reg.Register(KindEquationRef, r.renderEquationRef)
+	reg.Register(KindQuantity, r.renderQuantity)
+	reg.Register(KindChemistry, r.renderChemistry)
+	reg.Register(KindRawInline, r.renderRawInline)
+	reg.Register(KindLaTeXCommand, r.renderLaTeXCommand)
+	reg.Register(KindSpan, r.renderSpan)
+	reg.Register(east.KindFootnoteLink, r.renderFootnoteLink)
+	reg.Register(east.KindFootnoteBacklink, r.renderFootnoteList)
+	reg.Register(east.KindFootnoteList, r.renderFootnoteList)
+
+	// third party math extensions
+	r.registerCompatKinds(reg)
+
+	// other node kinds
+	r.registerFallback(registerer, reg.kinds)
+
+	for kind, fn := range r.NodeRendererOverrides {
+		registerer.Register(kind, fn)
+	}
 }
 
 func (r *Renderer) renderDocument(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
 	if !entering {
 		// End of program.
-		comment(w, "end of document")
-		w.WriteString("\n\\end{document}\n")
-		return ast.WalkStop, nil
+		if r.book != nil && !r.book.last() {
+			return ast.WalkStop, nil
+		}
+		r.closeHTMLContainers(w, 0)
+		r.closeFrame(w)
+		r.writeSplitEnd(w)
+		meta := r.metadata(node.(*ast.Document))
+		if files, _ := metaBibliography(meta); r.Matters && len(files) > 0 {
+			r.writeMatter(w, backMatter)
+		}
+		r.writeBibliography(w, meta)
+		writeRaw(w, r.BodySuffix)
+		r.writeColophon(w)
+		r.comment(w, "end of document")
+		if !r.Fragment {
+			w.WriteString("\n\\end{document}\n")
+		}
+		return ast.WalkStop, r.strictError()
 	}
 
-	comment(w, "start of document")
-
-	if r.Preamble == nil {
-		comment(w, "default preamble start")
-		w.Write(defaultPreamble)
-		comment(w, "default preamble end")
-	} else {
-		comment(w, "custom preamble start")
-		w.Write(r.Preamble)
-		comment(w, "custom preamble end")
+	if r.book != nil && !r.book.first() {
+		r.comment(w, "chapter %s", r.book.paths[r.book.chapter])
+		r.writeBookPart(w)
+		return ast.WalkContinue, nil
 	}
-	if r.DeclareUnicode != nil {
-		_ = w.WriteByte('\n')
-		const unicodeDecl = "\\DeclareUnicodeCharacter{"
-		const zeropad = "00"
-		declared := make(map[rune]struct{})
-		n := len(source)
-		i := 0
-		for i < n {
-			char, lchar := utf8.DecodeRune(source[i:])
-			i += lchar
-			if lchar == 1 {
-				continue // ASCII character.
-			}
-			if _, ok := declared[char]; ok {
-				continue
-			}
-			declared[char] = struct{}{}
-			replace, ok := r.DeclareUnicode(char)
-			if !ok {
-				continue
-			}
-			_, _ = w.WriteString(unicodeDecl)
-			num := strconv.FormatUint(uint64(char), 16)
-			_, _ = w.WriteString(zeropad[:2-(len(num)-2)])
-			_, _ = w.WriteString(num)
-			_, _ = w.WriteString("}{")
-			_, _ = w.WriteString(replace)
-			_, _ = w.WriteString("}\n")
+	r.comment(w, "start of document")
+	r.resetState()
+	meta := r.metadata(node.(*ast.Document))
+	if r.Fragment {
+		r.setDirection(r.documentLanguages(meta))
+		writeRaw(w, r.BodyPrefix)
+		if r.Matters {
+			r.writeMatter(w, frontMatter)
+		}
+		r.writePageNumberingStart(w)
+		if err := r.writeFrontMatter(w, node, source, meta); err != nil {
+			return ast.WalkStop, err
 		}
+		return r.renderDocumentBody(w, source, node)
+	}
+
+	b, kind, err := r.preambleBuilder(node, source, meta)
+	if err != nil {
+		return ast.WalkStop, err
 	}
+	r.comment(w, kind+" preamble start")
+	r.writePreamble(w, b, meta)
+	r.comment(w, kind+" preamble end")
+	text := source
+	if r.book != nil {
+		text = r.book.source() // Declare the characters of all chapters.
+	}
+	r.writeUnicodeDeclarations(w, text)
+	r.writeTitleBlock(w, meta)
+	r.writePDFInfo(w, meta)
 	w.WriteString("\n\\begin{document}\n")
-	if r.makeTitle {
-		w.WriteString("\\maketitle\n")
+	writeRaw(w, r.BodyPrefix)
+	if r.Matters {
+		r.writeMatter(w, frontMatter)
 	}
-	return ast.WalkContinue, nil
+	r.writePageNumberingStart(w)
+	if err := r.writeFrontMatter(w, node, source, meta); err != nil {
+		return ast.WalkStop, err
+	}
+	r.writeBookPart(w)
+	return r.renderDocumentBody(w, source, node)
+}
+
+// resetState resets the state of the rendering of a document.
+func (r *Renderer) resetState() {
+	r.abstract = nil
+	r.inAppendix = false
+	r.matter = noMatter
+	r.headingSeen = false
+	r.sections = 0
+	r.inlineHTML = r.inlineHTML[:0]
+	r.inlineDepths = r.inlineDepths[:0]
+	r.htmlContainers = r.htmlContainers[:0]
+	r.quoteDepth = 0
+	r.frame, r.beamerBlock = false, false
+	r.skipText = r.skipText[:0]
+	r.diagnostics = nil
+	r.line, r.lineOffset = 1, 0
+}
+
+// RenderNode renders a node and its descendants, such as a block quote or a
+// table cell, to w without preamble nor document environment, for embedding
+// in other LaTeX documents. The children of a document are rendered. See
+// SectionNodes for rendering a section.
+func (r *Renderer) RenderNode(w io.Writer, source []byte, n ast.Node) error {
+	return r.renderNodeContext(r.ctx, w, source, n)
+}
+
+// renderNodeContext renders a node as RenderNode, in a session of its own
+// unless r is unshared.
+func (r *Renderer) renderNodeContext(ctx context.Context, w io.Writer, source []byte, n ast.Node) error {
+	s := r
+	if !r.unshared {
+		s = r.newSession()
+		defer func() { r.setDiagnostics(s.diagnostics) }()
+	} else {
+		defer func(ctx context.Context) { r.ctx = ctx }(r.ctx)
+	}
+	s.resetState()
+	s.ctx = ctx
+	if err := s.checkLimits(source, n); err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	var err error
+	if n.Kind() == ast.KindDocument {
+		err = s.renderSiblings(bw, source, n.FirstChild())
+	} else {
+		err = s.renderNode(bw, source, n)
+	}
+	s.closeHTMLContainers(bw, 0)
+	if err == nil {
+		err = s.strictError()
+	}
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// SectionNodes returns the blocks of the section started by a heading: the
+// heading and the blocks following it up to the next heading of the same or
+// a higher level. Each can be rendered with RenderNode.
+func SectionNodes(heading *ast.Heading) []ast.Node {
+	nodes := []ast.Node{heading}
+	for n := heading.NextSibling(); n != nil; n = n.NextSibling() {
+		if h, ok := n.(*ast.Heading); ok && h.Level <= heading.Level {
+			break
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes
 }
 
 // Do not modify.
@@ -204,108 +519,402 @@ func (r *Renderer) renderDocument(w util.BufWriter, source []byte, node ast.Node
 //go:embed defaultPreamble.tex
 var defaultPreamble []byte
 
-// DefaultPreamble returns a copy of the default preamble provided by goldmark-latex.
+// DefaultPreamble returns a copy of the default preamble provided by goldmark-latex
+// for the pdfLaTeX engine.
 // It does not include \begin{document} text within, as expected by Config.Preamble.
 func DefaultPreamble() []byte {
-	cp := make([]byte, len(defaultPreamble))
-	copy(cp, defaultPreamble)
-	return cp
+	var b PreambleBuilder
+	b.Parse(defaultPreamble)
+	PDFLaTeX.addFonts(&b)
+	return b.Bytes()
 }
 
 func (r *Renderer) renderHeading(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
 	n := node.(*ast.Heading)
+	if level := r.headingLevel(n); r.Beamer && level > 0 {
+		return r.renderBeamerHeading(w, source, n, level, entering)
+	}
 	if entering {
-		headingLevel := max(0, min(6, r.HeadingLevelOffset+n.Level-1))
+		headingLevel := r.headingLevel(n)
+		if r.Beamer {
+			r.closeFrame(w)
+		}
+		r.startHeading(w, source, n, headingLevel)
 		start := headingTable[headingLevel][bool2int(r.NoHeadingNumbering)]
-		comment(w, "heading start - level %d, start: %v", headingLevel, start)
+		if r.Chapters && headingLevel == 0 {
+			start = chapterHeading[bool2int(r.NoHeadingNumbering)]
+		} else if r.Chapters {
+			start = headingTable[headingLevel-1][bool2int(r.NoHeadingNumbering)]
+		}
+		if cv, ok := r.cvHeading(headingLevel); ok {
+			start = cv
+		}
+		if headingLevel < len(r.HeadingCommands) && r.HeadingCommands[headingLevel] != "" {
+			start = []byte("\\" + r.HeadingCommands[headingLevel] + "{")
+			if r.NoHeadingNumbering {
+				start = []byte("\\" + r.HeadingCommands[headingLevel] + "*{")
+			}
+		}
+		if r.debugComments() {
+			_, _ = w.WriteString("% goldmark-latex: heading start - level ")
+			_, _ = w.WriteString(strconv.Itoa(headingLevel))
+			_, _ = w.WriteString(", start: ")
+			_, _ = w.Write(start)
+			_ = w.WriteByte('\n')
+		}
 		// _ = w.WriteByte('\n')
-		_, _ = w.Write(start)
+		short, hasShort := attributeString(n, "short")
+		if hasShort && headingLevel < 5 && !r.NoHeadingNumbering {
+			// Short titles go in the optional argument, i.e. \section[short]{long}.
+			_, _ = w.Write(start[:len(start)-1])
+			_ = w.WriteByte('[')
+			r.Writer.Write(w, []byte(short))
+			_, _ = w.WriteString("]{")
+		} else {
+			_, _ = w.Write(start)
+		}
 		if headingLevel >= 5 {
 			// _, _ = w.Write(softBreak)
 			w.WriteByte('\n')
 		}
+		if hasFragileContent(n) {
+			_, _ = w.WriteString("\\texorpdfstring{")
+		}
 	} else {
+		if hasFragileContent(n) {
+			// PDF bookmarks cannot hold formatting: provide a plain text alternative.
+			_, _ = w.WriteString("}{")
+			text := getBuffer()
+			writePlainText(text, n, source)
+			r.Writer.Write(w, text.Bytes())
+			putBuffer(text)
+			_ = w.WriteByte('}')
+		}
 		_, _ = w.Write([]byte{'}', '\n'})
-		comment(w, "heading end")
+		r.writeBookLabels(w, n, r.headingLevel(n))
+		r.comment(w, "heading end")
 	}
 	return ast.WalkContinue, nil
 }
 
+// startHeading writes the commands preceding a heading which change the state
+// of the document: the split marker, the matter, page numbering and appendix.
+func (r *Renderer) startHeading(w util.BufWriter, source []byte, n *ast.Heading, level int) {
+	if level == 0 && n.Parent() != nil && n.Parent().Kind() == ast.KindDocument {
+		// Sections started in quotes or lists cannot be split.
+		r.writeSplitMarker(w)
+	}
+	if m := r.headingMatter(n); m != noMatter {
+		r.writeMatter(w, m)
+	}
+	r.writePageNumberingHeading(w, level)
+	if !r.inAppendix && r.isAppendixHeading(n, source) {
+		r.writeAppendix(w)
+	}
+}
+
+// headingLevel returns the level of a heading, from 0 for \section to 5,
+// shifted by the heading level offset and limited by the maximum heading level.
+func (r *Renderer) headingLevel(n *ast.Heading) int {
+	level := r.HeadingLevelOffset + n.Level - 1
+	if r.MaxHeadingLevel > 0 {
+		level = min(level, r.MaxHeadingLevel-1)
+	}
+	return max(0, min(len(headingTable)-1, level))
+}
+
+// hasFragileContent reports whether the node has children other than
+// plain text, which would break hyperref's PDF bookmarks if used as is.
+func hasFragileContent(n ast.Node) bool {
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		switch c.Kind() {
+		case ast.KindText, ast.KindString:
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// plainText returns the text content of the node and its descendants,
+// stripped of any formatting.
+func plainText(n ast.Node, source []byte) []byte {
+	var b bytes.Buffer
+	writePlainText(&b, n, source)
+	return b.Bytes()
+}
+
+// writePlainText writes the plain text of the node and its descendants to b.
+func writePlainText(b *bytes.Buffer, n ast.Node, source []byte) {
+	_ = ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch c := c.(type) {
+		case *ast.Text:
+			b.Write(c.Segment.Value(source))
+			if c.SoftLineBreak() || c.HardLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(c.Value)
+		case *ast.AutoLink:
+			b.Write(c.Label(source))
+		case *LaTeXCommand:
+			b.Write(c.Command)
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// isAppendixHeading reports whether the heading marks the start of the appendix.
+func (r *Renderer) isAppendixHeading(n *ast.Heading, source []byte) bool {
+	if hasClass(n, "appendix") {
+		return true
+	}
+	return r.AppendixHeading != "" && string(bytes.TrimSpace(plainText(n, source))) == r.AppendixHeading
+}
+
+func (r *Renderer) writeAppendix(w util.BufWriter) {
+	if r.inAppendix {
+		return
+	}
+	r.inAppendix = true
+	_, _ = w.WriteString("\n\\appendix\n")
+}
+
+// attributeString returns the named attribute of the node as a string,
+// as set for instance by goldmark's parser.WithHeadingAttribute option.
+func attributeString(n ast.Node, name string) (string, bool) {
+	v, ok := n.AttributeString(name)
+	if !ok {
+		return "", false
+	}
+	switch v := v.(type) {
+	case []byte:
+		return string(v), true
+	case string:
+		return v, true
+	default:
+		return fmt.Sprint(v), true
+	}
+}
+
+// renderBlockquote writes callouts as admonitions and other quotes as framed
+// quotes. Nested quotes are not framed again.
 func (r *Renderer) renderBlockquote(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
 	if entering {
-		_, _ = w.Write(blockQuoteStart)
+		r.quoteDepth++
 	} else {
+		r.quoteDepth--
+	}
+	if c, ok := blockquoteCallout(n, source); ok {
+		if entering {
+			r.skipText = append(r.skipText, c.marker)
+			c.writeStart(w, r.Writer)
+		} else {
+			writeAdmonitionEnd(w)
+		}
+		return ast.WalkContinue, nil
+	}
+	framed := r.quoteDepth == 0 || entering && r.quoteDepth == 1
+	switch {
+	case entering && framed:
+		_, _ = w.Write(blockQuoteStart)
+	case entering:
+		_, _ = w.WriteString("\n\\begin{quote}\n")
+	case n.LastChild() != nil && n.LastChild().Kind() == KindAttribution:
+		// The attribution ends the quote.
+		if framed {
+			_, _ = w.WriteString("\\end{framed}\n")
+		}
+	case framed:
 		_, _ = w.Write(blockQuoteEnd)
+	default:
+		_, _ = w.WriteString("\\end{quote}\n")
 	}
 	return ast.WalkContinue, nil
 }
 
 func (r *Renderer) renderCodeBlock(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
 	if entering {
-		comment(w, "code block start")
+		r.comment(w, "code block start")
 		//_, _ = w.Write(blockCodeStart)
-		w.Write([]byte("\\begin{minted}{go}\n"))
+		r.writeCodeStart(w, "")
 		_ = w.WriteByte('\n')
 		r.writeRawLines(w, source, n)
 	} else {
-		w.Write([]byte("\\end{minted}\n"))
+		r.writeCodeEnd(w)
 		// _, _ = w.Write(blockCodeEnd)
-		comment(w, "code block end")
+		r.comment(w, "code block end")
 	}
 	return ast.WalkContinue, nil
 }
 
 func (r *Renderer) renderFencedCodeBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
 	n := node.(*ast.FencedCodeBlock)
+	if n.Info != nil {
+		if format, ok := rawFormat(n.Info.Segment.Value(source)); ok {
+			if entering {
+				r.writeRawBlock(w, source, format, n.Lines())
+			}
+			return ast.WalkSkipChildren, nil
+		}
+	}
+	if isMathCodeBlock(n, source) {
+		// GitLab and GitHub render math code blocks as display math.
+		if entering {
+			r.writeDisplayMath(w, source, n, "")
+		}
+		return ast.WalkSkipChildren, nil
+	}
 	if entering {
-		comment(w, "code fenced block start")
+		r.comment(w, "code fenced block start")
 		//_, _ = w.Write(blockCodeStart)
-		w.Write([]byte("\\begin{minted}"))
 		language := n.Language(source)
 		language = language[:min(10, len(language))]
-		_, supported := supportedLang[string(language)]
-		if language != nil && supported {
-			// _, _ = w.WriteString("[language=")
-			// escapeLaTeX(w, language)
-			// _ = w.WriteByte(']')
-			w.WriteString(fmt.Sprintf("{%s}", string(language)))
+		if _, supported := supportedLang[string(language)]; !supported {
+			if len(language) > 0 {
+				r.warn(source, n, DiagnosticUnknownLanguage, "code language %s unknown, written without highlighting", language)
+			}
+			language = nil
 		}
+		r.writeCodeStart(w, string(language))
 		_ = w.WriteByte('\n')
 		r.writeRawLines(w, source, n)
 	} else {
 		// _, _ = w.Write(blockCodeEnd)
-		w.Write([]byte("\\end{minted}\n"))
-		comment(w, "code fenced block end")
+		r.writeCodeEnd(w)
+		r.comment(w, "code fenced block end")
 	}
 	return ast.WalkContinue, nil
 }
 
 func (r *Renderer) renderHTMLBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
-	w.WriteString("\n% goldmark-latex: HTML block rendering unsupported, skipped\n")
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	if directive, ok := htmlCommentDirective(node, source); ok {
+		switch directive {
+		case "appendix":
+			r.writeAppendix(w)
+			return ast.WalkSkipChildren, nil
+		case "frontmatter", "mainmatter", "backmatter":
+			m, _ := matterDirective(directive)
+			r.writeMatter(w, m)
+			return ast.WalkSkipChildren, nil
+		case "pagebreak", "newpage":
+			_, _ = w.WriteString("\n\\newpage\n")
+			return ast.WalkSkipChildren, nil
+		case "clearpage":
+			_, _ = w.WriteString("\n\\clearpage\n")
+			return ast.WalkSkipChildren, nil
+		}
+		if latex, ok := r.rawLaTeXComment(directive); ok {
+			r.writeRawLaTeX(w, latex, true)
+			return ast.WalkSkipChildren, nil
+		}
+		if r.renderTodoComment(w, directive, true) {
+			return ast.WalkSkipChildren, nil
+		}
+		if name, ok := includeDirective(directive); ok && r.IncludeFS != nil {
+			return ast.WalkSkipChildren, r.include(w, name)
+		}
+	}
+	if images, ok := htmlImages(blockContent(node, source)); ok {
+		for _, image := range images {
+			r.writeHTMLImage(w, image)
+		}
+		return ast.WalkSkipChildren, nil
+	}
+	if t, ok := parseHTMLTable(blockContent(node, source)); ok {
+		r.writeHTMLTable(w, t)
+		return ast.WalkSkipChildren, nil
+	}
+	if r.writeHTMLContainers(w, blockContent(node, source)) {
+		return ast.WalkSkipChildren, nil
+	}
+	r.warn(source, node, DiagnosticUnsupportedHTML, "HTML block skipped")
+	_ = w.WriteByte('\n')
+	r.warnComment(w, "HTML block rendering unsupported, skipped")
 	return ast.WalkSkipChildren, nil
 }
 
+// pageBreakParagraph returns the page break command of a paragraph made only of
+// \newpage, \clearpage or \pagebreak, as commonly written in markdown meant for LaTeX.
+func pageBreakParagraph(n ast.Node, source []byte) (string, bool) {
+	if n.Kind() != ast.KindParagraph {
+		return "", false
+	}
+	switch text := string(bytes.TrimSpace(plainText(n, source))); text {
+	case "\\newpage", "\\clearpage", "\\pagebreak":
+		return text, true
+	}
+	return "", false
+}
+
+// blockContent returns the lines of a block node.
+func blockContent(n ast.Node, source []byte) []byte {
+	var b []byte
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		b = append(b, line.Value(source)...)
+	}
+	return b
+}
+
+// htmlCommentDirective returns the trimmed content of an HTML block made of a single
+// comment, such as <!-- appendix -->.
+func htmlCommentDirective(n ast.Node, source []byte) (string, bool) {
+	b := bytes.TrimSpace(blockContent(n, source))
+	if !bytes.HasPrefix(b, []byte("<!--")) || !bytes.HasSuffix(b, []byte("-->")) || len(b) < 7 {
+		return "", false
+	}
+	b = b[4 : len(b)-3]
+	if bytes.Contains(b, []byte("-->")) {
+		return "", false // More than one comment.
+	}
+	return string(bytes.TrimSpace(b)), true
+}
+
 func (r *Renderer) renderList(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
 	n := node.(*ast.List)
 	tag := "itemize"
 	if n.IsOrdered() {
 		tag = "enumerate"
 	}
+	if items, entries := r.cvList(n, source); items != nil {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		return ast.WalkSkipChildren, r.renderCVList(w, source, items, entries)
+	}
 	if entering {
+		r.writeDirectionStart(w, source, n)
 		_, _ = w.WriteString("\n\\begin{")
 		_, _ = w.WriteString(tag)
-		_, _ = w.WriteString("}\n")
+		_, _ = w.WriteString("}")
+		if r.Beamer && r.IncrementalLists {
+			_, _ = w.WriteString("[<+->]")
+		}
+		_ = w.WriteByte('\n')
 	} else {
 		_, _ = w.WriteString("\\end{")
 		_, _ = w.WriteString(tag)
 		_, _ = w.WriteString("}\n")
+		r.writeDirectionEnd(w, source, n)
 	}
 	return ast.WalkContinue, nil
 }
 
 func (r *Renderer) renderListItem(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
 	if entering {
-		_, _ = w.Write(itemCommand)
+		if overlay, ok := r.itemOverlay(n, source); ok {
+			_, _ = w.WriteString("\\item" + overlay + "~ ")
+		} else {
+			_, _ = w.Write(itemCommand)
+		}
 		fc := n.FirstChild()
 		if fc != nil {
 			if _, ok := fc.(*ast.TextBlock); !ok {
@@ -319,8 +928,19 @@ func (r *Renderer) renderListItem(w util.BufWriter, source []byte, n ast.Node, e
 }
 
 func (r *Renderer) renderParagraph(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if _, _, ok := r.todoParagraph(n, source); ok {
+		return r.renderTodoParagraph(w, source, n, entering)
+	}
 	if entering {
-		comment(w, fmt.Sprintf("paragraph start (type: %T)", n))
+		if command, ok := pageBreakParagraph(n, source); ok {
+			_, _ = w.WriteString("\n" + command + "\n")
+			return ast.WalkSkipChildren, nil
+		}
+		if r.Beamer && isPause(n, source) {
+			_, _ = w.WriteString("\n\\pause\n")
+			return ast.WalkSkipChildren, nil
+		}
+		r.comment(w, "paragraph start (type: *ast.Paragraph)")
 		// paragraph := n.(*ast.Paragraph)
 
 		parent := n.Parent()
@@ -329,6 +949,7 @@ func (r *Renderer) renderParagraph(w util.BufWriter, source []byte, n ast.Node,
 			// TODO: check if this really made sense
 			// _, _ = w.Write(hardBreak)
 			// _, _ = w.Write([]byte("\n\\par\n"))
+			r.writeDirectionStart(w, source, n)
 			_, _ = w.Write([]byte("\n"))
 			// _, _ = w.Write(softBreak)
 		} else {
@@ -336,7 +957,10 @@ func (r *Renderer) renderParagraph(w util.BufWriter, source []byte, n ast.Node,
 		}
 	} else {
 		_, _ = w.WriteString("\n")
-		comment(w, "paragraph end")
+		if pkind := n.Parent().Kind(); pkind != ast.KindList && pkind != ast.KindListItem {
+			r.writeDirectionEnd(w, source, n)
+		}
+		r.comment(w, "paragraph end")
 	}
 	return ast.WalkContinue, nil
 }
@@ -369,9 +993,9 @@ func (r *Renderer) renderAutoLink(w util.BufWriter, source []byte, node ast.Node
 	if n.AutoLinkType == ast.AutoLinkEmail && haslowerprefix(url, mailToPrefix) {
 		_, _ = w.WriteString("mailto:")
 	}
-	escLink(w, url)
+	r.Writer.Write(w, url)
 	_, _ = w.WriteString("}{")
-	escapeLaTeX(w, label)
+	r.Writer.Write(w, label)
 	_ = w.WriteByte('}')
 	return ast.WalkContinue, nil
 }
@@ -405,10 +1029,10 @@ func (r *Renderer) renderCodeSpan(w util.BufWriter, source []byte, n ast.Node, e
 		segment := c.(*ast.Text).Segment
 		value := segment.Value(source)
 		if bytes.HasSuffix(value, []byte("\n")) {
-			escapeLaTeX(w, value[:len(value)-1])
+			r.Writer.Write(w, value[:len(value)-1])
 			_ = w.WriteByte(' ')
 		} else {
-			escapeLaTeX(w, value)
+			r.Writer.Write(w, value)
 		}
 	}
 	return ast.WalkSkipChildren, nil // Skip all of them after rendering.
@@ -435,94 +1059,241 @@ func (r *Renderer) renderEmphasis(w util.BufWriter, source []byte, node ast.Node
 	return ast.WalkContinue, nil
 }
 
+func (r *Renderer) renderStrikethrough(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = w.Write(strikeStart)
+	} else {
+		_ = w.WriteByte('}')
+	}
+	return ast.WalkContinue, nil
+}
+
+// WithLinkFootnotes writes the URLs of links in footnotes, so that they can
+// be read on paper. Internal links and links in headings are left alone.
+func WithLinkFootnotes(footnotes bool) Option {
+	return func(r *Renderer) {
+		r.LinkFootnotes = footnotes
+	}
+}
+
 func (r *Renderer) renderLink(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
 	n := node.(*ast.Link)
+	if r.book != nil {
+		if label, ok := r.book.resolve(string(n.Destination)); ok {
+			if entering {
+				_, _ = w.WriteString("\\hyperref[" + label + "]{")
+			} else {
+				_ = w.WriteByte('}')
+			}
+			return ast.WalkContinue, nil
+		}
+	}
+	safe := r.UnsafeLinks || !html.IsDangerousURL(n.Destination)
 	if entering {
 		_, _ = w.WriteString(`\href{`)
-		if r.Unsafe || !html.IsDangerousURL(n.Destination) {
-			escapeLaTeX(w, n.Destination)
+		if safe {
+			r.Writer.Write(w, n.Destination)
 			// _, _ = w.Write(util.EscapeHTML(util.URLEscape(n.Destination, true)))
 		}
 		_, _ = w.WriteString("}{")
 	} else {
 		_ = w.WriteByte('}')
+		if r.LinkFootnotes && safe && len(n.Destination) > 0 && n.Destination[0] != '#' && !inHeading(n) {
+			_, _ = w.WriteString("\\footnote{\\url{")
+			r.Writer.Write(w, n.Destination)
+			_, _ = w.WriteString("}}")
+		}
 	}
 	return ast.WalkContinue, nil
 }
 
+// inHeading reports whether a node is in a heading.
+func inHeading(n ast.Node) bool {
+	for p := n.Parent(); p != nil; p = p.Parent() {
+		if p.Kind() == ast.KindHeading {
+			return true
+		}
+	}
+	return false
+}
+
 func (r *Renderer) renderImage(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
 	// No image rendering implemented yet.
 	if !entering {
 		return ast.WalkContinue, nil
 	}
 	n := node.(*ast.Image)
-	w.WriteString(fmt.Sprintf("\n%% goldmark-latex: destination: %s, title: %s \n", string(n.Destination), string(n.Title)))
+	_ = w.WriteByte('\n')
+	if r.debugComments() {
+		_, _ = w.WriteString("% goldmark-latex: destination: ")
+		_, _ = w.Write(n.Destination)
+		_, _ = w.WriteString(", title: ")
+		_, _ = w.Write(n.Title)
+		_, _ = w.WriteString(" \n")
+	}
+
+	path, attributes := r.imageAttributes(w, string(n.Destination))
+	r.checkImage(source, n, path)
+
+	alt := getBuffer()
+	if r.TaggedPDF {
+		writePlainText(alt, n, source)
+	}
+	r.writeFigure(w, path, attributes, alt.Bytes())
+	putBuffer(alt)
+	putAttributes(attributes)
+
+	// 	\begin{figure}[h]
+	//     \centering
+	//     \includegraphics[width=0.75\textwidth]{mesh}
+	//     \caption{A nice plot.}
+	//     \label{fig:mesh1}
+	// \end{figure}
+	//w.WriteString(fmt.Sprintf("\\includegraphics{%s}\n", string(n.Destination)))
+	return ast.WalkSkipChildren, nil
+}
 
-	tokens := strings.Split(string(n.Destination), "?")
+// imageAttributes splits an image destination into the path and the figure
+// attributes given as query parameters: width, caption, label and class. The
+// attributes are released with putAttributes once written.
+func (r *Renderer) imageAttributes(w util.BufWriter, destination string) (string, map[string]string) {
+	tokens := strings.Split(destination, "?")
 	path := tokens[0]
-	attributes := map[string]string{}
+	attributes := getAttributes()
 	if len(tokens) > 1 {
 		tokens := strings.Split(tokens[1], "&")
 		for _, token := range tokens {
 			t := strings.Split(token, "=")
 			if len(t) != 2 {
-				w.WriteString(fmt.Sprintf("\n%% goldmark-latex: image %s has invalid attribute %s\n", path, token))
+				_ = w.WriteByte('\n')
+				r.warnComment(w, "image %s has invalid attribute %s", path, token)
 				continue
 			}
 			switch t[0] {
-			case "width", "label":
+			case "width", "label", "class":
 				attributes[t[0]] = t[1]
 			case "caption":
-				attributes["caption"] = strings.ReplaceAll(t[1], "%20", " ")
+				caption := strings.ReplaceAll(t[1], "%20", " ")
+				if !r.UnsafeRawLaTeX {
+					caption = r.Writer.EscapeString(caption)
+				}
+				attributes["caption"] = caption
 			default:
-				w.WriteString(fmt.Sprintf("\n%% goldmark-latex: image %s has unsupported attribute %s\n", path, t[0]))
+				_ = w.WriteByte('\n')
+				r.warnComment(w, "image %s has unsupported attribute %s", path, t[0])
 			}
 		}
 	}
+	return path, attributes
+}
 
-	w.WriteString(
-		fmt.Sprintf(
-			"\\begin{figure}[h]\n\t\\centering\n\t\\includegraphics[width=%s\\textwidth]{%s}\n\t\\caption{%s}\n\t\\label {%s}\n\\end{figure}\n",
-			attributes["width"],
-			path,
-			attributes["caption"],
-			attributes["label"],
-		),
-	)
-
-	// 	\begin{figure}[h]
-	//     \centering
-	//     \includegraphics[width=0.75\textwidth]{mesh}
-	//     \caption{A nice plot.}
-	//     \label{fig:mesh1}
-	// \end{figure}
-	//w.WriteString(fmt.Sprintf("\\includegraphics{%s}\n", string(n.Destination)))
-	return ast.WalkSkipChildren, nil
+// writeFigure writes an image as a figure. A numeric width is a fraction of the
+// text width, any other width a LaTeX length; alt is the alternate text of tagged PDFs.
+// Figures of the fullwidth class span the page width, in two column documents
+// too, and those of the margin class are set in the margin with margin figures.
+func (r *Renderer) writeFigure(w util.BufWriter, path string, attributes map[string]string, alt []byte) {
+	if !isSafePath(path) {
+		r.warnAt(nil, nil, -1, DiagnosticUnsafeContent, "image %s skipped, its path cannot be written", path)
+		_ = w.WriteByte('\n')
+		r.warnComment(w, "image skipped, its path has braces, backslashes or %% signs")
+		return
+	}
+	placement := r.FloatPlacement
+	if placement == "" {
+		placement = "h"
+	}
+	env, base := "figure", "\\textwidth"
+	switch attributes["class"] {
+	case "fullwidth":
+		env = "figure*"
+	case "margin":
+		if r.MarginFigures {
+			env, base, placement = "marginfigure", "\\linewidth", ""
+		}
+	}
+	if placement != "" {
+		placement = "[" + placement + "]"
+	}
+	width := attributes["width"]
+	if _, err := strconv.ParseFloat(width, 64); err != nil && width != "" {
+		if isLength(width) {
+			// A width with a unit replaces the base width, a bare factor scales it.
+			base = ""
+		} else {
+			r.warnAt(nil, nil, -1, DiagnosticUnsafeContent, "image %s width %s ignored", path, width)
+			width = ""
+		}
+	}
+	var altText string
+	if r.TaggedPDF && len(alt) > 0 {
+		altText = ", alt={" + r.Writer.EscapeString(string(alt)) + "}"
+	}
+	_, _ = w.WriteString("\\begin{")
+	_, _ = w.WriteString(env)
+	_, _ = w.WriteString("}")
+	_, _ = w.WriteString(placement)
+	_, _ = w.WriteString("\n\t\\centering\n\t\\includegraphics[width=")
+	_, _ = w.WriteString(width)
+	_, _ = w.WriteString(base)
+	_, _ = w.WriteString(altText)
+	_, _ = w.WriteString("]{")
+	_, _ = w.WriteString(path)
+	_, _ = w.WriteString("}\n\t\\caption{")
+	_, _ = w.WriteString(attributes["caption"])
+	_, _ = w.WriteString("}\n\t\\label {")
+	_, _ = w.WriteString(labelID(attributes["label"]))
+	_, _ = w.WriteString("}\n\\end{")
+	_, _ = w.WriteString(env)
+	_, _ = w.WriteString("}\n")
 }
 
 func (r *Renderer) renderRawHTML(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
-	// No rawHTML rendering supported
-	w.WriteString("\n% goldmark-latex: raw HTML rendering unsupported\n")
+	if !entering || r.writeInlineHTML(w, rawHTML(node.(*ast.RawHTML), source)) {
+		return ast.WalkSkipChildren, nil
+	}
+	if directive, ok := inlineComment(rawHTML(node.(*ast.RawHTML), source)); ok {
+		if latex, ok := r.rawLaTeXComment(directive); ok {
+			r.writeRawLaTeX(w, latex, false)
+			return ast.WalkSkipChildren, nil
+		}
+		if r.renderTodoComment(w, directive, false) {
+			return ast.WalkSkipChildren, nil
+		}
+	}
+	if t, ok := parseHTMLTag(rawHTML(node.(*ast.RawHTML), source)); ok && t.name == "img" && !t.end {
+		r.writeHTMLImage(w, t)
+		return ast.WalkSkipChildren, nil
+	}
+	// Unknown tags are skipped.
+	r.warn(source, node, DiagnosticUnsupportedHTML, "raw HTML %s skipped", rawHTML(node.(*ast.RawHTML), source))
+	_ = w.WriteByte('\n')
+	r.warnComment(w, "raw HTML rendering unsupported")
 	return ast.WalkSkipChildren, nil
 }
 
 func (r *Renderer) renderText(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
 	if !entering {
-		// comment(w, "render text end")
+		// r.comment(w, "render text end")
 		return ast.WalkContinue, nil
 	}
-	// comment(w, "render text start")
+	// r.comment(w, "render text start")
 	n := node.(*ast.Text)
+	if r.isSkipped(n.Segment) {
+		return ast.WalkContinue, nil
+	}
 	segment := n.Segment.Value(source)
 	if n.IsRaw() {
 		w.Write(segment)
 		// r.Writer.RawWrite(w, segment.Value(source))
 	} else {
-		escapeLaTeX(w, segment)
+		r.Writer.Write(w, segment)
 		if n.HardLineBreak() {
 			_, _ = w.Write(hardBreak)
 		} else if n.SoftLineBreak() {
 			// _, _ = w.Write(softBreak)
+			if isVerse(n) {
+				_, _ = w.WriteString("\\\\")
+			}
 			_ = w.WriteByte('\n')
 		}
 	}
@@ -537,7 +1308,7 @@ func (r *Renderer) renderString(w util.BufWriter, source []byte, node ast.Node,
 	if n.IsCode() || n.IsRaw() {
 		_, _ = w.Write(n.Value)
 	} else {
-		escapeLaTeX(w, n.Value)
+		r.Writer.Write(w, n.Value)
 	}
 	return ast.WalkContinue, nil
 }
@@ -546,7 +1317,7 @@ func (r *Renderer) writeLines(w util.BufWriter, source []byte, n ast.Node) {
 	l := n.Lines().Len()
 	for i := 0; i < l; i++ {
 		line := n.Lines().At(i)
-		escapeLaTeX(w, line.Value(source))
+		r.Writer.Write(w, line.Value(source))
 	}
 }
 
@@ -555,11 +1326,13 @@ func (r *Renderer) writeRawLines(w util.BufWriter, source []byte, n ast.Node) {
 	for i := 0; i < l; i++ {
 		line := n.Lines().At(i)
 		text := line.Value(source)
-		if r.Unsafe || !bytes.Contains(text, endCmdPrefix) {
-			_, _ = w.Write(text)
+		if r.UnsafeCodeContent || !bytes.Contains(text, endCmdPrefix) {
+			r.Writer.SecureWrite(w, text)
 		} else {
-			_, _ = w.WriteString("% goldmark-latex: Skipped following line due to possibly unsafe content:\n%")
-			_, _ = w.Write(text)
+			r.warnAt(source, n, line.Start, DiagnosticUnsafeContent, "code line containing \\end commented out")
+			r.warnComment(w, "Skipped following line due to possibly unsafe content:")
+			_ = w.WriteByte('%')
+			r.Writer.SecureWrite(w, text)
 		}
 	}
 }
@@ -599,10 +1372,11 @@ var (
 	blockCodeEnd    = []byte("\\end{lstlisting}\n")
 	hruleCommand    = []byte("\n\\hrulefill\n")
 
-	itemCommand  = []byte("\\item~ ")
-	tableStart   = []byte("\n\\begin{table}\n")
-	tableEnd     = []byte("\n\\end{table}\n")
-	headingTable = [6][2][]byte{
+	itemCommand    = []byte("\\item~ ")
+	tableStart     = []byte("\n\\begin{table}\n")
+	tableEnd       = []byte("\n\\end{table}\n")
+	chapterHeading = [2][]byte{[]byte("\\chapter{"), []byte("\\chapter*{")}
+	headingTable   = [6][2][]byte{
 		// {[]byte("\\part{"), []byte("\\part*{")},
 		// {[]byte("\\chapter{"), []byte("\\chapter*{")},
 		{[]byte("\\section{"), []byte("\\section*{")},
@@ -614,37 +1388,70 @@ var (
 	}
 )
 
-var escapeTable = [256][]byte{
-	'\\': []byte("\\textbackslash~"),
-	'~':  []byte("\\textasciitilde~"),
-	'^':  []byte("\\textasciicircum~"),
-	'&':  []byte("\\&"),
-	'%':  []byte("\\%"),
-	'$':  []byte("\\$"),
-	'#':  []byte("\\#"),
-	'_':  []byte("\\_"),
-	'{':  []byte("\\{"),
-	'}':  []byte("\\}"),
+// escapeTable holds the escape sequences of the characters special to LaTeX.
+var escapeTable = [256]string{
+	'\\': "\\textbackslash~",
+	'~':  "\\textasciitilde~",
+	'^':  "\\textasciicircum~",
+	'&':  "\\&",
+	'%':  "\\%",
+	'$':  "\\$",
+	'#':  "\\#",
+	'_':  "\\_",
+	'{':  "\\{",
+	'}':  "\\}",
 }
 
+// escapeLaTeX writes s escaping the characters special to LaTeX, the runs of
+// other characters being written at once.
 func escapeLaTeX(w io.Writer, s []byte) {
-	var start, end int
-	for end < len(s) {
-		escSeq := escapeTable[s[end]]
-		if escSeq != nil {
-			w.Write(s[start:end])
-			w.Write(escSeq)
-			start = end + 1
+	sw, ok := w.(io.StringWriter)
+	start := 0
+	for i, c := range s {
+		if escapeTable[c] == "" {
+			continue
 		}
-		end++
+		if start < i {
+			_, _ = w.Write(s[start:i])
+		}
+		if ok {
+			_, _ = sw.WriteString(escapeTable[c])
+		} else {
+			_, _ = io.WriteString(w, escapeTable[c])
+		}
+		start = i + 1
+	}
+	if start < len(s) {
+		_, _ = w.Write(s[start:])
+	}
+}
+
+// escapeLaTeXString is escapeLaTeX for strings, sparing their conversion.
+func escapeLaTeXString(w io.StringWriter, s string) {
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if escapeTable[s[i]] == "" {
+			continue
+		}
+		if start < i {
+			_, _ = w.WriteString(s[start:i])
+		}
+		_, _ = w.WriteString(escapeTable[s[i]])
+		start = i + 1
 	}
-	if start < len(s) && end <= len(s) {
-		w.Write(s[start:end])
+	if start < len(s) {
+		_, _ = w.WriteString(s[start:])
 	}
 }
 
-func escLink(w io.Writer, text []byte) {
-	escapeLaTeX(w, text)
+// needsEscape reports whether s has characters special to LaTeX.
+func needsEscape(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if escapeTable[s[i]] != "" {
+			return true
+		}
+	}
+	return false
 }
 
 // Languages supported by lstlisting.
@@ -761,6 +1568,23 @@ var supportedLang = map[string]struct{}{
 	"xml":         {},
 }
 
-func comment(w util.BufWriter, format string, args ...any) {
-	w.WriteString(fmt.Sprintf("%% goldmark-latex: %s\n", fmt.Sprintf(format, args...)))
+// writeRaw writes raw LaTeX on lines of its own.
+func writeRaw(w util.BufWriter, latex []byte) {
+	if len(latex) == 0 {
+		return
+	}
+	_, _ = w.Write(latex)
+	if latex[len(latex)-1] != '\n' {
+		_ = w.WriteByte('\n')
+	}
+}
+
+// isSkipped reports whether the text segment is not rendered.
+func (r *Renderer) isSkipped(segment text.Segment) bool {
+	for _, s := range r.skipText {
+		if segment.Start >= s.Start && segment.Stop <= s.Stop && s.Len() > 0 {
+			return true
+		}
+	}
+	return false
 }
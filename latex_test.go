@@ -1,15 +1,28 @@
 package latex_test
 
 import (
+	"archive/zip"
+	"bufio"
 	"bytes"
+	"context"
 	_ "embed"
+	"errors"
+	"fmt"
 	"io"
 	"os"
+	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
 
 	latex "github.com/dihedron/goldmark-latex"
 	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
 	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
 	"github.com/yuin/goldmark/util"
 )
 
@@ -62,3 +75,1022 @@ func render(t *testing.T, markdown io.Reader) *bytes.Buffer {
 	}
 	return &output
 }
+
+// convert renders the markdown string with the given options and returns the LaTeX output.
+func convert(t *testing.T, markdown string, options ...latex.Option) string {
+	t.Helper()
+	r := renderer.NewRenderer(renderer.WithNodeRenderers(util.Prioritized(latex.NewRenderer(options...), 1000)))
+	md := goldmark.New(
+		goldmark.WithRenderer(r),
+		goldmark.WithParserOptions(
+			parser.WithHeadingAttribute(),
+			// Only the parser: extensions also register their HTML renderers.
+			parser.WithBlockParsers(
+				util.Prioritized(latex.NewDisplayMathParser(), 150),
+				util.Prioritized(latex.NewDivParser(), 150),
+				util.Prioritized(latex.NewAdmonitionParser(), 150),
+				util.Prioritized(extension.NewFootnoteBlockParser(), 999),
+			),
+			parser.WithASTTransformers(
+				util.Prioritized(latex.NewChemistryTransformer(), 500),
+				util.Prioritized(latex.NewSpanTransformer(), 500),
+				util.Prioritized(latex.NewAbstractTransformer(), 500),
+				util.Prioritized(latex.NewAttributionTransformer(), 500),
+				util.Prioritized(extension.NewFootnoteASTTransformer(), 999),
+			),
+			parser.WithInlineParsers(
+				util.Prioritized(extension.NewStrikethroughParser(), 500),
+				util.Prioritized(latex.NewInlineMathParser(), 500),
+				util.Prioritized(latex.NewEquationRefParser(), 500),
+				util.Prioritized(latex.NewQuantityParser(), 50),
+				util.Prioritized(latex.NewRawInlineParser(), 50),
+				util.Prioritized(latex.NewChemistryParser(), 500),
+				util.Prioritized(latex.NewLaTeXCommandParser(), 600),
+				util.Prioritized(extension.NewFootnoteParser(), 101),
+			),
+		),
+	)
+	var output bytes.Buffer
+	if err := md.Convert([]byte(markdown), &output); err != nil {
+		t.Fatal(err)
+	}
+	return output.String()
+}
+
+func TestHeadingShortTitle(t *testing.T) {
+	output := convert(t, "# Full Long Introduction Title {short=\"Intro\"}\n")
+	if !strings.Contains(output, "\\section[Intro]{Full Long Introduction Title}") {
+		t.Errorf("expected short title in output, got:\n%s", output)
+	}
+	output = convert(t, "# Full Long Introduction Title {short=\"Intro\"}\n", latex.WithNoHeadingNumbering(true))
+	if !strings.Contains(output, "\\section*{Full Long Introduction Title}") {
+		t.Errorf("expected starred section without short title, got:\n%s", output)
+	}
+}
+
+func TestHeadingFragileContent(t *testing.T) {
+	output := convert(t, "# The `Render` *method*\n")
+	if !strings.Contains(output, "\\section{\\texorpdfstring{The \\texttt{Render} \\textit{method}}{The Render method}}") {
+		t.Errorf("expected texorpdfstring wrapping, got:\n%s", output)
+	}
+	output = convert(t, "# Plain heading\n")
+	if strings.Contains(output, "texorpdfstring") {
+		t.Errorf("plain heading should not be wrapped, got:\n%s", output)
+	}
+}
+
+func TestAppendix(t *testing.T) {
+	for _, test := range []struct {
+		markdown string
+		options  []latex.Option
+	}{
+		{"# Introduction\n\n# Proofs\n\n# Data\n", []latex.Option{latex.WithAppendix("Proofs")}},
+		{"# Introduction\n\n# Proofs {.appendix}\n\n# Data\n", nil},
+		{"# Introduction\n\n<!-- appendix -->\n\n# Proofs\n\n# Data {.appendix}\n", nil},
+	} {
+		output := convert(t, test.markdown, test.options...)
+		appendix := strings.Index(output, "\\appendix")
+		if strings.Count(output, "\\appendix") != 1 ||
+			appendix < strings.Index(output, "{Introduction}") ||
+			appendix > strings.Index(output, "{Proofs}") {
+			t.Errorf("expected a single appendix before the Proofs section, got:\n%s", output)
+		}
+	}
+	// Empty comments are not directives.
+	for _, markdown := range []string{"<!-->\n\nText.\n", "<!--->\n\nText.\n"} {
+		if output := convert(t, markdown); !strings.Contains(output, "Text.") || strings.Contains(output, "\\appendix") {
+			t.Errorf("unexpected output for %q:\n%s", markdown, output)
+		}
+	}
+}
+
+func TestFragment(t *testing.T) {
+	output := convert(t, "# Title\n\nText.\n", latex.WithStandalone(false))
+	for _, unexpected := range []string{"\\documentclass", "\\usepackage", "\\begin{document}", "\\end{document}"} {
+		if strings.Contains(output, unexpected) {
+			t.Errorf("unexpected %q in fragment:\n%s", unexpected, output)
+		}
+	}
+	if !strings.Contains(output, "\\section{Title}") || !strings.Contains(output, "Text.") {
+		t.Errorf("expected the document body, got:\n%s", output)
+	}
+}
+
+func TestBodyHooks(t *testing.T) {
+	output := convert(t, "Text.\n",
+		latex.WithMakeTitle(true),
+		latex.WithBodyPrefix([]byte("\\prefix")),
+		latex.WithAfterTitle([]byte("\\aftertitle\n")),
+		latex.WithBodySuffix([]byte("\\printbibliography")))
+	order := []string{"\\begin{document}\n", "\\prefix\n", "\\maketitle\n", "\\aftertitle\n", "Text.", "\\printbibliography\n", "\\end{document}"}
+	last := -1
+	for _, s := range order {
+		i := strings.Index(output, s)
+		if i <= last {
+			t.Fatalf("expected %q after %d, got:\n%s", s, last, output)
+		}
+		last = i
+	}
+}
+
+type closeBuffer struct{ bytes.Buffer }
+
+func (*closeBuffer) Close() error { return nil }
+
+func TestSplit(t *testing.T) {
+	output := convert(t, "Intro.\n\n# One\n\nFirst.\n\n> # Quoted\n\n## Sub\n\n# Two\n\nSecond.\n", latex.WithSplitSections(true))
+	files := make(map[string]*closeBuffer)
+	master, err := latex.Split([]byte(output), "doc-", func(name string) (io.WriteCloser, error) {
+		files[name] = &closeBuffer{}
+		return files[name], nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files["doc-section-01.tex"] == nil || files["doc-section-02.tex"] == nil {
+		t.Fatalf("expected two section files, got %v", files)
+	}
+	if s := files["doc-section-01.tex"].String(); !strings.Contains(s, "\\section{One}") || !strings.Contains(s, "\\subsection{Sub}") || !strings.Contains(s, "Quoted") || strings.Contains(s, "Second.") {
+		t.Errorf("unexpected first section:\n%s", s)
+	}
+	m := string(master)
+	if !strings.Contains(m, "Intro.") || !strings.Contains(m, "\\input{doc-section-01}\n") || !strings.Contains(m, "\\input{doc-section-02}\n") ||
+		strings.Contains(m, "First.") || !strings.HasSuffix(m, "\\end{document}\n") {
+		t.Errorf("unexpected master document:\n%s", m)
+	}
+}
+
+func TestInclude(t *testing.T) {
+	fsys := fstest.MapFS{
+		"parts/a.md": {Data: []byte("# Included\n\nFrom A.\n\n<!-- include: b.md -->\n")},
+		"parts/b.md": {Data: []byte("From *B*.\n")},
+		"loop.md":    {Data: []byte("<!-- include: loop.md -->\n")},
+	}
+	output := convert(t, "Before.\n\n<!-- include: parts/a.md -->\n\nAfter.\n", latex.WithIncludes(fsys, nil))
+	order := []string{"Before.", "\\section{Included}", "From A.", "From \\textit{B}.", "After.", "\\end{document}"}
+	last := -1
+	for _, s := range order {
+		i := strings.Index(output, s)
+		if i <= last {
+			t.Fatalf("expected %q after %d, got:\n%s", s, last, output)
+		}
+		last = i
+	}
+	if strings.Count(output, "\\documentclass") != 1 {
+		t.Errorf("included files must not write a preamble, got:\n%s", output)
+	}
+
+	md := goldmark.New(goldmark.WithRenderer(renderer.NewRenderer(renderer.WithNodeRenderers(
+		util.Prioritized(latex.NewRenderer(latex.WithIncludes(fsys, nil)), 1000)))))
+	err := md.Convert([]byte("<!-- include: loop.md -->\n"), io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("expected an include cycle error, got %v", err)
+	}
+}
+
+func TestPageBreak(t *testing.T) {
+	for markdown, expected := range map[string]string{
+		"<!-- pagebreak -->\n": "\\newpage",
+		"<!-- clearpage -->\n": "\\clearpage",
+		"\\newpage\n":          "\\newpage",
+	} {
+		output := convert(t, "Before.\n\n"+markdown+"\nAfter.\n")
+		if !strings.Contains(output, "\n"+expected+"\n") || strings.Contains(output, "textbackslash") {
+			t.Errorf("expected %q for %q, got:\n%s", expected, markdown, output)
+		}
+	}
+}
+
+func TestUnsafeOptions(t *testing.T) {
+	markdown := "[click](javascript:alert(1)) <!-- latex: \\relax -->\n"
+	output := convert(t, markdown, latex.WithUnsafe(latex.UnsafeOptions{RawLaTeX: true}))
+	if strings.Contains(output, "javascript") || !strings.Contains(output, "\\relax") {
+		t.Errorf("expected raw LaTeX without the dangerous link, got:\n%s", output)
+	}
+	output = convert(t, markdown, latex.WithUnsafe(latex.UnsafeOptions{Links: true}))
+	if !strings.Contains(output, "javascript") || strings.Contains(output, "\\relax") {
+		t.Errorf("expected the dangerous link without raw LaTeX, got:\n%s", output)
+	}
+
+	fsys := fstest.MapFS{
+		"parts/a.md": {Data: []byte("<!-- include: ../secret.md -->\n")},
+		"secret.md":  {Data: []byte("Secret.\n")},
+	}
+	for _, unsafe := range []bool{false, true} {
+		md := goldmark.New(goldmark.WithRenderer(renderer.NewRenderer(renderer.WithNodeRenderers(util.Prioritized(
+			latex.NewRenderer(latex.WithIncludes(fsys, nil), latex.WithUnsafe(latex.UnsafeOptions{Includes: unsafe})), 1000)))))
+		var b bytes.Buffer
+		err := md.Convert([]byte("<!-- include: parts/a.md -->\n"), &b)
+		if unsafe && (err != nil || !strings.Contains(b.String(), "Secret.")) {
+			t.Errorf("expected the include outside the directory, got %v:\n%s", err, b.String())
+		}
+		if !unsafe && (err == nil || !strings.Contains(err.Error(), "outside")) {
+			t.Errorf("expected an include error, got %v", err)
+		}
+	}
+}
+
+func TestBlockquoteAttribution(t *testing.T) {
+	output := convert(t, "> To be, or not to be.\n> — Hamlet, *Act III*\n\n> Brevity is the soul of wit.\n>\n> -- Polonius\n\n> A -- B\n")
+	for _, want := range []string{
+		"To be, or not to be.\n% goldmark-latex: paragraph end\n\\end{quote}\n\\begin{flushright}\n---~Hamlet, \\textit{Act III}\n\\end{flushright}\n\\end{framed}\n",
+		"\\end{quote}\n\\begin{flushright}\n---~Polonius\n\\end{flushright}\n\\end{framed}\n",
+		"A -- B",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+	if strings.Count(output, "\\end{quote}") != 3 {
+		t.Errorf("expected three quotes:\n%s", output)
+	}
+}
+
+func TestNestedBlockquotes(t *testing.T) {
+	output := convert(t, "> Outer.\n>\n> > Inner.\n> >\n> > > Innermost.\n>\n> Outer again.\n")
+	if strings.Count(output, "\\begin{framed}") != 1 || strings.Count(output, "\\end{framed}") != 1 ||
+		strings.Count(output, "\\begin{quote}") != 3 || strings.Count(output, "\\end{quote}") != 3 {
+		t.Errorf("expected a single frame around nested quotes:\n%s", output)
+	}
+	if i, j := strings.Index(output, "Outer again."), strings.Index(output, "\\end{framed}"); i > j {
+		t.Errorf("expected the outer quote to end last:\n%s", output)
+	}
+}
+
+func TestTodoNotes(t *testing.T) {
+	input := "Text<!-- TODO: check\n  the figures -->.\n\n<!-- FIXME: broken reference -->\n\nTODO: write the *conclusion*.\n"
+	output := convert(t, input, latex.WithTodoNotes(latex.TodoNotes{Markers: true}))
+	for _, want := range []string{
+		"Text\\todo{check the figures}.",
+		"\n\\todo[inline, color=red!40]{broken reference}\n",
+		"\n\\todo[inline]{write the \\textit{conclusion}.}\n",
+		"\\usepackage{todonotes}",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+	output = convert(t, input, latex.WithTodoNotes(latex.TodoNotes{Markers: true, Strip: true}))
+	if strings.Contains(output, "todo") || strings.Contains(output, "conclusion") || !strings.Contains(output, "Text.") {
+		t.Errorf("notes not stripped:\n%s", output)
+	}
+	output = convert(t, input)
+	if strings.Contains(output, "\\todo") || !strings.Contains(output, "TODO: write") {
+		t.Errorf("notes typeset by default:\n%s", output)
+	}
+}
+
+func TestCodeLanguages(t *testing.T) {
+	input := "    indented\n\n```python\nfenced\n```\n\n```x}\\input{y}\nunknown\n```\n"
+	for _, c := range []struct {
+		engine latex.CodeEngine
+		want   []string
+	}{
+		{latex.Minted, []string{"\\begin{minted}{text}\nindented\n", "\\begin{minted}{python}\n", "\\begin{minted}{text}\nunknown\n"}},
+		{latex.Listings, []string{"\\begin{lstlisting}\nindented\n", "\\begin{lstlisting}[language=python]\n", "\\begin{lstlisting}\nunknown\n"}},
+	} {
+		output := convert(t, input, latex.WithCodeEngine(c.engine))
+		for _, want := range c.want {
+			if !strings.Contains(output, want) {
+				t.Errorf("output does not contain %q:\n%s", want, output)
+			}
+		}
+		if strings.Contains(output, "\\input") {
+			t.Errorf("unknown language written:\n%s", output)
+		}
+	}
+}
+
+func TestBeamer(t *testing.T) {
+	input := "# Introduction\n\n## Motivation {#motivation}\n\n- one\n- two\n\n### Aside\n\nText.\n\n## Code\n\n```go\nfmt.Println()\n```\n\n# End\n"
+	output := convert(t, input, latex.WithBeamer(true), latex.WithIncrementalLists(true), latex.WithGeometry("a4", "2cm"))
+	for _, want := range []string{
+		"\\documentclass[xcolor={dvipsnames}]{beamer}",
+		"\\section{Introduction}\n",
+		"\n\\begin{frame}[label=motivation]{Motivation}\n",
+		"\\begin{itemize}[<+->]\n",
+		"\n\\begin{block}{Aside}\n",
+		"\\end{block}\n\\end{frame}\n\n\\begin{frame}[fragile]{Code}\n",
+		"\\end{minted}\n",
+		"\\end{frame}\n% goldmark-latex: heading start - level 0",
+		"\\section{End}\n% goldmark-latex: heading end\n% goldmark-latex: end of document",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+	for _, unwanted := range []string{"geometry", "{hyperref}", "{xcolor}"} {
+		if strings.Contains(output, unwanted) {
+			t.Errorf("output contains %q:\n%s", unwanted, output)
+		}
+	}
+}
+
+func TestBeamerOverlays(t *testing.T) {
+	input := "## Slide\n\n- <2-> second\n- first {.fragment}\n- always\n\nBefore.\n\n. . .\n\nAfter.\n\n::: notes\nSay *hello*.\n:::\n"
+	output := convert(t, input, latex.WithBeamer(true))
+	for _, want := range []string{
+		"\\item<2->~ second\n",
+		"\\item<+->~ first\n",
+		"\\item~ always\n",
+		"\n\\pause\n",
+		"\n\\note{",
+		"Say \\textit{hello}.",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+	output = convert(t, input)
+	if strings.Contains(output, "pause") || strings.Contains(output, "hello") {
+		t.Errorf("slide markup typeset outside beamer:\n%s", output)
+	}
+	if !strings.Contains(output, "\n. . .\n") {
+		t.Errorf("ellipsis paragraph dropped outside beamer:\n%s", output)
+	}
+}
+
+func TestNew(t *testing.T) {
+	input := "# Title {short=T}\n\nMass $m$.[^1]\n\n::: warning\nHot.\n:::\n\n[^1]: Note.\n"
+	var b bytes.Buffer
+	if err := latex.New(latex.WithStandalone(false)).Convert([]byte(input), &b); err != nil {
+		t.Fatal(err)
+	}
+	output := b.String()
+	for _, want := range []string{"\\section[T]{Title}", "Mass $m$.", "\\footnote{Note.}", "warning"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "<") || strings.Contains(output, "\\begin{document}") {
+		t.Errorf("unexpected output:\n%s", output)
+	}
+}
+
+func TestRendererOptions(t *testing.T) {
+	md := goldmark.New(
+		goldmark.WithRenderer(renderer.NewRenderer(renderer.WithNodeRenderers(util.Prioritized(latex.NewRenderer(), 1000)))),
+		goldmark.WithRendererOptions(
+			latex.WithStandalone(false),
+			latex.WithTableOfContents(true),
+			renderer.WithOption("NoHeadingNumbering", true),
+			renderer.WithOption("Unsafe", true),
+		),
+	)
+	var b bytes.Buffer
+	if err := md.Convert([]byte("# Title\n\n[x](javascript:alert)\n"), &b); err != nil {
+		t.Fatal(err)
+	}
+	output := b.String()
+	for _, want := range []string{"\\tableofcontents\n", "\\section*{Title}", "javascript:alert"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "\\begin{document}") {
+		t.Errorf("options given to goldmark not applied:\n%s", output)
+	}
+}
+
+type upperWriter struct {
+	latex.Writer
+}
+
+func (u upperWriter) Write(w util.BufWriter, source []byte) {
+	u.Writer.Write(w, bytes.ToUpper(source))
+}
+
+func (u upperWriter) EscapeString(s string) string {
+	return u.Writer.EscapeString(strings.ToUpper(s))
+}
+
+func TestWithWriter(t *testing.T) {
+	input := "# Title\n\nSome _text_ & `code`.\n\n```\nx\x00y\n```\n"
+	input += "\n::: {.theorem title=\"Main & only\"}\nText.\n:::\n"
+	output := convert(t, input, latex.WithWriter(upperWriter{latex.DefaultWriter}),
+		latex.WithMetadata(map[string]any{"dedication": "To my cat."}), latex.WithHeaders(latex.Headers{FootCenter: "Page {page}"}))
+	for _, want := range []string{
+		"\\section{TITLE}", "SOME \\textit{TEXT} \\& \\texttt{CODE}.", "x\uFFFDy\n",
+		"\\begin{theorem}[MAIN \\& ONLY]", "\\itshape TO MY CAT.", "\\fancyfoot[C]{PAGE {\\thepage}}",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	var b bytes.Buffer
+	if err := latex.Convert([]byte("Some $x$ ~~old~~.\n"), &b, latex.WithStandalone(false)); err != nil {
+		t.Fatal(err)
+	}
+	if output := b.String(); !strings.Contains(output, "Some $x$ \\textasciitilde~") {
+		t.Errorf("unexpected output:\n%s", output)
+	}
+	b.Reset()
+	strikethrough := parser.WithInlineParsers(util.Prioritized(extension.NewStrikethroughParser(), 500))
+	if err := latex.ConvertWithParserOptions([]byte("Some $x$ ~~old~~.\n"), &b, []parser.Option{strikethrough}, latex.WithStandalone(false)); err != nil {
+		t.Fatal(err)
+	}
+	if output := b.String(); !strings.Contains(output, "Some $x$ \\sout{old}.") {
+		t.Errorf("unexpected output:\n%s", output)
+	}
+}
+
+func TestRenderNode(t *testing.T) {
+	source := []byte("# One\n\nIntro.\n\n## Two\n\n> Quoted *text*.\n\n# Three\n\n| a | b |\n|---|---|\n| $x$ | y |\n")
+	p := goldmark.New(goldmark.WithExtensions(extension.Table, latex.Extension())).Parser()
+	doc := p.Parse(text.NewReader(source))
+	r := latex.NewRenderer()
+	render := func(n ast.Node) string {
+		var b bytes.Buffer
+		if err := r.RenderNode(&b, source, n); err != nil {
+			t.Fatal(err)
+		}
+		return b.String()
+	}
+	var quote, cell ast.Node
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		switch {
+		case !entering:
+		case n.Kind() == ast.KindBlockquote && quote == nil:
+			quote = n
+		case n.Kind() == east.KindTableCell && cell == nil && n.Parent().Kind() == east.KindTableRow:
+			cell = n
+		}
+		return ast.WalkContinue, nil
+	})
+	if output := render(quote); !strings.Contains(output, "\\begin{quote}") || !strings.Contains(output, "Quoted \\textit{text}.") || strings.Contains(output, "\\documentclass") {
+		t.Errorf("unexpected block quote output:\n%s", output)
+	}
+	if output := render(cell); !strings.HasSuffix(output, "\n$x$") {
+		t.Errorf("unexpected table cell output:\n%s", output)
+	}
+	var section strings.Builder
+	for _, n := range latex.SectionNodes(doc.FirstChild().(*ast.Heading)) {
+		section.WriteString(render(n))
+	}
+	for _, want := range []string{"\\section{One}", "Intro.", "\\subsection{Two}", "Quoted"} {
+		if !strings.Contains(section.String(), want) {
+			t.Errorf("section does not contain %q:\n%s", want, section.String())
+		}
+	}
+	if strings.Contains(section.String(), "Three") {
+		t.Errorf("section goes past the next heading:\n%s", section.String())
+	}
+	if output := render(doc); !strings.Contains(output, "\\section{Three}") || strings.Contains(output, "\\begin{document}") {
+		t.Errorf("unexpected document output:\n%s", output)
+	}
+}
+
+var (
+	kindWidget = ast.NewNodeKind("Widget")
+	kindGadget = ast.NewNodeKind("Gadget")
+)
+
+// widget is a node of a kind unknown to the renderer.
+type widget struct {
+	ast.BaseInline
+	kind ast.NodeKind
+}
+
+func (w *widget) Kind() ast.NodeKind            { return w.kind }
+func (w *widget) Dump(source []byte, level int) { ast.DumpHelper(w, source, level, nil, nil) }
+
+func TestFallback(t *testing.T) {
+	source := []byte("Before after.")
+	doc := goldmark.DefaultParser().Parse(text.NewReader(source))
+	paragraph := doc.FirstChild()
+	after := paragraph.FirstChild().(*ast.Text)
+	before := ast.NewTextSegment(after.Segment.WithStop(6))
+	after.Segment = after.Segment.WithStart(6)
+	container := &widget{kind: kindWidget}
+	container.AppendChild(container, before)
+	paragraph.InsertBefore(paragraph, after, container)
+	paragraph.InsertAfter(paragraph, after, &widget{kind: kindGadget})
+	var b bytes.Buffer
+	if err := latex.NewRenderer().RenderNode(&b, source, paragraph); err != nil {
+		t.Fatal(err)
+	}
+	output := b.String()
+	for _, want := range []string{"Before after.\n% goldmark-latex: unsupported node Gadget skipped\n"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestNodeRendererOverride(t *testing.T) {
+	quote := func(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			_, _ = w.WriteString("\n\\begin{displayquote}\n")
+		} else {
+			_, _ = w.WriteString("\\end{displayquote}\n")
+		}
+		return ast.WalkContinue, nil
+	}
+	output := convert(t, "> Quoted.\n", latex.WithNodeRendererOverride(ast.KindBlockquote, quote))
+	if !strings.Contains(output, "\\begin{displayquote}\n") || strings.Contains(output, "\\begin{quote}") {
+		t.Errorf("block quote not overridden:\n%s", output)
+	}
+}
+
+func TestNodeHook(t *testing.T) {
+	var entered []string
+	hook := func(w util.BufWriter, n ast.Node, entering bool) {
+		if h, ok := n.(*ast.Heading); ok && h.Level == 1 && !entering {
+			_, _ = w.WriteString("\\index{section}\n")
+		}
+		if entering {
+			entered = append(entered, n.Kind().String())
+		}
+	}
+	output := convert(t, "# One\n\nText.\n", latex.WithNodeHook(hook))
+	if !strings.Contains(output, "\\section{One}\n% goldmark-latex: heading end\n\\index{section}\n") {
+		t.Errorf("hook output missing:\n%s", output)
+	}
+	if got := strings.Join(entered, " "); got != "Document Heading Text Paragraph Text" {
+		t.Errorf("hook called for %s", got)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	source := []byte("Text.\n\n<form>\nblock\n</form>\n\n![Logo](logo) ![Missing](missing.png)\n\n```klingon\nDISPLAY.\n```\n")
+	r := latex.NewRenderer(latex.WithImageFS(fstest.MapFS{"logo.png": {}}))
+	md := goldmark.New(goldmark.WithRenderer(renderer.NewRenderer(renderer.WithNodeRenderers(util.Prioritized(r, 1000)))))
+	if err := md.Convert(source, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, d := range r.Diagnostics() {
+		got = append(got, d.String())
+	}
+	want := []string{
+		"3:1: HTML block skipped (unsupported-html)",
+		"7:17: image missing.png not found (missing-image)",
+		"10:1: code language klingon unknown, written without highlighting (unknown-language)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got diagnostics:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestConvertDiagnostics(t *testing.T) {
+	r := latex.NewRenderer()
+	md := goldmark.New(goldmark.WithRenderer(renderer.NewRenderer(renderer.WithNodeRenderers(util.Prioritized(r, 1000)))))
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			source := []byte("Text.\n")
+			if i%2 == 1 {
+				source = []byte("```klingon\nDISPLAY.\n```\n")
+			}
+			diagnostics, err := r.ConvertDiagnostics(md, source, io.Discard)
+			if err != nil {
+				t.Error(err)
+			}
+			if len(diagnostics) != i%2 {
+				t.Errorf("got diagnostics %v converting %q", diagnostics, source)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestStrict(t *testing.T) {
+	err := latex.Convert([]byte("Text.\n\n<form>\nblock\n</form>\n"), io.Discard, latex.WithStrict(true))
+	var strict *latex.StrictError
+	if !errors.As(err, &strict) || len(strict.Diagnostics) != 1 || strict.Diagnostics[0].Line != 3 {
+		t.Fatalf("got error %v", err)
+	}
+	if err := latex.Convert([]byte("Text.\n"), io.Discard, latex.WithStrict(true)); err != nil {
+		t.Errorf("got error %v", err)
+	}
+}
+
+type logger []string
+
+func (l *logger) Warn(msg string, args ...any) {
+	*l = append(*l, fmt.Sprintf("%s %v", msg, args))
+}
+
+func TestLogger(t *testing.T) {
+	var l logger
+	convert(t, "Text.\n\n<form>\nblock\n</form>\n", latex.WithLogger(&l))
+	if len(l) != 1 || l[0] != "HTML block skipped [code unsupported-html line 3 column 1]" {
+		t.Errorf("got log %q", l)
+	}
+}
+
+func TestConvertContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stop := func(w util.BufWriter, n ast.Node, entering bool) {
+		if n.Kind() == ast.KindHeading && !entering {
+			cancel()
+		}
+	}
+	var b bytes.Buffer
+	err := latex.ConvertContext(ctx, []byte("# One\n\nFirst.\n\n# Two\n"), &b, latex.WithNodeHook(stop))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v", err)
+	}
+	if strings.Contains(b.String(), "First.") {
+		t.Errorf("rendering not aborted:\n%s", b.String())
+	}
+}
+
+func TestComments(t *testing.T) {
+	const md = "# One\n\nText.\n\n<form>\n</form>\n\n# Two\n"
+	output := convert(t, md, latex.WithComments(latex.CommentsOff), latex.WithSplitSections(true))
+	if strings.Contains(output, "% goldmark-latex:") || !strings.Contains(output, "% goldmark-latex split: section-02\n") {
+		t.Errorf("comments written:\n%s", output)
+	}
+	output = convert(t, md, latex.WithComments(latex.CommentsWarnings))
+	if strings.Count(output, "% goldmark-latex:") != 1 || !strings.Contains(output, "% goldmark-latex: HTML block rendering unsupported, skipped\n") {
+		t.Errorf("warnings only expected:\n%s", output)
+	}
+}
+
+func TestSourceLines(t *testing.T) {
+	output := convert(t, "# One\n\nText\non two lines.\n\n- item\n", latex.WithSourceLines(true), latex.WithComments(latex.CommentsOff))
+	got := latex.SourceMap([]byte(output))
+	want := []latex.SourceLine{{Markdown: 1}, {Markdown: 3}, {Markdown: 6}}
+	lines := strings.Split(output, "\n")
+	if len(got) != len(want) {
+		t.Fatalf("got source map %v:\n%s", got, output)
+	}
+	for i, l := range got {
+		if l.Markdown != want[i].Markdown || l.TeX < 2 || l.TeX > len(lines) || !strings.HasPrefix(lines[l.TeX-2], "% goldmark-latex line: ") {
+			t.Errorf("got source line %v:\n%s", l, output)
+		}
+	}
+	if !strings.Contains(output, "% goldmark-latex line: 3\n\nText\non two lines.") {
+		t.Errorf("paragraph not marked:\n%s", output)
+	}
+}
+
+func TestOptionsFromFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := dir + "/" + name
+		if err := os.WriteFile(path, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	write("preamble.tex", "\\documentclass{report}\n")
+	path := write("config.json", `{"PreambleFile": "preamble.tex", "CodeEngine": "listings", "TableOfContents": true}`)
+	options, err := latex.OptionsFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := latex.Convert([]byte("```go\nx\n```\n"), &b, options...); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"\\documentclass{report}\n", "\\tableofcontents", "\\begin{lstlisting}"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, b.String())
+		}
+	}
+	options, err = latex.OptionsFromFile(write("ieee.json", `{"Preset": "ieee", "tableCaptionsAbove": false}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := latex.NewRenderer(options...); r.Preset == nil || r.TableCaptionsAbove {
+		t.Errorf("expected the preset with captions below tables, got preset %v", r.Preset)
+	}
+	for _, config := range []string{`{"Engine": "tex"}`, `{"TableOfContent": true}`, `{"Preset": "none"}`} {
+		if _, err := latex.OptionsFromFile(write("bad.json", config)); err == nil {
+			t.Errorf("no error for %s", config)
+		}
+	}
+}
+
+func TestOptionBundles(t *testing.T) {
+	options := append(latex.TechnicalDocDefaults(), latex.PrintFriendly()...)
+	output := convert(t, "# [Go](https://go.dev) docs\n\nSee [Go](https://go.dev) and [below](#docs).\n\n```go\nx\n```\n", options...)
+	for _, want := range []string{
+		"\\tableofcontents",
+		"\\begin{lstlisting}",
+		"\\hypersetup{hidelinks}",
+		"See \\href{https://go.dev}{Go}\\footnote{\\url{https://go.dev}} and \\href{\\#docs}{below}.",
+		"\\section{\\texorpdfstring{\\href{https://go.dev}{Go} docs}{Go docs}}",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output lacks %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "% goldmark-latex: paragraph") {
+		t.Errorf("debug comments written:\n%s", output)
+	}
+}
+
+func TestConcurrentRendering(t *testing.T) {
+	md := latex.New(latex.WithSplitSections(true))
+	sources := []string{"# One\n\n> Quote.\n\n# Two\n", "# Only\n\n<div>\n\nText.\n\n</div>\n", "Text[^1].\n\n[^1]: Note.\n"}
+	want := make([]string, len(sources))
+	for i, source := range sources {
+		var b bytes.Buffer
+		if err := md.Convert([]byte(source), &b); err != nil {
+			t.Fatal(err)
+		}
+		want[i] = b.String()
+	}
+	var wg sync.WaitGroup
+	for n := 0; n < 8; n++ {
+		for i, source := range sources {
+			wg.Add(1)
+			go func(i int, source string) {
+				defer wg.Done()
+				var b bytes.Buffer
+				if err := md.Convert([]byte(source), &b); err != nil {
+					t.Error(err)
+				} else if b.String() != want[i] {
+					t.Errorf("concurrent rendering of %q differs:\n%s", source, b.String())
+				}
+			}(i, source)
+		}
+	}
+	wg.Wait()
+}
+
+func readZipFile(t *testing.T, zr *zip.Reader, name string) string {
+	t.Helper()
+	rc, err := zr.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	content, _ := io.ReadAll(rc)
+	return string(content)
+}
+
+func TestWriteProject(t *testing.T) {
+	fsys := fstest.MapFS{"img/plot.png": {Data: []byte("png")}, "logo.png": {Data: []byte("png")}, "refs.bib": {Data: []byte("@book{}")}}
+	var b bytes.Buffer
+	zw := zip.NewWriter(&b)
+	err := latex.WriteProject("paper", []byte("![Plot](img/plot?width=0.5)\n\n```python\nx\n```\n\n<img src=\"logo.png\">\n"), fsys, latex.ZipFiles(zw),
+		latex.WithMetadata(map[string]any{"bibliography": "refs.bib"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	if got := strings.Join(names, " "); got != "paper.tex latexmkrc Makefile refs.bib img/plot.png logo.png" {
+		t.Errorf("got files %s", got)
+	}
+	if content := readZipFile(t, zr, "latexmkrc"); !strings.Contains(content, "-shell-escape") {
+		t.Errorf("got latexmkrc %s", content)
+	}
+	b.Reset()
+	zw = zip.NewWriter(&b)
+	if err := latex.WriteProject("paper", []byte("Not code: $\\text{minted}$\n"), fsys, latex.ZipFiles(zw)); err != nil {
+		t.Fatal(err)
+	}
+	_ = zw.Close()
+	zr, _ = zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if content := readZipFile(t, zr, "latexmkrc"); strings.Contains(content, "-shell-escape") {
+		t.Errorf("shell escape enabled without code, got latexmkrc %s", content)
+	}
+	err = latex.WriteProject("paper", []byte("![Missing](missing.png)\n"), fsys, latex.ZipFiles(zip.NewWriter(io.Discard)))
+	if err == nil {
+		t.Error("no error for a missing image")
+	}
+}
+
+func TestWithTee(t *testing.T) {
+	var out, copy1, copy2 bytes.Buffer
+	source := []byte("# One\n\nText[^1].\n\n[^1]: Note.\n")
+	if err := latex.Convert(source, &out, latex.WithTee(&copy1, &copy2), latex.WithSourceLines(true)); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "\\footnote{Note.}") || copy1.String() != out.String() || copy2.String() != out.String() {
+		t.Errorf("copies differ from output:\n%s\n---\n%s", out.String(), copy1.String())
+	}
+
+	copy1.Reset()
+	err := latex.Convert([]byte(strings.Repeat("Text.\n\n", 1000)), io.Discard, latex.WithTee(&copy1), latex.WithLimits(latex.Limits{MaxOutputBytes: 4000}))
+	if err == nil || copy1.Len() > 0 {
+		t.Errorf("got error %v and %d bytes copied", err, copy1.Len())
+	}
+
+	copy1.Reset()
+	md := latex.New(latex.WithTee(&copy1))
+	outputs := make([]bytes.Buffer, 2)
+	var wg sync.WaitGroup
+	for i, source := range []string{strings.Repeat("One.\n\n", 500), strings.Repeat("Two.\n\n", 500)} {
+		wg.Add(1)
+		go func(i int, source string) {
+			defer wg.Done()
+			if err := md.Convert([]byte(source), &outputs[i]); err != nil {
+				t.Error(err)
+			}
+		}(i, source)
+	}
+	wg.Wait()
+	if s := copy1.String(); s != outputs[0].String()+outputs[1].String() && s != outputs[1].String()+outputs[0].String() {
+		t.Errorf("concurrent copies interleaved:\n%s", s)
+	}
+}
+
+func TestDebugging(t *testing.T) {
+	source := []byte("Intro.\n\n## Usage {#usage}\n")
+	doc := goldmark.New(goldmark.WithParserOptions(parser.WithHeadingAttribute())).Parser().Parse(text.NewReader(source))
+	var dump bytes.Buffer
+	if err := latex.DumpAST(&dump, source, doc); err != nil {
+		t.Fatal(err)
+	}
+	want := "Document\n  Paragraph 1:1\n    Text 1:1 \"Intro.\"\n  Heading 3:4 Level=2 id=\"usage\"\n    Text 3:4 \"Usage\"\n"
+	if dump.String() != want {
+		t.Errorf("got AST dump:\n%s", dump.String())
+	}
+	var trace bytes.Buffer
+	convert(t, string(source), latex.WithTrace(&trace))
+	want = "Document renderDocument\n  Paragraph 1:1 renderParagraph\n    Text 1:1 renderText\n  Heading 3:4 renderHeading\n    Text 3:4 renderText\n"
+	if trace.String() != want {
+		t.Errorf("got trace:\n%s", trace.String())
+	}
+}
+
+func TestPooledBuffers(t *testing.T) {
+	// Pooled attributes, texts and rune sets must not leak from a figure or
+	// a rendering into the next.
+	first := convert(t, "# Café\n\n![Büro](a.png?caption=First&label=fig:a&width=0.5)\n", latex.WithTaggedPDF(true))
+	if !strings.Contains(first, "\\includegraphics[width=0.5\\textwidth, alt={Büro}]{a.png}\n\t\\caption{First}") {
+		t.Errorf("got first figure:\n%s", first)
+	}
+	declare := latex.WithUnicodeCharactersMapping(func(r rune) (string, bool) { return "?", true })
+	for i := 0; i < 2; i++ {
+		if out := convert(t, "Café\n", declare); !strings.Contains(out, "\\DeclareUnicodeCharacter{00e9}{?}") {
+			t.Errorf("got declarations of rendering %d:\n%s", i, out)
+		}
+	}
+	for _, out := range []string{
+		convert(t, "![](b.png)\n\n<table><tr><td>*x*</td></tr></table>\n"),
+		convert(t, "![](a.png?caption=First)\n\n![](b.png)\n"),
+	} {
+		if !strings.Contains(out, "\\includegraphics[width=\\textwidth]{b.png}\n\t\\caption{}\n\t\\label {}") {
+			t.Errorf("got figure with leaked attributes:\n%s", out)
+		}
+	}
+}
+
+func TestUnicodeCache(t *testing.T) {
+	calls := 0
+	mapping := latex.WithUnicodeCharactersMapping(func(r rune) (string, bool) {
+		calls++
+		return "?", true
+	})
+	cache := latex.NewUnicodeCache(8)
+	first := convert(t, "Café 😀\n", mapping, latex.WithUnicodeCache(cache))
+	if !strings.Contains(first, "\\DeclareUnicodeCharacter{00e9}{?}\n\\DeclareUnicodeCharacter{1f600}{?}\n") {
+		t.Errorf("got declarations:\n%s", first)
+	}
+	if second := convert(t, "Café 😀\n", mapping, latex.WithUnicodeCache(cache)); second != first || calls != 2 {
+		t.Errorf("got %d mapping calls, output:\n%s", calls, second)
+	}
+	if out := convert(t, "Café\n", mapping, latex.WithUnicodeCache(cache), latex.WithEngine(latex.LuaLaTeX)); strings.Contains(out, "DeclareUnicodeCharacter") || calls != 2 {
+		t.Errorf("got %d mapping calls, output with a unicode engine:\n%s", calls, out)
+	}
+	if out := convert(t, "Caf\xe9\n", mapping); strings.Contains(out, "DeclareUnicodeCharacter") {
+		t.Errorf("got declarations of invalid UTF-8:\n%s", out)
+	}
+}
+
+// chunkWriter records the writes it receives.
+type chunkWriter struct {
+	chunks []string
+}
+
+func (c *chunkWriter) Write(p []byte) (int, error) {
+	c.chunks = append(c.chunks, string(p))
+	return len(p), nil
+}
+
+func TestStreaming(t *testing.T) {
+	source := "# One\n\n> [!NOTE]\n> Noted.\n\nTODO: fix.\n\nLast paragraph.\n"
+	var serial, streamed chunkWriter
+	if err := latex.Convert([]byte(source), &serial); err != nil {
+		t.Fatal(err)
+	}
+	if err := latex.Convert([]byte(source), &streamed, latex.WithStreaming(true)); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(streamed.chunks, ""), strings.Join(serial.chunks, ""); got != want {
+		t.Errorf("got streamed output:\n%s\nwant:\n%s", got, want)
+	}
+	// The heading, the alert and each paragraph are written as rendered.
+	if len(streamed.chunks) != 5 || !strings.Contains(streamed.chunks[0], "\\section{One}") || strings.Contains(streamed.chunks[0], "Noted") {
+		t.Errorf("got %d chunks: %q", len(streamed.chunks), streamed.chunks)
+	}
+}
+
+func TestParallelSections(t *testing.T) {
+	// The appendix, the matters and the HTML element spanning sections change
+	// the state carried from a section to the next.
+	spanning := "# One\n\n<details>\n\nOpen.\n\n# Two\n\nStill open.\n\n</details>\n\n# Appendix\n\nNotes.\n\n# Back {.backmatter}\n\n<form>\nx\n</form>\n"
+	for _, c := range []struct {
+		source  []byte
+		options []latex.Option
+	}{
+		{data, nil},
+		{data, []latex.Option{latex.WithSplitSections(true), latex.WithSourceLines(true)}},
+		{data, []latex.Option{latex.WithMatters(true), latex.WithPageNumbering(latex.PageNumbering{FrontMatterStyle: "roman"})}},
+		{[]byte(spanning), []latex.Option{latex.WithAppendix("Appendix"), latex.WithMatters(true)}},
+		{[]byte("Preface.\n\n## Deep\n\n# One\n\n> quote\n"), []latex.Option{latex.WithStandalone(false)}},
+	} {
+		var serialLog, parallelLog logger
+		var serial, parallel bytes.Buffer
+		if err := latex.Convert(c.source, &serial, append(c.options, latex.WithLogger(&serialLog))...); err != nil {
+			t.Fatal(err)
+		}
+		options := append(c.options, latex.WithLogger(&parallelLog), latex.WithParallelSections(4))
+		if err := latex.Convert(c.source, &parallel, options...); err != nil {
+			t.Fatal(err)
+		}
+		if parallel.String() != serial.String() {
+			t.Errorf("got parallel output:\n%s\nwant:\n%s", parallel.String(), serial.String())
+		}
+		if strings.Join(parallelLog, "\n") != strings.Join(serialLog, "\n") {
+			t.Errorf("got parallel diagnostics %q, want %q", parallelLog, serialLog)
+		}
+	}
+}
+
+func TestLimits(t *testing.T) {
+	limits := latex.WithLimits(latex.Limits{MaxNesting: 2, MaxImages: 1, MaxOutputBytes: 4000})
+	for _, c := range []struct {
+		source, limit string
+		max           int
+	}{
+		{"> - > deep\n", "nesting levels", 2},
+		{"> ::: note\n> > Deep.\n> :::\n", "nesting levels", 2},
+		{"<div>\n<div>\n<details>\n", "nesting levels", 2},
+		{"![a](a.png) ![b](b.png)\n", "images", 1},
+		{strings.Repeat("Some text.\n\n", 1000), "output bytes", 4000},
+	} {
+		var b bytes.Buffer
+		err := latex.Convert([]byte(c.source), &b, limits, latex.WithParallelSections(2))
+		var limit *latex.LimitError
+		if !errors.As(err, &limit) || limit.Limit != c.limit || limit.Max != c.max {
+			t.Errorf("got error %v converting %.20q", err, c.source)
+		}
+		if b.Len() > 4000 {
+			t.Errorf("got %d bytes of output", b.Len())
+		}
+	}
+	if err := latex.Convert([]byte("> - nested\n\n![a](a.png)\n"), io.Discard, limits); err != nil {
+		t.Errorf("got error %v within the limits", err)
+	}
+}
+
+// benchmarkText is prose with the occasional character special to LaTeX.
+var benchmarkText = bytes.Repeat([]byte("The cost is 5$ & the rate 10% for item_id {42}, see ~user/notes. "), 64)
+
+func BenchmarkEscape(b *testing.B) {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	b.SetBytes(int64(len(benchmarkText)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		latex.DefaultWriter.Write(w, benchmarkText)
+		_ = w.Flush()
+	}
+}
+
+func BenchmarkEscapeString(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = latex.DefaultWriter.EscapeString("Plain heading text")
+		_ = latex.DefaultWriter.EscapeString("Cost & rate_id")
+	}
+}
+
+func BenchmarkConvert(b *testing.B) {
+	var source bytes.Buffer
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(&source, "## Section %d\n\n%s\n\n- item *%d* with `code_%d`\n- [link](https://example.com/%d)\n\n", i, benchmarkText[:200], i, i, i)
+	}
+	md := latex.New()
+	b.SetBytes(int64(source.Len()))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := md.Convert(source.Bytes(), io.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
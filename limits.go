@@ -0,0 +1,184 @@
+package latex
+
+import (
+	"fmt"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// Limits bounds the rendering of documents, for servers converting untrusted
+// markdown. Zero fields set no limit.
+type Limits struct {
+	// MaxNesting is the number of containers which can be nested: lists, block
+	// quotes, divs, admonitions and HTML <div> and <details> elements.
+	MaxNesting int
+	// MaxOutputBytes is the size of the output, which is cut at the limit.
+	MaxOutputBytes int
+	// MaxImages is the number of images of a document.
+	MaxImages int
+}
+
+// WithLimits aborts the rendering of documents exceeding limits with a
+// *LimitError. Nesting and images are checked before anything is written.
+func WithLimits(limits Limits) Option {
+	return func(r *Renderer) {
+		r.Limits = &limits
+	}
+}
+
+// LimitError is the error returned when a document exceeds a limit, see WithLimits.
+type LimitError struct {
+	// Limit is the name of the limit: "nesting levels", "output bytes" or "images".
+	Limit string
+	Max   int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("latex: document exceeds the limit of %d %s", e.Max, e.Limit)
+}
+
+// checkLimits returns a *LimitError if the nesting or the images of the node
+// and its descendants exceed the limits. The HTML elements of HTML blocks,
+// which may span several blocks, are nested in the containers they start in.
+func (r *Renderer) checkLimits(source []byte, n ast.Node) error {
+	l := r.Limits
+	if l == nil || l.MaxNesting <= 0 && l.MaxImages <= 0 {
+		return nil
+	}
+	var err error
+	depth, htmlDepth, images := 0, 0, 0
+	_ = ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		switch c.Kind() {
+		case ast.KindList, ast.KindBlockquote, KindDiv, KindAdmonition:
+			if !entering {
+				depth--
+				break
+			}
+			if depth++; l.MaxNesting > 0 && depth+htmlDepth > l.MaxNesting {
+				err = &LimitError{"nesting levels", l.MaxNesting}
+				return ast.WalkStop, nil
+			}
+		case ast.KindHTMLBlock:
+			if !entering || l.MaxNesting <= 0 {
+				break
+			}
+			for _, t := range htmlTokens(string(blockContent(c, source))) {
+				if !t.isTag || t.tag.name != "div" && t.tag.name != "details" {
+					continue
+				}
+				if t.tag.end {
+					htmlDepth = max(0, htmlDepth-1)
+				} else if htmlDepth++; depth+htmlDepth > l.MaxNesting {
+					err = &LimitError{"nesting levels", l.MaxNesting}
+					return ast.WalkStop, nil
+				}
+			}
+		case ast.KindImage:
+			if !entering {
+				break
+			}
+			if images++; l.MaxImages > 0 && images > l.MaxImages {
+				err = &LimitError{"images", l.MaxImages}
+				return ast.WalkStop, nil
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return err
+}
+
+// limitWriter is a util.BufWriter dropping the output beyond a size.
+type limitWriter struct {
+	util.BufWriter
+	n, max int
+}
+
+// allow returns the part of p within the limit.
+func (l *limitWriter) allow(p int) int {
+	allowed := min(p, max(0, l.max-l.n))
+	l.n += p
+	return allowed
+}
+
+func (l *limitWriter) Write(p []byte) (int, error) {
+	_, err := l.BufWriter.Write(p[:l.allow(len(p))])
+	return len(p), err
+}
+
+func (l *limitWriter) WriteByte(c byte) error {
+	if l.allow(1) == 0 {
+		return nil
+	}
+	return l.BufWriter.WriteByte(c)
+}
+
+func (l *limitWriter) WriteRune(r rune) (int, error) {
+	size := len(string(r))
+	if l.allow(size) < size {
+		return size, nil
+	}
+	return l.BufWriter.WriteRune(r)
+}
+
+func (l *limitWriter) WriteString(s string) (int, error) {
+	_, err := l.BufWriter.WriteString(s[:l.allow(len(s))])
+	return len(s), err
+}
+
+// exceeded returns a *LimitError once the output exceeds the limit.
+func (l *limitWriter) exceeded() error {
+	if l.n > l.max {
+		return &LimitError{"output bytes", l.max}
+	}
+	return nil
+}
+
+// limitWriter returns the writer of the output limited by w, wrapping w if
+// it is not already.
+func (r *Renderer) limitWriter(w util.BufWriter) (util.BufWriter, *limitWriter) {
+	switch lw := w.(type) {
+	case *limitWriter:
+		return w, lw
+	case *teeWriter:
+		if lw, ok := lw.BufWriter.(*limitWriter); ok {
+			return w, lw
+		}
+	}
+	if r.limited == nil || r.limited.BufWriter != w {
+		r.limited = &limitWriter{BufWriter: w, max: r.Limits.MaxOutputBytes}
+	}
+	return r.limited, r.limited
+}
+
+// limitRegisterer registers node rendering functions enforcing the limits of
+// the renderer.
+type limitRegisterer struct {
+	renderer.NodeRendererFuncRegisterer
+	r *Renderer
+}
+
+func (l *limitRegisterer) Register(kind ast.NodeKind, fn renderer.NodeRendererFunc) {
+	l.NodeRendererFuncRegisterer.Register(kind, func(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if kind == ast.KindDocument && entering {
+			if err := l.r.checkLimits(source, n); err != nil {
+				return ast.WalkStop, err
+			}
+		}
+		if l.r.Limits.MaxOutputBytes <= 0 {
+			return fn(w, source, n, entering)
+		}
+		w, lw := l.r.limitWriter(w)
+		if kind == ast.KindDocument && entering {
+			lw.n = 0
+		}
+		status, err := fn(w, source, n, entering)
+		if err == nil {
+			if err = lw.exceeded(); err != nil {
+				status = ast.WalkStop
+			}
+		}
+		return status, err
+	})
+}
@@ -0,0 +1,446 @@
+package latex
+
+import (
+	"bytes"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// KindInlineMath is the NodeKind of InlineMath nodes.
+var KindInlineMath = ast.NewNodeKind("InlineMath")
+
+// InlineMath is TeX math written between dollar signs, e.g. $E=mc^2$.
+// Its children are Text nodes holding the raw TeX, which is not escaped.
+type InlineMath struct {
+	ast.BaseInline
+}
+
+// Kind implements ast.Node.Kind.
+func (n *InlineMath) Kind() ast.NodeKind {
+	return KindInlineMath
+}
+
+// Dump implements ast.Node.Dump.
+func (n *InlineMath) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// NewInlineMath returns a new InlineMath node.
+func NewInlineMath() *InlineMath {
+	return &InlineMath{}
+}
+
+type inlineMathParser struct{}
+
+// NewInlineMathParser returns a parser of $...$ math spans. As in Pandoc, the opening
+// dollar sign must be followed by a non space character and the closing one must be
+// preceded by a non space character and not followed by a digit, so that amounts
+// such as $5 and $10 are left alone. Escaped dollar signs, \$, are never math.
+func NewInlineMathParser() parser.InlineParser {
+	return &inlineMathParser{}
+}
+
+func (p *inlineMathParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+func (p *inlineMathParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, segment := block.PeekLine()
+	if len(line) < 3 || line[1] == '$' || util.IsSpace(line[1]) {
+		return nil
+	}
+	for i := 2; i < len(line); i++ {
+		if line[i] != '$' || util.IsSpace(line[i-1]) || line[i-1] == '\\' {
+			continue
+		}
+		if i+1 < len(line) && line[i+1] >= '0' && line[i+1] <= '9' {
+			continue
+		}
+		n := NewInlineMath()
+		n.AppendChild(n, ast.NewRawTextSegment(text.NewSegment(segment.Start+1, segment.Start+i)))
+		block.Advance(i + 1)
+		return n
+	}
+	return nil
+}
+
+// unsafeMathCommands are the commands, without backslash, which read or write
+// files, run programs or redefine TeX, so that they are not written from math
+// unless raw LaTeX is trusted.
+var unsafeMathCommands = map[string]bool{
+	"input": true, "include": true, "InputIfFileExists": true, "verbatiminput": true,
+	"write": true, "write18": true, "immediate": true, "openin": true, "openout": true,
+	"read": true, "readline": true, "special": true, "shipout": true, "scantokens": true,
+	"catcode": true, "def": true, "edef": true, "gdef": true, "xdef": true, "let": true,
+	"csname": true, "newcommand": true, "renewcommand": true, "providecommand": true,
+	"DeclareRobustCommand": true, "usepackage": true, "documentclass": true,
+}
+
+// isSafeMath reports whether math can be written verbatim: raw LaTeX is
+// trusted, see UnsafeOptions, or the math has no command of
+// unsafeMathCommands nor ^^ notation, which can hide commands.
+func (r *Renderer) isSafeMath(math []byte) bool {
+	if r.UnsafeRawLaTeX {
+		return true
+	}
+	if bytes.Contains(math, []byte("^^")) {
+		return false
+	}
+	for i := 0; i < len(math); i++ {
+		if math[i] != '\\' {
+			continue
+		}
+		end := i + 1
+		for end < len(math) && isLetter(math[end]) {
+			end++
+		}
+		if end == i+1 {
+			i++ // Control symbol.
+			continue
+		}
+		if unsafeMathCommands[string(math[i+1:end])] && !r.isAllowedCommand(string(math[i+1:end])) {
+			return false
+		}
+		i = end - 1
+	}
+	return true
+}
+
+// safeBlock reports whether the lines of a block of math are safe, see
+// isSafeMath, or reports and writes the block is skipped.
+func (r *Renderer) safeBlock(w util.BufWriter, source []byte, node ast.Node, what string) bool {
+	lines := node.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		// Commands cannot span lines.
+		if line := lines.At(i); !r.isSafeMath(line.Value(source)) {
+			r.warn(source, node, DiagnosticUnsafeContent, "%s skipped: %s", what, bytes.TrimSpace(line.Value(source)))
+			_ = w.WriteByte('\n')
+			r.warnComment(w, "%s skipped, unsafe rendering disabled", what)
+			return false
+		}
+	}
+	return true
+}
+
+// renderInlineMath writes the math span verbatim between dollar signs, or as
+// text when it is not safe.
+func (r *Renderer) renderInlineMath(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	var math []byte
+	for c := node.FirstChild(); c != nil; c = c.NextSibling() {
+		if t, ok := c.(*ast.Text); ok {
+			math = append(math, t.Segment.Value(source)...)
+		}
+	}
+	r.writeInlineMath(w, source, node, math)
+	return ast.WalkSkipChildren, nil
+}
+
+// writeInlineMath writes math between dollar signs, or the math text, dollar
+// signs included, when it is not safe.
+func (r *Renderer) writeInlineMath(w util.BufWriter, source []byte, node ast.Node, math []byte) {
+	if !r.isSafeMath(math) {
+		r.warn(source, node, DiagnosticUnsafeContent, "math written as text: %s", math)
+		r.Writer.Write(w, []byte("$"+string(math)+"$"))
+		return
+	}
+	_ = w.WriteByte('$')
+	_, _ = w.Write(math)
+	_ = w.WriteByte('$')
+}
+
+// KindDisplayMath is the NodeKind of DisplayMath nodes.
+var KindDisplayMath = ast.NewNodeKind("DisplayMath")
+
+// DisplayMath is a block of TeX math between $$ lines. Its lines hold the raw TeX.
+type DisplayMath struct {
+	ast.BaseBlock
+	closed bool // Set when the closing $$ has been read.
+	// opening is the first line, from the opening $$, and openingLines the
+	// number of lines of math it holds, for unclosed blocks to be paragraphs.
+	opening      text.Segment
+	openingLines int
+}
+
+// Kind implements ast.Node.Kind.
+func (n *DisplayMath) Kind() ast.NodeKind {
+	return KindDisplayMath
+}
+
+// IsRaw implements ast.Node.IsRaw.
+func (n *DisplayMath) IsRaw() bool {
+	return true
+}
+
+// Dump implements ast.Node.Dump.
+func (n *DisplayMath) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// NewDisplayMath returns a new DisplayMath node.
+func NewDisplayMath() *DisplayMath {
+	return &DisplayMath{}
+}
+
+var mathFence = []byte("$$")
+
+type displayMathParser struct{}
+
+// NewDisplayMathParser returns a parser of display math blocks, starting with a line
+// beginning with $$ and ending with a line ending with $$, e.g. $$ x^2 $$ on a single line.
+// Blocks not closed before a blank line or the end of their container are paragraphs.
+func NewDisplayMathParser() parser.BlockParser {
+	return &displayMathParser{}
+}
+
+func (p *displayMathParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+func (p *displayMathParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	pos := pc.BlockOffset()
+	if pos < 0 || !bytes.HasPrefix(line[pos:], mathFence) {
+		return nil, parser.NoChildren
+	}
+	n := NewDisplayMath()
+	start := segment.Start + pos + len(mathFence)
+	rest := line[pos+len(mathFence):]
+	if i := bytes.Index(rest, mathFence); i >= 0 {
+		if !setEquationLabel(n, rest[i+len(mathFence):]) {
+			return nil, parser.NoChildren
+		}
+		rest, n.closed = rest[:i], true
+	}
+	n.opening = text.NewSegment(segment.Start+pos, segment.Stop)
+	if !util.IsBlank(rest) {
+		n.Lines().Append(text.NewSegment(start, start+len(rest)))
+		n.openingLines = 1
+	}
+	return n, parser.NoChildren
+}
+
+func (p *displayMathParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	n := node.(*DisplayMath)
+	if n.closed {
+		return parser.Close
+	}
+	line, segment := reader.PeekLine()
+	if util.IsBlank(line) {
+		// Math cannot hold blank lines: the block is not closed.
+		return parser.Close
+	}
+	if i := bytes.LastIndex(line, mathFence); i >= 0 && setEquationLabel(n, line[i+len(mathFence):]) {
+		content := line[:i]
+		if !util.IsBlank(content) {
+			n.Lines().Append(text.NewSegment(segment.Start, segment.Start+len(content)))
+		}
+		reader.Advance(segment.Len() - 1)
+		n.closed = true
+		return parser.Close
+	}
+	n.Lines().Append(segment)
+	reader.Advance(segment.Len() - 1)
+	return parser.Continue | parser.NoChildren
+}
+
+// equationLabel matches the {#label} attribute following the closing $$ of labeled equations.
+var equationLabel = regexp.MustCompile(`^\s*\{#([^\s{}]+)\}\s*$`)
+
+// setEquationLabel sets the label found in the text following the closing $$ as
+// the node's id and reports whether the text is either blank or such a label.
+func setEquationLabel(n ast.Node, rest []byte) bool {
+	if util.IsBlank(rest) {
+		return true
+	}
+	m := equationLabel.FindSubmatch(rest)
+	if m == nil {
+		return false
+	}
+	n.SetAttributeString("id", m[1])
+	return true
+}
+
+// Close replaces the block with a paragraph of its lines if it is not closed,
+// so that a lone $$ does not turn the rest of the document into math.
+func (p *displayMathParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {
+	n := node.(*DisplayMath)
+	if n.closed {
+		return
+	}
+	paragraph := ast.NewParagraph()
+	paragraph.Lines().Append(n.opening)
+	lines := n.Lines()
+	for i := n.openingLines; i < lines.Len(); i++ {
+		paragraph.Lines().Append(lines.At(i))
+	}
+	node.Parent().ReplaceChild(node.Parent(), node, paragraph)
+}
+
+func (p *displayMathParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (p *displayMathParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+// WithNumberedEquations typesets display math in numbered equation environments
+// instead of unnumbered \[...\] displays.
+func WithNumberedEquations(numbered bool) Option {
+	return func(r *Renderer) {
+		r.NumberedEquations = numbered
+	}
+}
+
+// renderDisplayMath writes the math block verbatim in a display.
+func (r *Renderer) renderDisplayMath(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	label, _ := attributeString(node, "id")
+	r.writeDisplayMath(w, source, node, labelID(label))
+	return ast.WalkSkipChildren, nil
+}
+
+// writeDisplayMath writes the lines of TeX math of a block in a display.
+// Labeled equations are numbered. Math with alignment markers, & and \\, is
+// set in an align environment. Math which is not safe is skipped.
+func (r *Renderer) writeDisplayMath(w util.BufWriter, source []byte, node ast.Node, label string) {
+	lines := node.Lines()
+	if !r.safeBlock(w, source, node, "math") {
+		return
+	}
+	numbered := r.NumberedEquations || label != ""
+	start, end := "\\[", "\\]"
+	switch aligned := alignedMath(source, lines); {
+	case aligned && numbered:
+		start, end = "\\begin{align}", "\\end{align}"
+	case aligned:
+		start, end = "\\begin{align*}", "\\end{align*}"
+	case numbered:
+		start, end = "\\begin{equation}", "\\end{equation}"
+	}
+	_, _ = w.WriteString("\n" + start + "\n")
+	if label != "" {
+		_, _ = w.WriteString("\\label{" + label + "}\n")
+	}
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		_, _ = w.Write(bytes.TrimRight(line.Value(source), "\r\n"))
+		_ = w.WriteByte('\n')
+	}
+	_, _ = w.WriteString(end + "\n")
+}
+
+// KindEquationRef is the NodeKind of EquationRef nodes.
+var KindEquationRef = ast.NewNodeKind("EquationRef")
+
+// EquationRef is a reference to a labeled equation, e.g. @eq:energy.
+type EquationRef struct {
+	ast.BaseInline
+	Label []byte
+}
+
+// Kind implements ast.Node.Kind.
+func (n *EquationRef) Kind() ast.NodeKind {
+	return KindEquationRef
+}
+
+// Dump implements ast.Node.Dump.
+func (n *EquationRef) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Label": string(n.Label)}, nil)
+}
+
+// NewEquationRef returns a new EquationRef node.
+func NewEquationRef(label []byte) *EquationRef {
+	return &EquationRef{Label: label}
+}
+
+type equationRefParser struct{}
+
+var equationRefPrefix = []byte("@eq:")
+
+// NewEquationRefParser returns a parser of @eq:label references to equations
+// labeled with {#eq:label}, as in pandoc-crossref.
+func NewEquationRefParser() parser.InlineParser {
+	return &equationRefParser{}
+}
+
+func (p *equationRefParser) Trigger() []byte {
+	return []byte{'@'}
+}
+
+func (p *equationRefParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	if c := block.PrecendingCharacter(); c < utf8.RuneSelf && util.IsAlphaNumeric(byte(c)) {
+		return nil // An e-mail address.
+	}
+	line, _ := block.PeekLine()
+	if !bytes.HasPrefix(line, equationRefPrefix) {
+		return nil
+	}
+	i := len(equationRefPrefix)
+	for i < len(line) && (util.IsAlphaNumeric(line[i]) || line[i] == '_' || line[i] == '-' || line[i] == ':') {
+		i++
+	}
+	if i == len(equationRefPrefix) {
+		return nil
+	}
+	block.Advance(i)
+	return NewEquationRef(line[1:i])
+}
+
+// renderEquationRef writes a reference to an equation.
+func (r *Renderer) renderEquationRef(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString("\\eqref{" + labelID(string(node.(*EquationRef).Label)) + "}")
+	}
+	return ast.WalkContinue, nil
+}
+
+// isMathCodeBlock reports whether the fenced code block holds display math, as
+// rendered by GitLab and GitHub for the math language.
+func isMathCodeBlock(n *ast.FencedCodeBlock, source []byte) bool {
+	return string(n.Language(source)) == "math"
+}
+
+// alignedMath reports whether display math contains alignment markers, & or \\,
+// outside of an environment of its own such as aligned or cases.
+func alignedMath(source []byte, lines *text.Segments) bool {
+	var math []byte
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		math = append(math, line.Value(source)...)
+	}
+	if bytes.Contains(math, []byte("\\begin{")) {
+		return false
+	}
+	return bytes.Contains(math, []byte("&")) || bytes.Contains(math, []byte("\\\\"))
+}
+
+type mathExtension struct{}
+
+// Math is a goldmark extension parsing TeX math, $...$ spans and $$ blocks,
+// which the renderer writes verbatim instead of escaping it, and references to
+// labeled equations, such as @eq:energy for $$ E=mc^2 $$ {#eq:energy}:
+//
+//	md := goldmark.New(goldmark.WithExtensions(latex.Math), goldmark.WithRenderer(r))
+var Math goldmark.Extender = &mathExtension{}
+
+func (e *mathExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithBlockParsers(util.Prioritized(NewDisplayMathParser(), 150)),
+		parser.WithInlineParsers(
+			util.Prioritized(NewInlineMathParser(), 500),
+			util.Prioritized(NewEquationRefParser(), 500),
+		),
+	)
+}
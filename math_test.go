@@ -0,0 +1,114 @@
+package latex_test
+
+import (
+	"strings"
+	"testing"
+
+	latex "github.com/dihedron/goldmark-latex"
+)
+
+func TestInlineMath(t *testing.T) {
+	output := convert(t, "Energy $E=mc^2$ costs $5 and $10.\n\n\\$x$ is not math, nor $ x $.\n")
+	for _, expected := range []string{"Energy $E=mc^2$ costs \\$5 and \\$10.", "\\$x\\$ is not math", "nor \\$ x \\$."} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in output, got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestDisplayMath(t *testing.T) {
+	markdown := "Before.\n$$\n\\int_0^1 x\\,dx = \\frac{1}{2}\n$$\nAfter.\n\n$$ a_1 + b^2 $$\n"
+	output := convert(t, markdown)
+	for _, expected := range []string{"\n\\[\n\\int_0^1 x\\,dx = \\frac{1}{2}\n\\]\n", "\n\\[\n a_1 + b^2 \n\\]\n", "After."} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in output, got:\n%s", expected, output)
+		}
+	}
+	output = convert(t, markdown, latex.WithNumberedEquations(true))
+	if strings.Count(output, "\\begin{equation}") != 2 {
+		t.Errorf("expected numbered equations, got:\n%s", output)
+	}
+}
+
+func TestUnsafeMath(t *testing.T) {
+	markdown := "Read $\\input{/etc/passwd}$ and $\\frac{1}{2}$.\n\n$$\n\\immediate\\write18{touch x}\n$$\n"
+	output := convert(t, markdown)
+	for _, unexpected := range []string{"$\\input", "\\immediate\\write18"} {
+		if strings.Contains(output, unexpected) {
+			t.Errorf("unexpected %q in output, got:\n%s", unexpected, output)
+		}
+	}
+	if !strings.Contains(output, "Read \\$\\textbackslash~input") || !strings.Contains(output, "$\\frac{1}{2}$") {
+		t.Errorf("expected safe math only, got:\n%s", output)
+	}
+	output = convert(t, markdown, latex.WithUnsafe(latex.UnsafeOptions{RawLaTeX: true}))
+	if !strings.Contains(output, "$\\input{/etc/passwd}$") || !strings.Contains(output, "\n\\immediate\\write18{touch x}\n") {
+		t.Errorf("expected trusted math, got:\n%s", output)
+	}
+}
+
+func TestMathCodeBlock(t *testing.T) {
+	output := convert(t, "```math\na^2 + b^2 = c^2\n```\n", latex.WithNumberedEquations(true))
+	if !strings.Contains(output, "\\begin{equation}\na^2 + b^2 = c^2\n\\end{equation}\n") || strings.Contains(output, "minted") {
+		t.Errorf("expected an equation, got:\n%s", output)
+	}
+}
+
+func TestEquationLabels(t *testing.T) {
+	output := convert(t, "$$\nE = mc^2\n$$ {#eq:energy}\n\nSee @eq:energy, not user@eq:x.\n\n$$ F = ma $$ {#eq:force}\n")
+	for _, expected := range []string{
+		"\\begin{equation}\n\\label{eq:energy}\nE = mc^2\n\\end{equation}\n",
+		"See \\eqref{eq:energy}, not user@eq:x.",
+		"\\begin{equation}\n\\label{eq:force}\n F = ma \n\\end{equation}\n",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in output, got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestQuantities(t *testing.T) {
+	output := convert(t, "Force `qty:5 kg·m/s^2`, ratio `qty:12.5 %`, count `qty:42`, code `qty:x`.\n")
+	for _, expected := range []string{"\\qty{5}{kg.m/s^2}", "\\qty{12.5}{\\%}", "\\num{42}", "\\texttt{qty:x}", "\\usepackage{siunitx}"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in output, got:\n%s", expected, output)
+		}
+	}
+	if output := convert(t, "No quantities.\n"); strings.Contains(output, "siunitx") {
+		t.Errorf("siunitx must only be loaded when used, got:\n%s", output)
+	}
+}
+
+func TestChemistry(t *testing.T) {
+	output := convert(t, "Water is \\ce{H2O}, \\ce{SO4^{2-}} an ion.\n\n```chem\n2H2 + O2 -> 2H2O\n```\n")
+	for _, expected := range []string{"Water is \\ce{H2O}, \\ce{SO4^{2-}} an ion.", "\\[\\ce{2H2 + O2 -> 2H2O}\\]", "\\usepackage[version=4]{mhchem}"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in output, got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestAlignedMath(t *testing.T) {
+	output := convert(t, "$$\na &= b + c \\\\\n  &= d\n$$\n\n$$\n\\begin{cases} 1 & x > 0 \\\\ 0 & x \\le 0 \\end{cases}\n$$ {#eq:step}\n")
+	for _, expected := range []string{
+		"\\begin{align*}\na &= b + c \\\\\n  &= d\n\\end{align*}\n",
+		"\\begin{equation}\n\\label{eq:step}\n\\begin{cases}",
+		"\\usepackage{amsmath}\n",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in output, got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestUnclosedDisplayMath(t *testing.T) {
+	output := convert(t, "$$5 and\nmore.\n\n# Heading\n\nText.\n\n$$\n")
+	for _, want := range []string{"\n\\$\\$5 and\nmore.\n", "\\section{Heading}", "Text.", "\n\\$\\$\n"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "\\[") {
+		t.Errorf("unclosed math typeset as math:\n%s", output)
+	}
+}
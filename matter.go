@@ -0,0 +1,125 @@
+package latex
+
+import (
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/util"
+)
+
+// matter is a division of a book: front, main or back matter.
+type matter int
+
+const (
+	noMatter matter = iota
+	frontMatter
+	mainMatter
+	backMatter
+)
+
+// matterCommands are the commands starting each matter, without backslash.
+var matterCommands = [...]string{
+	frontMatter: "frontmatter",
+	mainMatter:  "mainmatter",
+	backMatter:  "backmatter",
+}
+
+// WithMatters divides the document into front, main and back matter, for the
+// book class: \frontmatter is written at the beginning of the document, so that
+// the title, preface and contents have roman page numbers and unnumbered
+// chapters, \mainmatter at the first heading, unless it has the frontmatter
+// class, and \backmatter before the bibliography. Headings with the mainmatter
+// or backmatter class and the <!-- frontmatter -->, <!-- mainmatter --> and
+// <!-- backmatter --> HTML comments also start a matter, with or without this
+// option. The abstract is typeset as an unnumbered chapter.
+func WithMatters(matters bool) Option {
+	return func(r *Renderer) {
+		r.Matters = matters
+	}
+}
+
+// matterDirective returns the matter started by an HTML comment directive.
+func matterDirective(directive string) (matter, bool) {
+	for m, command := range matterCommands {
+		if command != "" && directive == command {
+			return matter(m), true
+		}
+	}
+	return noMatter, false
+}
+
+// headingMatter returns the matter started by a heading: the matter of its
+// class, or the main matter for a heading ending the front matter.
+func (r *Renderer) headingMatter(n *ast.Heading) matter {
+	for m, command := range matterCommands {
+		if command != "" && hasClass(n, command) {
+			return matter(m)
+		}
+	}
+	if r.Matters && r.matter == frontMatter {
+		return mainMatter
+	}
+	return noMatter
+}
+
+// writeMatter starts a matter following the current one; matters cannot be
+// started again nor out of order.
+func (r *Renderer) writeMatter(w util.BufWriter, m matter) {
+	if m <= r.matter {
+		return
+	}
+	r.matter = m
+	_, _ = w.WriteString("\n\\" + matterCommands[m] + "\n")
+}
+
+// writeMatterPages writes the pages found under the "declaration" and
+// "acknowledgements" metadata keys, such as the declaration of authorship of a
+// thesis, as unnumbered chapters, or sections, listed in the table of contents.
+func (r *Renderer) writeMatterPages(w util.BufWriter, meta map[string]any) {
+	heading := "section"
+	if r.Chapters {
+		heading = "chapter"
+	}
+	for _, page := range []struct{ keys, title string }{
+		{"declaration", "Declaration"},
+		{"acknowledgements acknowledgments", "Acknowledgements"},
+	} {
+		for _, key := range strings.Fields(page.keys) {
+			content, ok := metaString(meta, key)
+			if !ok || strings.TrimSpace(content) == "" {
+				continue
+			}
+			_, _ = w.WriteString("\n\\" + heading + "*{" + page.title + "}\n")
+			_, _ = w.WriteString("\\addcontentsline{toc}{" + heading + "}{" + page.title + "}\n")
+			r.Writer.Write(w, []byte(strings.TrimSpace(content)))
+			_ = w.WriteByte('\n')
+			break
+		}
+	}
+}
+
+// Thesis is the preset of theses and other book-length documents, typeset with
+// the book class: the title page, declaration, acknowledgements, abstract and
+// table of contents form the front matter, numbered in roman, top level
+// headings are chapters and the bibliography is in the back matter. See
+// WithMatters for marking the matters.
+var Thesis = Preset{
+	Name: "thesis",
+	Preamble: []byte(`\documentclass[11pt,a4paper,twoside,openright]{book}
+
+\usepackage[utf8]{inputenc}
+\usepackage[T1]{fontenc}
+\usepackage{lmodern}
+\usepackage[a4paper,inner=3cm,outer=2.5cm,top=3cm,bottom=3cm]{geometry}
+\usepackage{graphicx}
+\usepackage{setspace}
+\onehalfspacing
+`),
+	Options: []Option{
+		WithMakeTitle(true),
+		WithAbstractSection(true),
+		WithChapters(true),
+		WithMatters(true),
+		WithTableOfContents(true),
+	},
+}
@@ -0,0 +1,245 @@
+package latex
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/util"
+)
+
+// WithMetadata sets default document metadata such as title, author and date.
+// Metadata stored in the document itself, e.g. front matter parsed by goldmark-meta
+// with meta.WithStoresInDocument(), takes precedence over these values.
+func WithMetadata(metadata map[string]any) Option {
+	return func(r *Renderer) {
+		r.Metadata = metadata
+	}
+}
+
+// metadata merges the renderer's default metadata with the document's own.
+func (r *Renderer) metadata(doc *ast.Document) map[string]any {
+	meta := make(map[string]any, len(r.Metadata))
+	for k, v := range r.Metadata {
+		meta[k] = v
+	}
+	if doc != nil {
+		for k, v := range doc.Meta() {
+			meta[k] = v
+		}
+	}
+	return meta
+}
+
+// metaString returns the value of the first key found in meta as a string.
+func metaString(meta map[string]any, keys ...string) (string, bool) {
+	for _, key := range keys {
+		if v, ok := meta[key]; ok && v != nil {
+			return toString(v), true
+		}
+	}
+	return "", false
+}
+
+func toString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// metaList returns v as a list; scalar values become single element lists.
+func metaList(v any) []any {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case []any:
+		return v
+	case []string:
+		l := make([]any, len(v))
+		for i := range v {
+			l[i] = v[i]
+		}
+		return l
+	case []map[string]any:
+		l := make([]any, len(v))
+		for i := range v {
+			l[i] = v[i]
+		}
+		return l
+	default:
+		return []any{v}
+	}
+}
+
+// metaMap returns v as a map with string keys. YAML decoders commonly
+// produce map[any]any for nested mappings.
+func metaMap(v any) (map[string]any, bool) {
+	switch v := v.(type) {
+	case map[string]any:
+		return v, true
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			m[toString(k)] = e
+		}
+		return m, true
+	case map[string]string:
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			m[k] = e
+		}
+		return m, true
+	}
+	return nil, false
+}
+
+// Author is a document author as described in metadata.
+type Author struct {
+	Name        string
+	Affiliation string
+	Email       string
+	ORCID       string
+}
+
+// metaAuthors returns the authors listed under the "author" or "authors" keys. Each
+// author is either a plain name or a mapping with name, affiliation, email and orcid keys.
+func metaAuthors(meta map[string]any) []Author {
+	v, ok := meta["author"]
+	if !ok {
+		v = meta["authors"]
+	}
+	var authors []Author
+	for _, e := range metaList(v) {
+		m, ok := metaMap(e)
+		if !ok {
+			authors = append(authors, Author{Name: toString(e)})
+			continue
+		}
+		var a Author
+		a.Name, _ = metaString(m, "name")
+		a.Affiliation, _ = metaString(m, "affiliation", "affil", "institute")
+		a.Email, _ = metaString(m, "email")
+		a.ORCID, _ = metaString(m, "orcid", "ORCID")
+		authors = append(authors, a)
+	}
+	return authors
+}
+
+// writeTitleBlock writes the \title, \author and \date commands found in metadata.
+func (r *Renderer) writeTitleBlock(w util.BufWriter, meta map[string]any) {
+	if title, ok := metaString(meta, "title"); ok {
+		_, _ = w.WriteString("\\title{")
+		r.Writer.Write(w, []byte(title))
+		if subtitle, ok := metaString(meta, "subtitle"); ok {
+			_, _ = w.WriteString("\\\\[0.5em]\\large ")
+			r.Writer.Write(w, []byte(subtitle))
+		}
+		_, _ = w.WriteString("}\n")
+	}
+	r.writeAuthors(w, metaAuthors(meta))
+	if date, ok := metaString(meta, "date"); ok {
+		_, _ = w.WriteString("\\date{")
+		r.Writer.Write(w, []byte(date))
+		_, _ = w.WriteString("}\n")
+	}
+}
+
+// writeAuthors writes the author list, formatted by the preset if any. When any
+// author has an affiliation, the authblk package is used to number and share
+// affiliations.
+func (r *Renderer) writeAuthors(w util.BufWriter, authors []Author) {
+	if len(authors) == 0 {
+		return
+	}
+	if r.Preset != nil && r.Preset.Authors != nil {
+		r.Preset.Authors(w, r.Writer, authors)
+		return
+	}
+	var affiliations []string
+	index := make(map[string]int)
+	for _, a := range authors {
+		if _, ok := index[a.Affiliation]; a.Affiliation != "" && !ok {
+			affiliations = append(affiliations, a.Affiliation)
+			index[a.Affiliation] = len(affiliations)
+		}
+	}
+	if len(affiliations) == 0 {
+		_, _ = w.WriteString("\\author{")
+		for i, a := range authors {
+			if i > 0 {
+				_, _ = w.WriteString(" \\and ")
+			}
+			r.writeAuthorName(w, a)
+		}
+		_, _ = w.WriteString("}\n")
+		return
+	}
+	_, _ = w.WriteString("\\usepackage{authblk}\n")
+	for _, a := range authors {
+		_, _ = w.WriteString("\\author")
+		if a.Affiliation != "" {
+			fmt.Fprintf(w, "[%d]", index[a.Affiliation])
+		}
+		_ = w.WriteByte('{')
+		r.writeAuthorName(w, a)
+		_, _ = w.WriteString("}\n")
+	}
+	for i, affiliation := range affiliations {
+		fmt.Fprintf(w, "\\affil[%d]{", i+1)
+		r.Writer.Write(w, []byte(affiliation))
+		_, _ = w.WriteString("}\n")
+	}
+}
+
+// writeAuthorName writes the author name followed by a \thanks footnote with
+// the author's contact details, if any.
+func (r *Renderer) writeAuthorName(w util.BufWriter, a Author) {
+	r.Writer.Write(w, []byte(a.Name))
+	var details []string
+	if email := r.Writer.EscapeString(a.Email); email != "" {
+		details = append(details, "\\href{mailto:"+email+"}{"+email+"}")
+	}
+	if orcid := r.Writer.EscapeString(a.ORCID); orcid != "" {
+		details = append(details, "ORCID \\href{https://orcid.org/"+orcid+"}{"+orcid+"}")
+	}
+	if len(details) > 0 {
+		_, _ = w.WriteString("\\thanks{")
+		_, _ = w.WriteString(strings.Join(details, ", "))
+		_ = w.WriteByte('}')
+	}
+}
+
+// writePDFInfo sets the PDF document properties from metadata when hyperref is loaded.
+func (r *Renderer) writePDFInfo(w util.BufWriter, meta map[string]any) {
+	var info []string
+	if title, ok := metaString(meta, "title"); ok {
+		info = append(info, "pdftitle={"+r.Writer.EscapeString(title)+"}")
+	}
+	var names []string
+	for _, a := range metaAuthors(meta) {
+		names = append(names, a.Name)
+	}
+	if len(names) > 0 {
+		info = append(info, "pdfauthor={"+r.Writer.EscapeString(strings.Join(names, ", "))+"}")
+	}
+	if subject, ok := metaString(meta, "subject", "description"); ok {
+		info = append(info, "pdfsubject={"+r.Writer.EscapeString(subject)+"}")
+	}
+	if keywords := metaKeywords(meta); len(keywords) > 0 {
+		info = append(info, "pdfkeywords={"+r.Writer.EscapeString(strings.Join(keywords, ", "))+"}")
+	}
+	if lang := r.languageTag(meta); lang != "" {
+		info = append(info, "pdflang={"+r.Writer.EscapeString(lang)+"}")
+	}
+	if len(info) == 0 {
+		return
+	}
+	_, _ = w.WriteString("\\makeatletter\n\\@ifpackageloaded{hyperref}{\\hypersetup{\n  ")
+	_, _ = w.WriteString(strings.Join(info, ",\n  "))
+	_, _ = w.WriteString("}}{}\n\\makeatother\n")
+}
@@ -0,0 +1,192 @@
+package latex_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	latex "github.com/dihedron/goldmark-latex"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// convertWithMeta renders the markdown as if its front matter had been parsed into meta.
+func convertWithMeta(t *testing.T, markdown string, meta map[string]any, options ...latex.Option) string {
+	t.Helper()
+	r := renderer.NewRenderer(renderer.WithNodeRenderers(util.Prioritized(latex.NewRenderer(options...), 1000)))
+	md := goldmark.New(goldmark.WithRenderer(r), goldmark.WithExtensions(latex.AbstractSections))
+	source := []byte(markdown)
+	doc := md.Parser().Parse(text.NewReader(source))
+	doc.(*ast.Document).SetMeta(meta)
+	var output bytes.Buffer
+	if err := md.Renderer().Render(&output, source, doc); err != nil {
+		t.Fatal(err)
+	}
+	return output.String()
+}
+
+func TestMetadataAuthors(t *testing.T) {
+	meta := map[string]any{
+		"title": "On Things",
+		"author": []any{
+			map[any]any{"name": "Jane Doe", "affiliation": "ACME Labs", "email": "jane@acme.org", "orcid": "0000-0002-1825-0097"},
+			map[string]any{"name": "John Roe", "affiliation": "ACME Labs"},
+			map[string]any{"name": "Ann Poe", "affiliation": "Uni"},
+		},
+	}
+	output := convertWithMeta(t, "Hello\n", meta)
+	for _, expected := range []string{
+		"\\title{On Things}\n",
+		"\\usepackage{authblk}\n",
+		"\\author[1]{Jane Doe\\thanks{\\href{mailto:jane@acme.org}{jane@acme.org}, ORCID \\href{https://orcid.org/0000-0002-1825-0097}{0000-0002-1825-0097}}}\n",
+		"\\author[1]{John Roe}\n",
+		"\\author[2]{Ann Poe}\n",
+		"\\affil[1]{ACME Labs}\n\\affil[2]{Uni}\n",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in output, got:\n%s", expected, output)
+		}
+	}
+
+	output = convertWithMeta(t, "Hello\n", map[string]any{"author": []any{"Jane Doe", "John Roe"}}, latex.WithMetadata(map[string]any{"date": "2024"}))
+	if !strings.Contains(output, "\\author{Jane Doe \\and John Roe}\n\\date{2024}\n") || strings.Contains(output, "authblk") {
+		t.Errorf("expected plain author list, got:\n%s", output)
+	}
+}
+
+func TestAbstract(t *testing.T) {
+	output := convertWithMeta(t, "# Introduction\n\nText.\n", map[string]any{"abstract": "We study & prove things."}, latex.WithMakeTitle(true))
+	if !strings.Contains(output, "\\maketitle\n\n\\begin{abstract}\nWe study \\& prove things.\n\\end{abstract}\n") {
+		t.Errorf("expected abstract from metadata after maketitle, got:\n%s", output)
+	}
+
+	input := "# Introduction\n\nIntro text.\n\n# Abstract\n\nAbstract text.\n\n## Details\n\nMore.\n\n# Methods\n"
+	output = convert(t, input, latex.WithMakeTitle(true))
+	if strings.Contains(output, "\\begin{abstract}") || strings.Index(output, "{Abstract}") < strings.Index(output, "{Introduction}") {
+		t.Errorf("expected abstract section left in place by default, got:\n%s", output)
+	}
+
+	output = convert(t, input, latex.WithMakeTitle(true), latex.WithAbstractSection(true))
+	begin := strings.Index(output, "\\begin{abstract}")
+	end := strings.Index(output, "\\end{abstract}")
+	if begin < strings.Index(output, "\\maketitle") || begin > strings.Index(output, "{Introduction}") {
+		t.Fatalf("expected abstract environment right after maketitle, got:\n%s", output)
+	}
+	body := output[begin:end]
+	if !strings.Contains(body, "Abstract text.") || !strings.Contains(body, "{Details}") || strings.Contains(output, "{Abstract}") {
+		t.Errorf("expected abstract section content in abstract environment, got:\n%s", output)
+	}
+
+	output = convertWithMeta(t, input, map[string]any{"abstract": "Summary."}, latex.WithAbstractSection(true))
+	if strings.Count(output, "\\begin{abstract}") != 1 || !strings.Contains(output, "\\section{Abstract}") {
+		t.Errorf("expected metadata abstract and abstract section left in place, got:\n%s", output)
+	}
+}
+
+func TestKeywords(t *testing.T) {
+	meta := map[string]any{"abstract": "Summary.", "keywords": []any{"markdown", "LaTeX"}}
+	output := convertWithMeta(t, "Text.\n", meta)
+	if !strings.Contains(output, "pdfkeywords={markdown, LaTeX}") {
+		t.Errorf("expected pdfkeywords in preamble, got:\n%s", output)
+	}
+	if !strings.Contains(output, "\\end{abstract}\n\n\\noindent\\textbf{Keywords:} markdown, LaTeX\n") {
+		t.Errorf("expected keywords line after abstract, got:\n%s", output)
+	}
+
+	output = convert(t, "# Abstract\n\nSummary.\n\n# Introduction\n", latex.WithMetadata(map[string]any{"keywords": "a, b"}), latex.WithKeywordsCommand("keywords"), latex.WithAbstractSection(true))
+	if !strings.Contains(output, "\\end{abstract}\n\n\\keywords{a, b}\n") {
+		t.Errorf("expected keywords command after abstract section, got:\n%s", output)
+	}
+}
+
+func TestPDFInfo(t *testing.T) {
+	meta := map[string]any{
+		"title":    "A & B",
+		"author":   []any{"Jane Doe", map[string]any{"name": "John Roe"}},
+		"subject":  "Testing",
+		"keywords": "x, y",
+	}
+	output := convertWithMeta(t, "Text.\n", meta, latex.WithLanguage("german"))
+	expected := "\\@ifpackageloaded{hyperref}{\\hypersetup{\n  pdftitle={A \\& B},\n  pdfauthor={Jane Doe, John Roe},\n  pdfsubject={Testing},\n  pdfkeywords={x, y},\n  pdflang={de}}}{}\n"
+	if !strings.Contains(output, expected) {
+		t.Errorf("expected PDF properties %q, got:\n%s", expected, output)
+	}
+	if output := convert(t, "Text.\n"); strings.Contains(output, "@ifpackageloaded") {
+		t.Errorf("unexpected PDF properties without metadata, got:\n%s", output)
+	}
+}
+
+func TestTitlePage(t *testing.T) {
+	meta := map[string]any{"title": "Main", "subtitle": "Sub", "author": "Jane", "date": "2024"}
+	output := convertWithMeta(t, "Text.\n", meta, latex.WithTitlePage(latex.TitlePage{Environment: true}))
+	if !strings.Contains(output, "\\begin{titlepage}") || !strings.Contains(output, "{\\Huge\\bfseries Main\\par}") || !strings.Contains(output, "{\\Large Sub\\par}") {
+		t.Errorf("expected titlepage environment, got:\n%s", output)
+	}
+	if !strings.Contains(output, "\\title{Main\\\\[0.5em]\\large Sub}") {
+		t.Errorf("expected subtitle in title, got:\n%s", output)
+	}
+
+	output = convertWithMeta(t, "Text.\n", meta, latex.WithTitlePage(latex.TitlePage{Template: "\\noindent{\\bfseries {title}} by {author}"}))
+	if !strings.Contains(output, "\\begin{document}\n\\noindent{\\bfseries Main} by Jane\n") || strings.Contains(output, "\\maketitle") {
+		t.Errorf("expected custom title template, got:\n%s", output)
+	}
+}
+
+func TestFrontMatterOrder(t *testing.T) {
+	options := []latex.Option{
+		latex.WithMakeTitle(true),
+		latex.WithTableOfContents(true),
+		latex.WithFrontMatterOrder(latex.FrontMatterAbstract, latex.FrontMatterTitle, latex.FrontMatterContents),
+		latex.WithAbstractSection(true),
+	}
+	output := convertWithMeta(t, "# Intro\n", map[string]any{"abstract": "Summary."}, options...)
+	if !strings.Contains(output, "\\end{abstract}\n\\maketitle\n\\tableofcontents\n") {
+		t.Errorf("expected abstract, title and contents in order, got:\n%s", output)
+	}
+
+	output = convert(t, "# Abstract\n\nSummary.\n\n# Intro\n", options...)
+	if !strings.Contains(output, "\\end{abstract}\n\\maketitle\n\\tableofcontents\n") {
+		t.Errorf("expected title and contents after abstract section, got:\n%s", output)
+	}
+}
+
+func TestCoverAndDedication(t *testing.T) {
+	meta := map[string]any{"title": "My Book", "cover-image": "cover.jpg", "dedication": "To my cat."}
+	output := convertWithMeta(t, "# Chapter\n", meta, latex.WithMakeTitle(true))
+	if !strings.Contains(output, "\\usepackage{eso-pic}\n") {
+		t.Errorf("expected eso-pic in preamble, got:\n%s", output)
+	}
+	cover := strings.Index(output, "\\includegraphics[width=\\paperwidth,height=\\paperheight]{cover.jpg}")
+	title := strings.Index(output, "\\maketitle")
+	dedication := strings.Index(output, "\\itshape To my cat.")
+	if cover < strings.Index(output, "\\begin{document}") || cover > title || dedication < title || dedication > strings.Index(output, "{Chapter}") {
+		t.Errorf("expected cover, title and dedication in order, got:\n%s", output)
+	}
+
+	meta["cover-image"] = "x}}}\\input{/etc/passwd}"
+	output = convertWithMeta(t, "# Chapter\n", meta)
+	if strings.Contains(output, "\\input") || !strings.Contains(output, "\\begin{titlepage}") {
+		t.Errorf("expected unsafe cover image left out, got:\n%s", output)
+	}
+}
+
+func TestRevisions(t *testing.T) {
+	meta := map[string]any{"revisions": []any{
+		map[string]any{"version": "1.0", "date": "2024-01-01", "author": "Jane", "description": "Initial release"},
+		map[any]any{"version": "1.1", "date": "2024-02-01", "author": "John", "description": "Fixed 100% of typos"},
+	}}
+	output := convertWithMeta(t, "Text.\n", meta, latex.WithMakeTitle(true))
+	for _, expected := range []string{
+		"\\maketitle\n\\section*{Revision History}\n",
+		"\\textbf{Version} & \\textbf{Date} & \\textbf{Author} & \\textbf{Description} \\\\\n\\hline\n",
+		"1.0 & 2024-01-01 & Jane & Initial release \\\\\n\\hline\n",
+		"1.1 & 2024-02-01 & John & Fixed 100\\% of typos \\\\\n\\hline\n",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in output, got:\n%s", expected, output)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package latex
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"sync"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/util"
+)
+
+// WithParallelSections renders the top level sections of documents, those
+// started by top level headings, concurrently with up to workers goroutines
+// and writes them in order, for huge documents. The output is the same as
+// rendered serially: the sections depending on the previous ones in ways which
+// cannot be foreseen, such as HTML elements spanning sections, are rendered
+// again once the previous ones are. The functions set with options, such as
+// node renderer overrides, are called concurrently. Books, beamer
+// presentations and renderings with node hooks, traces or streaming are
+// rendered serially.
+func WithParallelSections(workers int) Option {
+	return func(r *Renderer) {
+		r.ParallelSections = workers
+	}
+}
+
+// documentState is the state of the rendering of a document carried from a
+// top level section to the next.
+type documentState struct {
+	headingSeen, inAppendix bool
+	matter                  matter
+	sections, quoteDepth    int
+	frame, beamerBlock      bool
+	abstract                *Abstract
+	inlineHTML              []htmlContainer
+	htmlContainers          []htmlContainer
+}
+
+func (r *Renderer) saveState() documentState {
+	return documentState{
+		headingSeen:    r.headingSeen,
+		inAppendix:     r.inAppendix,
+		matter:         r.matter,
+		sections:       r.sections,
+		quoteDepth:     r.quoteDepth,
+		frame:          r.frame,
+		beamerBlock:    r.beamerBlock,
+		abstract:       r.abstract,
+		inlineHTML:     append([]htmlContainer(nil), r.inlineHTML...),
+		htmlContainers: append([]htmlContainer(nil), r.htmlContainers...),
+	}
+}
+
+func (r *Renderer) restoreState(s documentState) {
+	r.headingSeen, r.inAppendix, r.matter = s.headingSeen, s.inAppendix, s.matter
+	r.sections, r.quoteDepth = s.sections, s.quoteDepth
+	r.frame, r.beamerBlock, r.abstract = s.frame, s.beamerBlock, s.abstract
+	r.inlineHTML = append(r.inlineHTML[:0], s.inlineHTML...)
+	r.htmlContainers = append(r.htmlContainers[:0], s.htmlContainers...)
+}
+
+func (s documentState) equal(t documentState) bool {
+	if s.headingSeen != t.headingSeen || s.inAppendix != t.inAppendix || s.matter != t.matter ||
+		s.sections != t.sections || s.quoteDepth != t.quoteDepth ||
+		s.frame != t.frame || s.beamerBlock != t.beamerBlock || s.abstract != t.abstract ||
+		len(s.inlineHTML) != len(t.inlineHTML) || len(s.htmlContainers) != len(t.htmlContainers) {
+		return false
+	}
+	for i := range s.inlineHTML {
+		if s.inlineHTML[i] != t.inlineHTML[i] {
+			return false
+		}
+	}
+	for i := range s.htmlContainers {
+		if s.htmlContainers[i] != t.htmlContainers[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// section is a run of top level blocks rendered by a worker.
+type section struct {
+	first, next ast.Node
+	// start is the state the section is rendered from, end the state after it.
+	start, end  documentState
+	out         bytes.Buffer
+	diagnostics []Diagnostic
+	err         error
+}
+
+// parallelSections reports whether the sections of the document are
+// rendered concurrently.
+func (r *Renderer) parallelSections() bool {
+	return r.ParallelSections > 1 && r.book == nil && !r.Beamer &&
+		len(r.NodeHooks) == 0 && r.Trace == nil && !r.Streaming
+}
+
+// renderDocumentBody renders the blocks of the document concurrently by top
+// level section in parallel mode, or leaves them to the walk of the document.
+func (r *Renderer) renderDocumentBody(w util.BufWriter, source []byte, doc ast.Node) (ast.WalkStatus, error) {
+	if !r.parallelSections() {
+		return ast.WalkContinue, nil
+	}
+	sections := r.topSections(source, doc)
+	if len(sections) < 2 {
+		return ast.WalkContinue, nil
+	}
+	queue := make(chan *section)
+	var wg sync.WaitGroup
+	for i := 0; i < r.ParallelSections && i < len(sections); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s := r.sectionSession()
+			for sec := range queue {
+				s.renderSection(source, sec)
+			}
+		}()
+	}
+	for _, sec := range sections {
+		queue <- sec
+	}
+	close(queue)
+	wg.Wait()
+
+	var serial *Renderer
+	state := r.saveState()
+	for _, sec := range sections {
+		if !sec.start.equal(state) {
+			// Mispredicted: render the section from the state left by the previous one.
+			if serial == nil {
+				serial = r.sectionSession()
+			}
+			sec.start = state
+			sec.out.Reset()
+			serial.renderSection(source, sec)
+		}
+		if sec.err != nil {
+			return ast.WalkStop, sec.err
+		}
+		_, _ = w.Write(sec.out.Bytes())
+		for _, d := range sec.diagnostics {
+			r.report(d)
+		}
+		state = sec.end
+	}
+	r.restoreState(state)
+	return ast.WalkSkipChildren, nil
+}
+
+// topSections splits the blocks of the document into sections starting at top
+// level headings and predicts the state each starts from, reproducing the
+// effects of the headings on the state of the document.
+func (r *Renderer) topSections(source []byte, doc ast.Node) []*section {
+	var sections []*section
+	for n := doc.FirstChild(); n != nil; n = n.NextSibling() {
+		h, ok := n.(*ast.Heading)
+		if len(sections) == 0 || ok && r.headingLevel(h) == 0 {
+			if len(sections) > 0 {
+				sections[len(sections)-1].next = n
+			}
+			sections = append(sections, &section{first: n})
+		}
+	}
+	p := r.sectionSession()
+	p.restoreState(r.saveState())
+	discard := bufio.NewWriter(io.Discard)
+	for _, sec := range sections {
+		sec.start = p.saveState()
+		if h, ok := sec.first.(*ast.Heading); ok && r.headingLevel(h) == 0 {
+			p.startHeading(discard, source, h, 0)
+		}
+	}
+	return sections
+}
+
+// sectionSession returns a renderer with the configuration of r and a state
+// of its own, rendering sections. Its output and diagnostics are written and
+// reported by r.
+func (r *Renderer) sectionSession() *Renderer {
+	s := &Renderer{Config: r.Config, unshared: true}
+	s.TeeWriters, s.Logger = nil, nil
+	s.ctx, s.rtl, s.bidi = r.ctx, r.rtl, r.bidi
+	return s
+}
+
+// renderSection renders the blocks of a section from its start state.
+func (r *Renderer) renderSection(source []byte, sec *section) {
+	r.resetState()
+	r.restoreState(sec.start)
+	r.limited = nil // The writer may be reused.
+	bw := getBufWriter(&sec.out)
+	defer putBufWriter(bw)
+	for n := sec.first; n != sec.next; n = n.NextSibling() {
+		if sec.err = r.renderNode(bw, source, n); sec.err != nil {
+			return
+		}
+	}
+	sec.err = bw.Flush()
+	sec.end = r.saveState()
+	sec.diagnostics = r.diagnostics
+}
@@ -0,0 +1,84 @@
+package latex
+
+import (
+	"strings"
+
+	"github.com/yuin/goldmark/util"
+)
+
+// WithPDFA configures the preamble for archival PDF/A output with the pdfx package,
+// e.g. WithPDFA("a-2b"). The XMP metadata required by the standard is generated
+// from the document metadata: title, author, subject, keywords, lang and date.
+func WithPDFA(level string) Option {
+	return func(r *Renderer) {
+		r.PDFA = level
+	}
+}
+
+// WithTaggedPDF enables the LaTeX tagging support, based on tagpdf, through
+// \DocumentMetadata, moving towards PDF/UA compliant output. Images get
+// their markdown description as alternative text.
+func WithTaggedPDF(tagged bool) Option {
+	return func(r *Renderer) {
+		r.TaggedPDF = tagged
+	}
+}
+
+// writeDocumentMetadata writes the \DocumentMetadata declaration, which must precede \documentclass.
+func (r *Renderer) writeDocumentMetadata(w util.BufWriter, meta map[string]any) {
+	if !r.TaggedPDF {
+		return
+	}
+	_, _ = w.WriteString("\\DocumentMetadata{\n  testphase={phase-III},\n  pdfversion=2.0,\n  pdfstandard=ua-2")
+	if lang := r.languageTag(meta); lang != "" {
+		_, _ = w.WriteString(",\n  lang=" + lang)
+	}
+	_, _ = w.WriteString("\n}\n")
+}
+
+// writePreamble writes the preamble, inserting the declarations which must precede
+// the document class and the packages which must be loaded right after it.
+func (r *Renderer) writePreamble(w util.BufWriter, b *PreambleBuilder, meta map[string]any) {
+	r.writeDocumentMetadata(w, meta)
+	b.writePrelude(w)
+	b.writeClass(w)
+	r.writeEarlyPackages(w, meta)
+	b.writeBody(w)
+}
+
+// writeEarlyPackages writes the packages loaded right after the document class.
+func (r *Renderer) writeEarlyPackages(w util.BufWriter, meta map[string]any) {
+	if r.PDFA == "" {
+		return
+	}
+	// pdfx reads the XMP metadata from \jobname.xmpdata and loads hyperref itself.
+	_, _ = w.WriteString("\\begin{filecontents*}[overwrite]{\\jobname.xmpdata}\n")
+	if title, ok := metaString(meta, "title"); ok {
+		_, _ = w.WriteString("\\Title{" + r.Writer.EscapeString(title) + "}\n")
+	}
+	var names []string
+	for _, a := range metaAuthors(meta) {
+		names = append(names, r.Writer.EscapeString(a.Name))
+	}
+	if len(names) > 0 {
+		_, _ = w.WriteString("\\Author{" + strings.Join(names, "\\sep ") + "}\n")
+	}
+	if subject, ok := metaString(meta, "subject", "description"); ok {
+		_, _ = w.WriteString("\\Subject{" + r.Writer.EscapeString(subject) + "}\n")
+	}
+	if keywords := metaKeywords(meta); len(keywords) > 0 {
+		for i := range keywords {
+			keywords[i] = r.Writer.EscapeString(keywords[i])
+		}
+		_, _ = w.WriteString("\\Keywords{" + strings.Join(keywords, "\\sep ") + "}\n")
+	}
+	if lang := r.languageTag(meta); lang != "" {
+		_, _ = w.WriteString("\\Language{" + r.Writer.EscapeString(lang) + "}\n")
+	}
+	if date, ok := metaString(meta, "date"); ok {
+		_, _ = w.WriteString("\\Date{" + r.Writer.EscapeString(date) + "}\n")
+	}
+	_, _ = w.WriteString("\\end{filecontents*}\n")
+	r.warnComment(w, "pdfx embeds an sRGB color profile as output intent, which may require the colorprofiles package")
+	_, _ = w.WriteString("\\usepackage[" + r.PDFA + "]{pdfx}\n")
+}
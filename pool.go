@@ -0,0 +1,77 @@
+package latex
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"sync"
+)
+
+// Pools of the temporary structures created while rendering, so that services
+// converting many small documents reuse them instead of churning the GC.
+var (
+	attributesPool = sync.Pool{New: func() any { return make(map[string]string) }}
+	bufferPool     = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+	bufWriterPool  = sync.Pool{New: func() any { return bufio.NewWriter(nil) }}
+	runeSetPool    = sync.Pool{New: func() any { return make(map[rune]struct{}) }}
+)
+
+// Structures grown beyond these sizes are dropped rather than pooled, not to
+// retain the memory of an exceptional document.
+const (
+	maxPooledBuffer  = 64 << 10
+	maxPooledRuneSet = 4096
+)
+
+// getAttributes returns an empty attribute map, to be released with putAttributes.
+func getAttributes() map[string]string {
+	return attributesPool.Get().(map[string]string)
+}
+
+func putAttributes(m map[string]string) {
+	for k := range m {
+		delete(m, k)
+	}
+	attributesPool.Put(m)
+}
+
+// getBuffer returns an empty buffer, to be released with putBuffer.
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+func putBuffer(b *bytes.Buffer) {
+	if b.Cap() > maxPooledBuffer {
+		return
+	}
+	b.Reset()
+	bufferPool.Put(b)
+}
+
+// getBufWriter returns a buffered writer writing to w, to be released with
+// putBufWriter once flushed.
+func getBufWriter(w io.Writer) *bufio.Writer {
+	bw := bufWriterPool.Get().(*bufio.Writer)
+	bw.Reset(w)
+	return bw
+}
+
+func putBufWriter(bw *bufio.Writer) {
+	bw.Reset(nil)
+	bufWriterPool.Put(bw)
+}
+
+// getRuneSet returns an empty set of runes, to be released with putRuneSet.
+func getRuneSet() map[rune]struct{} {
+	return runeSetPool.Get().(map[rune]struct{})
+}
+
+func putRuneSet(set map[rune]struct{}) {
+	if len(set) > maxPooledRuneSet {
+		return
+	}
+	for k := range set {
+		delete(set, k)
+	}
+	runeSetPool.Put(set)
+}
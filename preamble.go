@@ -0,0 +1,274 @@
+package latex
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/util"
+)
+
+// Geometry holds the page layout passed to the geometry package.
+type Geometry struct {
+	// Paper size, such as "a4" or "letterpaper".
+	Paper string
+	// Margins in CSS order: one value for all sides, two for vertical and
+	// horizontal margins or four for top, right, bottom and left.
+	Margins []string
+}
+
+// WithGeometry configures the page size and margins through the geometry package,
+// e.g. WithGeometry("a4", "2cm") or WithGeometry("letter", "1in", "1.25in").
+func WithGeometry(paper string, margins ...string) Option {
+	return func(r *Renderer) {
+		r.Geometry = &Geometry{Paper: paper, Margins: margins}
+	}
+}
+
+// options returns the geometry package options.
+func (g *Geometry) options() []string {
+	var opts []string
+	if g.Paper != "" {
+		paper := g.Paper
+		if !strings.HasSuffix(paper, "paper") {
+			paper += "paper"
+		}
+		opts = append(opts, paper)
+	}
+	switch m := g.Margins; len(m) {
+	case 0:
+	case 1:
+		opts = append(opts, "margin="+m[0])
+	case 2:
+		opts = append(opts, "vmargin="+m[0], "hmargin="+m[1])
+	case 3:
+		opts = append(opts, "top="+m[0], "hmargin="+m[1], "bottom="+m[2])
+	default:
+		opts = append(opts, "top="+m[0], "right="+m[1], "bottom="+m[2], "left="+m[3])
+	}
+	return opts
+}
+
+// WithLineSpacing sets the line spacing through the setspace package: 1, 1.5
+// and 2 select single, one-and-a-half and double spacing, any other
+// positive value is passed to \setstretch.
+func WithLineSpacing(spacing float64) Option {
+	return func(r *Renderer) {
+		r.LineSpacing = spacing
+	}
+}
+
+// writeLineSpacing writes the setspace configuration for the given spacing.
+func writeLineSpacing(w util.BufWriter, spacing float64) {
+	if spacing <= 0 {
+		return
+	}
+	_, _ = w.WriteString("\\usepackage{setspace}\n")
+	switch spacing {
+	case 1:
+		_, _ = w.WriteString("\\singlespacing\n")
+	case 1.5:
+		_, _ = w.WriteString("\\onehalfspacing\n")
+	case 2:
+		_, _ = w.WriteString("\\doublespacing\n")
+	default:
+		_, _ = w.WriteString("\\setstretch{")
+		_, _ = w.WriteString(strconv.FormatFloat(spacing, 'f', -1, 64))
+		_, _ = w.WriteString("}\n")
+	}
+}
+
+// ParagraphStyle selects how consecutive paragraphs are set apart.
+type ParagraphStyle int
+
+const (
+	// DefaultParagraphs keeps the preamble's paragraph settings.
+	DefaultParagraphs ParagraphStyle = iota
+	// IndentParagraphs indents the first line of paragraphs, without vertical space.
+	IndentParagraphs
+	// SkipParagraphs separates paragraphs with vertical space, without indentation.
+	SkipParagraphs
+)
+
+// WithParagraphStyle chooses between first-line indentation and vertical
+// space between paragraphs.
+func WithParagraphStyle(style ParagraphStyle) Option {
+	return func(r *Renderer) {
+		r.ParagraphStyle = style
+	}
+}
+
+func writeParagraphStyle(w util.BufWriter, style ParagraphStyle) {
+	switch style {
+	case IndentParagraphs:
+		_, _ = w.WriteString("\\setlength{\\parindent}{1.5em}\n\\setlength{\\parskip}{0pt}\n")
+	case SkipParagraphs:
+		_, _ = w.WriteString("\\usepackage{parskip}\n")
+	}
+}
+
+// writePreambleExtras writes the preamble lines generated from the renderer's
+// options and metadata, which are added after the default or custom preamble.
+func (r *Renderer) writePreambleExtras(w util.BufWriter, meta map[string]any) {
+	if r.Geometry != nil && !r.Beamer {
+		// \geometry applies the layout whatever the options geometry is loaded with.
+		_, _ = w.WriteString("\\usepackage{geometry}\n\\geometry{")
+		_, _ = w.WriteString(strings.Join(r.Geometry.options(), ", "))
+		_, _ = w.WriteString("}\n")
+	}
+	writeLineSpacing(w, r.LineSpacing)
+	writeParagraphStyle(w, r.ParagraphStyle)
+	r.writeHeaders(w, meta)
+	r.writeWatermark(w)
+	r.writeBuildInfo(w)
+	if image, ok := metaString(meta, "cover-image"); ok && image != "" {
+		_, _ = w.WriteString("\\usepackage{graphicx}\n\\usepackage{xcolor}\n\\usepackage{eso-pic}\n")
+	}
+	r.setDirection(r.documentLanguages(meta))
+}
+
+// WithDynamicPreamble replaces the default preamble with one generated from the
+// document content, loading only the packages it needs: graphicx for images,
+// minted for code, framed for quotes, ulem for strikethrough and hyperref for
+// links. It has no effect when a custom preamble is set.
+func WithDynamicPreamble(dynamic bool) Option {
+	return func(r *Renderer) {
+		r.DynamicPreamble = dynamic
+	}
+}
+
+// contentPackages lists the packages needed by extension syntaxes, which are
+// added to the preamble when used.
+var contentPackages = map[string]bool{
+	"siunitx":    true,
+	"mhchem":     true,
+	"amsmath":    true,
+	"multirow":   true,
+	"tcolorbox":  true,
+	"epigraph":   true,
+	"marginnote": true,
+	"sidenotes":  true,
+	"xcolor":     true,
+}
+
+// packageOptions holds the options used to load packages in generated preambles.
+var packageOptions = map[string]string{
+	"ulem":   "normalem",
+	"mhchem": "version=4",
+}
+
+// dynamicPreamble generates a preamble loading the packages needed by the document.
+func (r *Renderer) dynamicPreamble(doc ast.Node, source []byte, meta map[string]any) *PreambleBuilder {
+	b := &PreambleBuilder{}
+	b.SetClass("article")
+	b.AddCommand("")
+	r.Engine.addFonts(b)
+	b.AddPackage("geometry", "margin=1in")
+	r.addContentPackages(b, doc, source, meta)
+	b.AddCommand("\n\\setlength{\\parskip}{0.5\\baselineskip}\n\\setlength{\\parindent}{0pt}")
+	return b
+}
+
+// addContentPackages adds the packages needed by the document content, hyperref last.
+func (r *Renderer) addContentPackages(b *PreambleBuilder, doc ast.Node, source []byte, meta map[string]any) {
+	hyperref := false
+	for _, a := range metaAuthors(meta) {
+		hyperref = hyperref || a.Email != "" || a.ORCID != "" // For \href.
+	}
+	for _, p := range r.usedPackages(doc, source) {
+		if p == "hyperref" {
+			hyperref = true // Loaded last.
+			continue
+		}
+		addUsedPackage(b, p)
+	}
+	if hyperref {
+		b.AddPackage("hyperref")
+		b.AddCommand("\\hypersetup{colorlinks, linkcolor=blue, urlcolor=blue, breaklinks=true}")
+	}
+}
+
+// preambleBuilder assembles the preamble from the default, dynamic or custom
+// preamble, the lines generated from the options and metadata and the user's
+// contributions. It returns the kind of the base preamble.
+func (r *Renderer) preambleBuilder(doc ast.Node, source []byte, meta map[string]any) (*PreambleBuilder, string, error) {
+	if err := r.loadPreamble(); err != nil {
+		return nil, "custom", err
+	}
+	b := &PreambleBuilder{}
+	kind := "custom"
+	switch {
+	case r.Preamble != nil:
+		b.Parse(r.Preamble)
+	case r.Preset != nil:
+		kind = r.Preset.Name
+		b = r.presetPreamble(doc, source, meta)
+	case r.DynamicPreamble:
+		kind = "dynamic"
+		b = r.dynamicPreamble(doc, source, meta)
+	default:
+		kind = "default"
+		b.Parse(defaultPreamble)
+	}
+	if r.PreambleTemplate {
+		preamble, err := executePreamble(b.Bytes(), r.preambleData(doc, source, meta), r.Writer)
+		if err != nil {
+			return nil, kind, err
+		}
+		b = &PreambleBuilder{}
+		b.Parse(preamble)
+	}
+	if kind == "default" {
+		r.Engine.addFonts(b)
+	}
+	// Packages needed by extension syntaxes are loaded whatever the preamble.
+	for _, p := range r.usedPackages(doc, source) {
+		if contentPackages[p] {
+			addUsedPackage(b, p)
+		}
+	}
+	addTheorems(b, r.documents(doc, source)...)
+	addAdmonitions(b, r.documents(doc, source)...)
+	if r.usesTodoNotes(r.documents(doc, source)...) {
+		b.AddPackage("todonotes")
+	}
+	var extras bytes.Buffer
+	w := bufio.NewWriter(&extras)
+	r.writePreambleExtras(w, meta)
+	_ = w.Flush()
+	b.Parse(extras.Bytes())
+	if r.Beamer {
+		addBeamer(b)
+	}
+	if r.Preset != nil {
+		for _, p := range r.Preset.Conflicts {
+			b.removePackage(p)
+		}
+	}
+	for _, fn := range r.PreambleBuilders {
+		fn(b)
+	}
+	// Languages go last: bidi, loaded by polyglossia for right to left
+	// languages, must come after other packages.
+	extras.Reset()
+	w.Reset(&extras)
+	languages, unsafe := r.checkedLanguages(meta)
+	for _, name := range unsafe {
+		r.warnAt(nil, nil, -1, DiagnosticUnsafeContent, "language %s skipped, it is not a language name", name)
+	}
+	r.writeLanguages(w, languages)
+	_ = w.Flush()
+	b.Parse(extras.Bytes())
+	return b, kind, nil
+}
+
+// addUsedPackage adds a package needed by the document content with its options.
+func addUsedPackage(b *PreambleBuilder, name string) {
+	if opts, ok := packageOptions[name]; ok {
+		b.AddPackage(name, opts)
+	} else {
+		b.AddPackage(name)
+	}
+}
@@ -0,0 +1,349 @@
+package latex_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	latex "github.com/dihedron/goldmark-latex"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+func TestGeometry(t *testing.T) {
+	for _, test := range []struct {
+		option   latex.Option
+		expected string
+	}{
+		{latex.WithGeometry("a4", "2cm"), "\\geometry{a4paper, margin=2cm}"},
+		{latex.WithGeometry("letterpaper", "1in", "1.25in"), "\\geometry{letterpaper, vmargin=1in, hmargin=1.25in}"},
+		{latex.WithGeometry("", "1cm", "2cm", "3cm", "4cm"), "\\geometry{top=1cm, right=2cm, bottom=3cm, left=4cm}"},
+	} {
+		output := convert(t, "Text.\n", test.option)
+		if !strings.Contains(output, "{geometry}\n") || !strings.Contains(output, test.expected+"\n") {
+			t.Errorf("expected %q in output, got:\n%s", test.expected, output)
+		}
+		if strings.Index(output, test.expected) > strings.Index(output, "\\begin{document}") {
+			t.Errorf("geometry must be configured in the preamble")
+		}
+	}
+}
+
+func TestEngine(t *testing.T) {
+	mapping := latex.WithUnicodeCharactersMapping(func(r rune) (string, bool) { return "?", true })
+	output := convert(t, "Ünïcode\n", mapping)
+	for _, expected := range []string{"\\usepackage[utf8]{inputenc}", "\\usepackage[T1]{fontenc}", "\\DeclareUnicodeCharacter{"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q with pdflatex, got:\n%s", expected, output)
+		}
+	}
+	for _, engine := range []latex.Engine{latex.XeLaTeX, latex.LuaLaTeX} {
+		output := convert(t, "Ünïcode\n", mapping, latex.WithEngine(engine))
+		if !strings.Contains(output, "\\usepackage{fontspec}") {
+			t.Errorf("expected fontspec with %v, got:\n%s", engine, output)
+		}
+		for _, unexpected := range []string{"{inputenc}", "{fontenc}", "\\DeclareUnicodeCharacter"} {
+			if strings.Contains(output, unexpected) {
+				t.Errorf("unexpected %q with %v, got:\n%s", unexpected, engine, output)
+			}
+		}
+	}
+	if !strings.Contains(string(latex.DefaultPreamble()), "\\usepackage[T1]{fontenc}") {
+		t.Error("default preamble should include the pdflatex font setup")
+	}
+}
+
+func TestLanguage(t *testing.T) {
+	output := convert(t, "Text.\n", latex.WithLanguage("german", "english"))
+	if !strings.Contains(output, "\\usepackage[english,main=german]{babel}\n\\usepackage[autostyle]{csquotes}\n") {
+		t.Errorf("expected babel setup, got:\n%s", output)
+	}
+	output = convert(t, "Text.\n", latex.WithLanguage("german", "english", "french"), latex.WithEngine(latex.XeLaTeX))
+	if !strings.Contains(output, "\\usepackage{polyglossia}\n\\setdefaultlanguage{german}\n\\setotherlanguages{english,french}\n") {
+		t.Errorf("expected polyglossia setup, got:\n%s", output)
+	}
+	meta := map[string]any{"lang": "x]{babel}\\input{/etc/passwd}\\usepackage[y"}
+	for _, engine := range []latex.Engine{latex.PDFLaTeX, latex.XeLaTeX} {
+		output = convertWithMeta(t, "Text.\n", meta, latex.WithEngine(engine))
+		if strings.Contains(output, "\\input{") || strings.Contains(output, "]{babel}") || strings.Contains(output, "polyglossia") {
+			t.Errorf("expected the unsafe language dropped with %v, got:\n%s", engine, output)
+		}
+	}
+}
+
+func TestRightToLeft(t *testing.T) {
+	const markdown = "English paragraph.\n\nפסקה בעברית.\n\n- פריט\n- עוד פריט\n"
+	output := convert(t, markdown, latex.WithLanguage("english", "hebrew"), latex.WithEngine(latex.XeLaTeX))
+	if !strings.Contains(output, "\\begin{RTL}\nפסקה בעברית.\n\\end{RTL}") {
+		t.Errorf("expected RTL paragraph, got:\n%s", output)
+	}
+	if !strings.Contains(output, "\\begin{RTL}\n\\begin{itemize}") || strings.Count(output, "\\begin{RTL}") != 2 {
+		t.Errorf("expected a single RTL list environment, got:\n%s", output)
+	}
+
+	output = convertWithMeta(t, markdown, map[string]any{"lang": "he"}, latex.WithEngine(latex.LuaLaTeX))
+	if !strings.Contains(output, "\\setdefaultlanguage{hebrew}") {
+		t.Errorf("expected hebrew from lang metadata, got:\n%s", output)
+	}
+	if !strings.Contains(output, "\\begin{LTR}\nEnglish paragraph.\n\\end{LTR}") || strings.Contains(output, "\\begin{RTL}") {
+		t.Errorf("expected only the English paragraph to be wrapped, got:\n%s", output)
+	}
+}
+
+func TestRightToLeftOption(t *testing.T) {
+	output := convert(t, "Text.\n", latex.WithRightToLeft(true), latex.WithEngine(latex.XeLaTeX))
+	if !strings.Contains(output, "\\usepackage{bidi}\n\\AtBeginDocument{\\setRTL}\n") {
+		t.Errorf("expected bidi loaded, got:\n%s", output)
+	}
+	output = convert(t, "Text.\n", latex.WithRightToLeft(true), latex.WithLanguage("hebrew"), latex.WithEngine(latex.XeLaTeX))
+	if strings.Contains(output, "bidi") || strings.Contains(output, "\\setRTL") {
+		t.Errorf("expected polyglossia to set the direction, got:\n%s", output)
+	}
+	output = convert(t, "Text.\n", latex.WithRightToLeft(true), latex.WithEngine(latex.XeLaTeX),
+		latex.WithPreambleBuilder(func(b *latex.PreambleBuilder) { b.AddPackage("tikz") }))
+	if i, j := strings.Index(output, "\\usepackage{tikz}"), strings.Index(output, "\\usepackage{bidi}"); i < 0 || i > j {
+		t.Errorf("expected bidi loaded after the packages of preamble builders, got:\n%s", output)
+	}
+}
+
+func TestLineSpacing(t *testing.T) {
+	for spacing, expected := range map[float64]string{
+		1:    "\\singlespacing",
+		1.5:  "\\onehalfspacing",
+		2:    "\\doublespacing",
+		1.25: "\\setstretch{1.25}",
+	} {
+		output := convert(t, "Text.\n", latex.WithLineSpacing(spacing))
+		if !strings.Contains(output, "\\usepackage{setspace}\n"+expected+"\n") {
+			t.Errorf("expected %q for spacing %v, got:\n%s", expected, spacing, output)
+		}
+	}
+	if output := convert(t, "Text.\n"); strings.Contains(output, "setspace") {
+		t.Errorf("unexpected setspace by default, got:\n%s", output)
+	}
+}
+
+func TestParagraphStyle(t *testing.T) {
+	output := convert(t, "One.\n\nTwo.\n", latex.WithParagraphStyle(latex.IndentParagraphs))
+	if !strings.Contains(output, "\\setlength{\\parindent}{1.5em}\n\\setlength{\\parskip}{0pt}\n") {
+		t.Errorf("expected indented paragraphs, got:\n%s", output)
+	}
+	output = convert(t, "One.\n\nTwo.\n", latex.WithParagraphStyle(latex.SkipParagraphs))
+	if !strings.Contains(output, "\\usepackage{parskip}\n") {
+		t.Errorf("expected parskip package, got:\n%s", output)
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	headers := latex.Headers{
+		HeadLeft:   "{title}",
+		HeadRight:  "{section}",
+		FootLeft:   "R&D {unknown}",
+		FootCenter: "Page {page} of {pages}",
+	}
+	output := convertWithMeta(t, "Text.\n", map[string]any{"title": "The_Title"}, latex.WithHeaders(headers))
+	for _, expected := range []string{
+		"\\usepackage{fancyhdr}\n\\usepackage{lastpage}\n\\pagestyle{fancy}\n\\fancyhf{}\n",
+		"\\fancyhead[L]{The\\_Title}\n",
+		"\\fancyhead[R]{{\\leftmark}}\n",
+		"\\fancyfoot[L]{R\\&D \\{unknown\\}}\n",
+		"\\fancyfoot[C]{Page {\\thepage} of \\pageref{LastPage}}\n",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in output, got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestPageNumbering(t *testing.T) {
+	const markdown = "# One\n\n## Sub\n\n# Two\n"
+	output := convert(t, markdown, latex.WithMakeTitle(true), latex.WithPageNumbering(latex.PageNumbering{
+		FrontMatterStyle:  "roman",
+		NoTitlePageNumber: true,
+	}))
+	if !strings.Contains(output, "\\begin{document}\n\\pagenumbering{roman}\n\\maketitle\n\\thispagestyle{empty}\n") {
+		t.Errorf("expected roman front matter and no title page number, got:\n%s", output)
+	}
+	if strings.Count(output, "\\pagenumbering{arabic}") != 1 || strings.Index(output, "\\pagenumbering{arabic}") > strings.Index(output, "{One}") {
+		t.Errorf("expected a single switch to arabic before the first heading, got:\n%s", output)
+	}
+
+	output = convert(t, markdown, latex.WithPageNumbering(latex.PageNumbering{Style: "Roman", ResetAtTopHeadings: true}))
+	if strings.Count(output, "\\clearpage\n\\pagenumbering{Roman}") != 2 {
+		t.Errorf("expected numbering reset at each top level heading, got:\n%s", output)
+	}
+}
+
+func TestWatermark(t *testing.T) {
+	output := convert(t, "Text.\n", latex.WithWatermark("DRAFT"), latex.WithConfidentialStamp())
+	if !strings.Contains(output, "\\usepackage{draftwatermark}\n\\SetWatermarkText{DRAFT}\n") {
+		t.Errorf("expected draft watermark, got:\n%s", output)
+	}
+	if !strings.Contains(output, "\\usepackage{eso-pic}\n") || !strings.Contains(output, "\\bfseries CONFIDENTIAL}") {
+		t.Errorf("expected confidential stamp, got:\n%s", output)
+	}
+}
+
+func TestPDFA(t *testing.T) {
+	meta := map[string]any{"title": "Report", "author": []any{"Jane", "John"}, "keywords": "a, b", "lang": "en-US"}
+	output := convertWithMeta(t, "Text.\n", meta, latex.WithPDFA("a-2b"))
+	expected := "\\documentclass{article}\n\\begin{filecontents*}[overwrite]{\\jobname.xmpdata}\n\\Title{Report}\n\\Author{Jane\\sep John}\n\\Keywords{a\\sep b}\n\\Language{en-US}\n\\end{filecontents*}\n"
+	if !strings.Contains(output, expected) {
+		t.Errorf("expected XMP metadata right after the document class, got:\n%s", output)
+	}
+	if i := strings.Index(output, "\\usepackage[a-2b]{pdfx}"); i < 0 || i > strings.Index(output, "\\usepackage{hyperref}") {
+		t.Errorf("expected pdfx loaded before hyperref, got:\n%s", output)
+	}
+}
+
+func TestTaggedPDF(t *testing.T) {
+	output := convert(t, "![A *red* circle](circle.png)\n", latex.WithTaggedPDF(true), latex.WithLanguage("english"))
+	if !strings.Contains(output, "\\DocumentMetadata{\n  testphase={phase-III},\n  pdfversion=2.0,\n  pdfstandard=ua-2,\n  lang=en\n}\n\\documentclass{article}") {
+		t.Errorf("expected document metadata before the document class, got:\n%s", output)
+	}
+	if !strings.Contains(output, "\\includegraphics[width=\\textwidth, alt={A red circle}]{circle.png}") {
+		t.Errorf("expected image alternative text, got:\n%s", output)
+	}
+	output = convertWithMeta(t, "Text.\n", map[string]any{"lang": "en}\\input{/etc/passwd}"}, latex.WithTaggedPDF(true))
+	if !strings.Contains(output, "pdfstandard=ua-2\n}\n") || strings.Contains(output, "lang=") || strings.Contains(output, "pdflang") {
+		t.Errorf("expected the unsafe language tag omitted, got:\n%s", output)
+	}
+	output = convertWithMeta(t, "Text.\n", map[string]any{"lang": "pt-BR"}, latex.WithTaggedPDF(true))
+	if !strings.Contains(output, "  lang=pt-BR\n}\n") {
+		t.Errorf("expected the language tag, got:\n%s", output)
+	}
+}
+
+func TestBuildInfo(t *testing.T) {
+	output := convert(t, "Text.\n",
+		latex.WithBuildInfo("v1.4.2", "abc1234", "2024-05-01"),
+		latex.WithColophon(true),
+		latex.WithHeaders(latex.Headers{FootRight: "{buildinfo}", FootLeft: "{version}"}),
+	)
+	for _, expected := range []string{
+		"\\providecommand{\\buildversion}{v1.4.2}\n",
+		"\\providecommand{\\buildcommit}{abc1234}\n",
+		"\\providecommand{\\builddate}{2024-05-01}\n",
+		"\\providecommand{\\buildinfo}{v1.4.2 --- abc1234 --- 2024-05-01}\n",
+		"\\fancyfoot[L]{v1.4.2}\n",
+		"\\fancyfoot[R]{v1.4.2 --- abc1234 --- 2024-05-01}\n",
+		"\\small Built from \\buildinfo\n\\end{center}\n% goldmark-latex: end of document",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in output, got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestPreambleTemplate(t *testing.T) {
+	preamble := []byte("\\documentclass{article}\n" +
+		"\\title{((* .Title *))}\n" +
+		"((* if .HasPackage \"graphicx\" *))\\usepackage{graphicx}\n((* end *))" +
+		"((* range .Packages *))% uses ((* . *))\n((* end *))" +
+		"% lang: ((* .Language *)), engine: ((* .Engine *)), extra: ((* escape .Metadata.extra *))\n")
+	meta := map[string]any{"title": "A & B", "extra": "50%"}
+	output := convertWithMeta(t, "![image](a.png) [link](https://example.com)\n", meta,
+		latex.WithPreamble(preamble), latex.WithPreambleTemplate(true), latex.WithLanguage("italian"))
+	for _, expected := range []string{
+		"\\title{A \\& B}\n\\usepackage{graphicx}\n% uses graphicx\n% uses hyperref\n",
+		"% lang: italian, engine: pdflatex, extra: 50\\%\n",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in output, got:\n%s", expected, output)
+		}
+	}
+
+	// The default preamble is a valid template.
+	output = convert(t, "Text.\n", latex.WithPreambleTemplate(true))
+	if !strings.Contains(output, "\\lstset{") || !strings.Contains(output, "{á}{{\\'a}}1") {
+		t.Errorf("expected default preamble, got:\n%s", output)
+	}
+}
+
+func TestPreambleTemplateError(t *testing.T) {
+	r := renderer.NewRenderer(renderer.WithNodeRenderers(util.Prioritized(
+		latex.NewRenderer(latex.WithPreamble([]byte("((* .Missing")), latex.WithPreambleTemplate(true)), 1000)))
+	var output bytes.Buffer
+	if err := goldmark.New(goldmark.WithRenderer(r)).Convert([]byte("Text.\n"), &output); err == nil {
+		t.Error("expected an error for an invalid preamble template")
+	}
+}
+
+func TestDynamicPreamble(t *testing.T) {
+	output := convert(t, "Just text.\n", latex.WithDynamicPreamble(true))
+	for _, unexpected := range []string{"graphicx", "minted", "framed", "ulem", "hyperref", "listings"} {
+		if strings.Contains(output, unexpected) {
+			t.Errorf("unexpected %q for plain text, got:\n%s", unexpected, output)
+		}
+	}
+	if !strings.HasPrefix(output, "% goldmark-latex: start of document\n% goldmark-latex: dynamic preamble start\n\\documentclass{article}") {
+		t.Errorf("expected generated preamble, got:\n%s", output)
+	}
+
+	const markdown = "# The `code`\n\n![image](a.png)\n\n> quote ~~struck~~\n\n```go\nfunc main() {}\n```\n"
+	output = convert(t, markdown, latex.WithDynamicPreamble(true))
+	for _, expected := range []string{"\\usepackage{graphicx}", "\\usepackage{minted}", "\\usepackage{framed}", "\\usepackage[normalem]{ulem}", "\\usepackage{hyperref}", "\\sout{struck}"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in output, got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestPreambleBuilder(t *testing.T) {
+	output := convert(t, "Text.\n",
+		latex.WithGeometry("a4", "2cm"),
+		latex.WithPreambleBuilder(func(b *latex.PreambleBuilder) {
+			b.SetClass("report", "11pt")
+			b.AddPackage("xcolor", "table")
+			b.AddPackage("tikz")
+			b.AddCommand("\\newcommand{\\hello}{Hello}")
+		}))
+	for _, expected := range []string{
+		"\\documentclass[11pt]{report}\n",
+		"\\usepackage[dvipsnames,table]{xcolor}\n",
+		"\\usepackage{tikz}\n\\newcommand{\\hello}{Hello}\n",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in output, got:\n%s", expected, output)
+		}
+	}
+	for _, pkg := range []string{"{xcolor}", "{geometry}"} {
+		if strings.Count(output, pkg) != 1 {
+			t.Errorf("expected %s to be loaded once, got:\n%s", pkg, output)
+		}
+	}
+
+	var b latex.PreambleBuilder
+	b.Parse([]byte("\\documentclass[a4paper]{article}\n\\usepackage[margin={1in,2in}]{geometry} % layout\n\\title{X}\n"))
+	b.AddPackage("geometry", "landscape")
+	if class, options := b.Class(); class != "article" || len(options) != 1 {
+		t.Errorf("unexpected class %q %q", class, options)
+	}
+	expected := "\\documentclass[a4paper]{article}\n\\usepackage[margin={1in,2in},landscape]{geometry} % layout\n\\title{X}\n"
+	if got := string(b.Bytes()); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestPreambleFS(t *testing.T) {
+	fsys := fstest.MapFS{"house.tex": {Data: []byte("\\documentclass{report}\n")}}
+	output := convert(t, "Text\n", latex.WithPreambleFS(fsys, "house.tex"))
+	if !strings.Contains(output, "custom preamble start\n\\documentclass{report}\n") {
+		t.Errorf("output does not use the preamble file:\n%s", output)
+	}
+	output = convert(t, "Text\n", latex.WithPreamble([]byte("\\documentclass{book}\n")), latex.WithPreambleFS(fsys, "house.tex"))
+	if !strings.Contains(output, "\\documentclass{report}\n") {
+		t.Errorf("output does not use the last preamble given:\n%s", output)
+	}
+	output = convert(t, "Text\n", latex.WithPreambleFS(fsys, "missing.tex"), latex.WithPreamble([]byte("\\documentclass{book}\n")))
+	if !strings.Contains(output, "\\documentclass{book}\n") {
+		t.Errorf("output does not use the last preamble given:\n%s", output)
+	}
+	md := latex.New(latex.WithPreambleFS(fsys, "missing.tex"))
+	var b bytes.Buffer
+	if err := md.Convert([]byte("Text\n"), &b); err == nil || !strings.Contains(err.Error(), "missing.tex") {
+		t.Errorf("Convert with a missing preamble file returned %v", err)
+	}
+}
@@ -0,0 +1,313 @@
+package latex
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/util"
+)
+
+// Preset is the house style of a publisher or of a kind of document: its class,
+// preamble and conventions. See WithPreset, and RegisterPreset for making a
+// preset available by name.
+type Preset struct {
+	// Name identifies the preset, such as ieee.
+	Name string
+	// Preamble is the base of the preamble, to which the packages needed by the
+	// document content are added. Should NOT end with \begin{document}.
+	Preamble []byte
+	// Build adjusts the preamble from the document metadata, e.g. the class
+	// options, escaping text with writer.
+	Build func(b *PreambleBuilder, writer Writer, meta map[string]any)
+	// Conflicts lists the packages clashing with the preset's class, which are
+	// not loaded.
+	Conflicts []string
+	// Authors writes the \author commands of the document authors, nil for the
+	// default author list, escaping text with writer.
+	Authors func(w util.BufWriter, writer Writer, authors []Author)
+	// BibliographyStyle is the default \bibliographystyle of the bibliography
+	// given in metadata.
+	BibliographyStyle string
+	// Options configure the renderer for the preset, e.g. WithHeadingCommands
+	// for the heading map, WithCodeEngine or WithPreambleTemplate for a
+	// Preamble written as a template.
+	Options []Option
+}
+
+// WithPreset applies a preset, such as IEEE. The preset's preamble replaces the
+// default and dynamic preambles, but not a custom preamble, and the preset's
+// options are applied in place, so that options following WithPreset override them.
+func WithPreset(preset Preset) Option {
+	return func(r *Renderer) {
+		r.Preset = &preset
+		for _, option := range preset.Options {
+			option(r)
+		}
+	}
+}
+
+var (
+	presetsMu sync.RWMutex
+	presets   = make(map[string]Preset)
+)
+
+func init() {
+	for _, preset := range []Preset{IEEE, ACM, LNCS, ArXiv, TufteHandout, TufteBook, Thesis, ModernCV, AwesomeCV} {
+		RegisterPreset(preset.Name, preset)
+	}
+}
+
+// RegisterPreset makes a preset available by name, e.g. to the -preset flag of
+// md2latex, so that an organization can ship its house style as a Go package
+// registering its presets in an init function. The preset's Name is set to
+// name. RegisterPreset panics if a preset is already registered under name or
+// if name is empty.
+func RegisterPreset(name string, preset Preset) {
+	presetsMu.Lock()
+	defer presetsMu.Unlock()
+	if name == "" {
+		panic("latex: RegisterPreset with empty name")
+	}
+	if _, dup := presets[name]; dup {
+		panic("latex: RegisterPreset called twice for preset " + name)
+	}
+	preset.Name = name
+	presets[name] = preset
+}
+
+// LookupPreset returns the preset registered under name.
+func LookupPreset(name string) (Preset, bool) {
+	presetsMu.RLock()
+	defer presetsMu.RUnlock()
+	preset, ok := presets[name]
+	return preset, ok
+}
+
+// PresetNames returns the sorted names of the registered presets.
+func PresetNames() []string {
+	presetsMu.RLock()
+	defer presetsMu.RUnlock()
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// presetPreamble returns the preamble of the preset, loading the packages needed by the document.
+func (r *Renderer) presetPreamble(doc ast.Node, source []byte, meta map[string]any) *PreambleBuilder {
+	b := &PreambleBuilder{}
+	b.Parse(r.Preset.Preamble)
+	if r.Preset.Build != nil {
+		r.Preset.Build(b, r.Writer, meta)
+	}
+	r.addContentPackages(b, doc, source, meta)
+	return b
+}
+
+// IEEE is the preset of IEEE conference papers, typeset with the IEEEtran class:
+// authors in IEEE author blocks, numeric citations compressed by the cite package
+// and the IEEEtran bibliography style, figures and tables at the top of columns
+// and table captions above tables.
+var IEEE = Preset{
+	Name: "ieee",
+	Preamble: []byte(`\documentclass[conference]{IEEEtran}
+
+\usepackage{cite}
+\usepackage{amsmath,amssymb,amsfonts}
+\usepackage{graphicx}
+\usepackage{textcomp}
+\usepackage{xcolor}
+`),
+	Authors:           writeIEEEAuthors,
+	BibliographyStyle: "IEEEtran",
+	Options:           []Option{WithMakeTitle(true), WithAbstractSection(true), WithFloatPlacement("!t"), WithTableCaptionsAbove(true)},
+}
+
+// writeIEEEAuthors writes the authors in IEEE author blocks: the name, then the
+// affiliation and email.
+func writeIEEEAuthors(w util.BufWriter, writer Writer, authors []Author) {
+	_, _ = w.WriteString("\\author{")
+	for i, a := range authors {
+		if i > 0 {
+			_, _ = w.WriteString("\n\\and\n")
+		}
+		_, _ = w.WriteString("\\IEEEauthorblockN{" + writer.EscapeString(a.Name) + "}")
+		var lines []string
+		if a.Affiliation != "" {
+			lines = append(lines, writer.EscapeString(a.Affiliation))
+		}
+		if a.Email != "" {
+			lines = append(lines, writer.EscapeString(a.Email))
+		}
+		if len(lines) > 0 {
+			_, _ = w.WriteString("\n\\IEEEauthorblockA{")
+			for j, line := range lines {
+				if j > 0 {
+					_, _ = w.WriteString("\\\\\n")
+				}
+				_, _ = w.WriteString(line)
+			}
+			_ = w.WriteByte('}')
+		}
+	}
+	_, _ = w.WriteString("}\n")
+}
+
+// WithFloatPlacement sets the placement of figures and tables, such as !t for the
+// top of pages.
+func WithFloatPlacement(placement string) Option {
+	return func(r *Renderer) {
+		r.FloatPlacement = placement
+	}
+}
+
+// WithTableCaptionsAbove writes the captions of tables above them, as most
+// journals require.
+func WithTableCaptionsAbove(above bool) Option {
+	return func(r *Renderer) {
+		r.TableCaptionsAbove = above
+	}
+}
+
+// ACM is the preset of ACM publications, typeset with the acmart class in the
+// format given by the "acm-format" metadata, sigconf by default, with the class
+// options listed under "acm-options", such as review or anonymous. Authors get
+// their affiliation and email, CCS concepts are taken from the "ccs" metadata,
+// a list of concepts such as "Computing methodologies~Machine learning" or of
+// mappings with concept and significance keys, and the keywords from the
+// "keywords" metadata. The abstract precedes the title, as acmart requires, and
+// the bibliography uses the ACM reference format.
+var ACM = Preset{
+	Name:              "acm",
+	Preamble:          []byte("\\documentclass{acmart}\n"),
+	Build:             buildACM,
+	Authors:           writeACMAuthors,
+	BibliographyStyle: "ACM-Reference-Format",
+	Options: []Option{
+		WithMakeTitle(true),
+		WithAbstractSection(true),
+		WithKeywordsCommand("keywords"),
+		WithFrontMatterOrder(FrontMatterAbstract, FrontMatterTitle, FrontMatterContents),
+		WithTableCaptionsAbove(true),
+	},
+}
+
+// buildACM sets the acmart format and options and the CCS concepts from metadata.
+func buildACM(b *PreambleBuilder, writer Writer, meta map[string]any) {
+	// Options which cannot be written in \documentclass are dropped.
+	format, _ := metaString(meta, "acm-format")
+	if !isClassOption(format) {
+		format = "sigconf"
+	}
+	options := []string{format}
+	for _, option := range metaList(meta["acm-options"]) {
+		if option := toString(option); isClassOption(option) {
+			options = append(options, option)
+		}
+	}
+	b.SetClass("acmart", options...)
+	for _, e := range metaList(meta["ccs"]) {
+		concept, significance := toString(e), "500"
+		if m, ok := metaMap(e); ok {
+			concept, _ = metaString(m, "concept")
+			if s, ok := metaString(m, "significance"); ok && isNumber(s) {
+				significance = s
+			}
+		}
+		// The tilde separates the concept from its subject.
+		parts := strings.Split(concept, "~")
+		for i, part := range parts {
+			parts[i] = writer.EscapeString(strings.TrimSpace(part))
+		}
+		b.AddCommand("\\ccsdesc[" + significance + "]{" + strings.Join(parts, "~") + "}")
+	}
+}
+
+// writeACMAuthors writes each author followed by their affiliation, email and ORCID.
+func writeACMAuthors(w util.BufWriter, writer Writer, authors []Author) {
+	for _, a := range authors {
+		_, _ = w.WriteString("\\author{" + writer.EscapeString(a.Name) + "}\n")
+		if a.Affiliation != "" {
+			_, _ = w.WriteString("\\affiliation{\\institution{" + writer.EscapeString(a.Affiliation) + "}}\n")
+		}
+		if a.Email != "" {
+			_, _ = w.WriteString("\\email{" + writer.EscapeString(a.Email) + "}\n")
+		}
+		if a.ORCID != "" {
+			_, _ = w.WriteString("\\orcid{" + writer.EscapeString(a.ORCID) + "}\n")
+		}
+	}
+}
+
+// LNCS is the preset of Springer Lecture Notes in Computer Science papers,
+// typeset with the llncs class: headings are limited to four levels, the last
+// one being run-in paragraph headings, authors are numbered after their
+// institute, listed with \institute with the email addresses, and the
+// bibliography uses the splncs04 style.
+var LNCS = Preset{
+	Name:              "lncs",
+	Preamble:          []byte("\\documentclass[runningheads]{llncs}\n\n\\usepackage{graphicx}\n"),
+	Authors:           writeLNCSAuthors,
+	BibliographyStyle: "splncs04",
+	Options: []Option{
+		WithMakeTitle(true),
+		WithAbstractSection(true),
+		WithMaxHeadingLevel(4),
+		WithKeywordsCommand("keywords"),
+		WithTableCaptionsAbove(true),
+	},
+}
+
+// writeLNCSAuthors writes the authors with the number of their institute,
+// \inst, and their ORCID, then the institutes with the email addresses of
+// their authors.
+func writeLNCSAuthors(w util.BufWriter, writer Writer, authors []Author) {
+	var institutes []string
+	emails := make(map[string][]string)
+	index := make(map[string]int)
+	for _, a := range authors {
+		if _, ok := index[a.Affiliation]; !ok && (a.Affiliation != "" || a.Email != "") {
+			institutes = append(institutes, a.Affiliation)
+			index[a.Affiliation] = len(institutes)
+		}
+		if a.Email != "" {
+			emails[a.Affiliation] = append(emails[a.Affiliation], "\\email{"+writer.EscapeString(a.Email)+"}")
+		}
+	}
+	_, _ = w.WriteString("\\author{")
+	for i, a := range authors {
+		if i > 0 {
+			_, _ = w.WriteString(" \\and ")
+		}
+		_, _ = w.WriteString(writer.EscapeString(a.Name))
+		if n, ok := index[a.Affiliation]; ok && len(institutes) > 1 {
+			_, _ = w.WriteString("\\inst{" + strconv.Itoa(n) + "}")
+		}
+		if a.ORCID != "" {
+			_, _ = w.WriteString("\\orcidID{" + writer.EscapeString(a.ORCID) + "}")
+		}
+	}
+	_, _ = w.WriteString("}\n")
+	if len(institutes) == 0 {
+		return
+	}
+	_, _ = w.WriteString("\\institute{")
+	for i, institute := range institutes {
+		if i > 0 {
+			_, _ = w.WriteString(" \\and\n")
+		}
+		_, _ = w.WriteString(writer.EscapeString(institute))
+		if e := emails[institute]; len(e) > 0 {
+			if institute != "" {
+				_, _ = w.WriteString("\\\\\n")
+			}
+			_, _ = w.WriteString(strings.Join(e, ", "))
+		}
+	}
+	_, _ = w.WriteString("}\n")
+}
@@ -0,0 +1,302 @@
+package latex_test
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+
+	latex "github.com/dihedron/goldmark-latex"
+)
+
+func TestIEEEPreset(t *testing.T) {
+	meta := map[string]any{
+		"title": "Paper",
+		"author": []any{
+			map[string]any{"name": "Jane Doe", "affiliation": "ACME", "email": "jane@acme.org"},
+			"John Roe",
+		},
+		"bibliography": "refs.bib",
+	}
+	output := convertWithMeta(t, "![Plot](plot.png){width=1}\n\n<table><caption>Results</caption><tr><td>1</td></tr></table>\n", meta, latex.WithPreset(latex.IEEE))
+	for _, want := range []string{
+		"\\documentclass[conference]{IEEEtran}\n",
+		"\\usepackage{cite}\n",
+		"\\author{\\IEEEauthorblockN{Jane Doe}\n\\IEEEauthorblockA{ACME\\\\\njane@acme.org}\n\\and\n\\IEEEauthorblockN{John Roe}}\n",
+		"\\begin{figure}[!t]",
+		"\\begin{table}[!t]\n\\centering\n\\caption{Results}\n\\begin{tabular}",
+		"\\bibliographystyle{IEEEtran}\n\\bibliography{refs}\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+	for _, unwanted := range []string{"helvet", "geometry", "authblk"} {
+		if strings.Contains(output, unwanted) {
+			t.Errorf("output contains %q:\n%s", unwanted, output)
+		}
+	}
+}
+
+func TestACMPreset(t *testing.T) {
+	meta := map[string]any{
+		"title":       "Paper",
+		"author":      []any{map[string]any{"name": "Jane Doe", "affiliation": "ACME", "email": "jane@acme.org"}},
+		"acm-format":  "acmsmall",
+		"acm-options": []any{"review"},
+		"ccs":         []any{"Computing methodologies~Machine learning", map[string]any{"concept": "Software~Compilers", "significance": 300}},
+		"keywords":    []any{"markdown", "LaTeX"},
+	}
+	output := convertWithMeta(t, "# Abstract\n\nSummary.\n\n# Introduction\n", meta, latex.WithPreset(latex.ACM))
+	for _, want := range []string{
+		"\\documentclass[acmsmall,review]{acmart}\n",
+		"\\ccsdesc[500]{Computing methodologies~Machine learning}\n",
+		"\\ccsdesc[300]{Software~Compilers}\n",
+		"\\author{Jane Doe}\n\\affiliation{\\institution{ACME}}\n\\email{jane@acme.org}\n",
+		"\\keywords{markdown, LaTeX}\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+	if a, k, m := strings.Index(output, "\\begin{abstract}"), strings.Index(output, "\\keywords"), strings.Index(output, "\\maketitle"); a < 0 || a > k || k > m {
+		t.Errorf("abstract and keywords do not precede the title:\n%s", output)
+	}
+
+	meta = map[string]any{
+		"acm-format":  "acmsmall]{x}\\input{a}%",
+		"acm-options": []any{"review", "x]{y}"},
+		"ccs":         []any{map[string]any{"concept": "Software", "significance": "1]{x}\\input{a}"}},
+	}
+	output = convertWithMeta(t, "Text.\n", meta, latex.WithPreset(latex.ACM))
+	if !strings.Contains(output, "\\documentclass[sigconf,review]{acmart}\n") || !strings.Contains(output, "\\ccsdesc[500]{Software}\n") || strings.Contains(output, "\\input") {
+		t.Errorf("expected unsafe ACM options dropped, got:\n%s", output)
+	}
+}
+
+func TestLNCSPreset(t *testing.T) {
+	meta := map[string]any{
+		"title": "Paper",
+		"author": []any{
+			map[string]any{"name": "Jane Doe", "affiliation": "ACME", "email": "jane@acme.org", "orcid": "0000-0001"},
+			map[string]any{"name": "John Roe", "affiliation": "University", "email": "john@uni.edu"},
+			map[string]any{"name": "Ann Poe", "affiliation": "ACME"},
+		},
+		"bibliography": []any{"refs.bib", "more"},
+	}
+	output := convertWithMeta(t, "# Introduction\n\n##### Details\n", meta, latex.WithPreset(latex.LNCS))
+	for _, want := range []string{
+		"\\documentclass[runningheads]{llncs}\n",
+		"\\author{Jane Doe\\inst{1}\\orcidID{0000-0001} \\and John Roe\\inst{2} \\and Ann Poe\\inst{1}}\n",
+		"\\institute{ACME\\\\\n\\email{jane@acme.org} \\and\nUniversity\\\\\n\\email{john@uni.edu}}\n",
+		"\\paragraph{Details}",
+		"\\bibliographystyle{splncs04}\n\\bibliography{refs,more}\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestBibliographyNames(t *testing.T) {
+	meta := map[string]any{
+		"bibliography": []any{"refs.bib", "x}\\input{/etc/passwd}", "a,b"},
+		"biblio-style": "plain}\\input{/etc/passwd}",
+	}
+	output := convertWithMeta(t, "Text.\n", meta)
+	if !strings.Contains(output, "\\bibliographystyle{plain}\n\\bibliography{refs}\n") || strings.Contains(output, "\\input") {
+		t.Errorf("expected unsafe bibliography names skipped, got:\n%s", output)
+	}
+}
+
+type memoryFile struct {
+	bytes.Buffer
+}
+
+func (f *memoryFile) Close() error { return nil }
+
+func TestArXivPreset(t *testing.T) {
+	input := "# Code\n\n```python\nprint(1)\n```\n\n| a |\n|---|\n"
+	output := convertWithMeta(t, input, map[string]any{"title": "Paper"}, latex.WithPreset(latex.ArXiv))
+	for _, want := range []string{
+		"\\pdfoutput=1\n\\documentclass{article}\n",
+		"\\usepackage{listings}\n",
+		"\\pdfinfoomitdate=1\n",
+		"\\date{}\n",
+		"\\begin{lstlisting}[language=python]\nprint(1)\n\\end{lstlisting}\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "minted") {
+		t.Errorf("output uses minted:\n%s", output)
+	}
+	if again := convertWithMeta(t, input, map[string]any{"title": "Paper"}, latex.WithPreset(latex.ArXiv)); again != output {
+		t.Errorf("output is not deterministic")
+	}
+	files := make(map[string]*memoryFile)
+	err := latex.WriteArXiv([]byte(output), func(name string) (io.WriteCloser, error) {
+		files[name] = &memoryFile{}
+		return files[name], nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files["main.tex"].String() != output || !strings.Contains(files["00README.json"].String(), `"filename": "main.tex", "usage": "toplevel"`) {
+		t.Errorf("unexpected files: %v", files)
+	}
+}
+
+func TestTuftePreset(t *testing.T) {
+	input := "# Intro\n\nText[^1] and more[^2].\n\n![Plot](plot.png?class=margin)\n\n![Wide](wide.png?class=fullwidth&width=1)\n\n[^1]: A *side* note.\n[^2]: First.\n\n    Second.\n\n### Deep\n"
+	output := convert(t, input, latex.WithMetadata(map[string]any{"title": "Handout", "author": "Jane Doe"}), latex.WithPreset(latex.TufteHandout), latex.WithDynamicPreamble(true))
+	for _, want := range []string{
+		"\\documentclass{tufte-handout}\n",
+		"\\author{Jane Doe}\n",
+		"Text\\sidenote{A \\textit{side} note.} and more\\sidenote{",
+		"\\begin{marginfigure}\n\t\\centering\n\t\\includegraphics[width=\\linewidth]{plot.png}",
+		"\\begin{figure*}[h]\n\t\\centering\n\t\\includegraphics[width=1\\textwidth]{wide.png}",
+		"\\subsection{Deep}",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+	for _, unwanted := range []string{"{geometry}", "{sidenotes}", "{helvet}", "\\begin{enumerate}"} {
+		if strings.Contains(output, unwanted) {
+			t.Errorf("output contains %q:\n%s", unwanted, output)
+		}
+	}
+	output = convert(t, "Text[^1].\n\n[^1]: Note.\n")
+	if !strings.Contains(output, "Text\\footnote{Note.}.") {
+		t.Errorf("footnote not typeset:\n%s", output)
+	}
+}
+
+func TestThesisPreset(t *testing.T) {
+	meta := map[string]any{
+		"title":            "Thesis",
+		"abstract":         "Summary.",
+		"declaration":      "I wrote it.",
+		"acknowledgements": "Thanks.",
+		"bibliography":     "refs.bib",
+	}
+	input := "# Introduction\n\n## Motivation\n\n<!-- backmatter -->\n\n# Glossary\n"
+	output := convertWithMeta(t, input, meta, latex.WithPreset(latex.Thesis))
+	for _, want := range []string{
+		"\\documentclass[11pt,a4paper,twoside,openright]{book}\n",
+		"\\begin{document}\n\n\\frontmatter\n",
+		"\\chapter*{Declaration}\n\\addcontentsline{toc}{chapter}{Declaration}\nI wrote it.\n",
+		"\\chapter*{Acknowledgements}\n",
+		"\\chapter*{Abstract}\n\\addcontentsline{toc}{chapter}{Abstract}\nSummary.\n",
+		"\\tableofcontents\n",
+		"\n\\mainmatter\n",
+		"\\chapter{Introduction}",
+		"\\section{Motivation}",
+		"\n\\backmatter\n",
+		"\\bibliography{refs}\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "abstract}") || strings.Count(output, "\\backmatter") != 1 {
+		t.Errorf("unexpected abstract environment or matters:\n%s", output)
+	}
+	if strings.Index(output, "\\tableofcontents") > strings.Index(output, "\\mainmatter") {
+		t.Errorf("contents not in front matter:\n%s", output)
+	}
+}
+
+func TestCVPresets(t *testing.T) {
+	meta := map[string]any{"name": "Jane Doe", "email": "jane@acme.org", "position": "Engineer"}
+	input := `# Experience
+
+- **Software Engineer**, ACME, Rome {date="2019--2023"}
+
+  - Built things.
+
+# Skills
+
+- Languages: Go, Python
+- Tools: Git
+
+# Interests
+
+- Chess
+`
+	for _, test := range []struct {
+		preset latex.Preset
+		want   []string
+	}{
+		{latex.ModernCV, []string{
+			"\\documentclass[11pt,a4paper,sans]{moderncv}\n",
+			"\\moderncvstyle{classic}\n",
+			"\\name{Jane}{Doe}\n",
+			"\\title{Engineer}\n",
+			"\\email{jane@acme.org}\n",
+			"\\makecvtitle\n",
+			"\\section{Experience}",
+			"\\cventry{2019--2023}{Software Engineer}{ACME}{Rome}{}{\n\\begin{itemize}\n\\item~ Built things.\n\\end{itemize}\n}\n",
+			"\\cvitem{Languages}{Go, Python}\n\\cvitem{Tools}{Git}\n",
+			"\\begin{itemize}\n\\item~ Chess\n\\end{itemize}\n",
+		}},
+		{latex.AwesomeCV, []string{
+			"\\documentclass[11pt,a4paper]{awesome-cv}\n",
+			"\\position{Engineer}\n",
+			"\\makecvheader\n",
+			"\\cvsection{Experience}",
+			"\\begin{cventries}\n\\cventry{Software Engineer}{ACME}{Rome}{2019--2023}{\n\\begin{cvitems}\n\\item {Built things.}\n\\end{cvitems}\n}\n\\end{cventries}\n",
+			"\\begin{cvskills}\n\\cvskill{Languages}{Go, Python}\n\\cvskill{Tools}{Git}\n\\end{cvskills}\n",
+		}},
+	} {
+		output := convertWithMeta(t, input, meta, latex.WithPreset(test.preset))
+		for _, want := range test.want {
+			if !strings.Contains(output, want) {
+				t.Errorf("%s: output does not contain %q:\n%s", test.preset.Name, want, output)
+			}
+		}
+	}
+}
+
+func TestRegisterPreset(t *testing.T) {
+	house := latex.Preset{
+		Preamble: []byte("\\documentclass{scrartcl}\n\n\\newcommand{\\house}{((* .Title *))}\n"),
+		Options: []latex.Option{
+			latex.WithPreambleTemplate(true),
+			latex.WithHeadingCommands("addsec", "subsection"),
+			latex.WithCodeEngine(latex.Listings),
+		},
+	}
+	if _, ok := latex.LookupPreset("house"); !ok { // Registered once with -count.
+		latex.RegisterPreset("house", house)
+	}
+	preset, ok := latex.LookupPreset("house")
+	if !ok || preset.Name != "house" {
+		t.Fatalf("LookupPreset(house) = %+v, %v", preset, ok)
+	}
+	names := latex.PresetNames()
+	if !sort.StringsAreSorted(names) || !strings.Contains(strings.Join(names, " "), "ieee") {
+		t.Errorf("PresetNames() = %v", names)
+	}
+	output := convertWithMeta(t, "# Top\n\n## Sub\n\n### Deep\n", map[string]any{"title": "Report"}, latex.WithPreset(preset))
+	for _, want := range []string{
+		"% goldmark-latex: house preamble start\n\\documentclass{scrartcl}\n",
+		"\\newcommand{\\house}{Report}\n",
+		"\\addsec{Top}",
+		"\\subsection{Sub}",
+		"\\subsubsection{Deep}",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a preset twice does not panic")
+		}
+	}()
+	latex.RegisterPreset("house", preset)
+}
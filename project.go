@@ -0,0 +1,151 @@
+package latex
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// WriteProject writes a compile-ready LaTeX project generated from a markdown
+// document with the syntaxes of Extension: name.tex, a latexmkrc and a
+// Makefile running latexmk, and the images, of markdown images and <img>
+// elements, and bibliography databases the document references, copied from
+// fsys. Images are copied as they are: those in formats LaTeX cannot include,
+// such as SVG or GIF, must be converted beforehand. The latexmkrc enables
+// shell escape, which minted requires, only for documents with code
+// typeset with minted. The files are created by create, see CreateFiles and
+// ZipFiles.
+func WriteProject(name string, source []byte, fsys fs.FS, create func(name string) (io.WriteCloser, error), options ...Option) error {
+	md := New(options...)
+	doc := md.Parser().Parse(text.NewReader(source))
+	r := NewRenderer(options...)
+	r.unshared = true
+	var tex bytes.Buffer
+	rd := renderer.NewRenderer(renderer.WithNodeRenderers(util.Prioritized(r, 1000)))
+	if err := rd.Render(&tex, source, doc); err != nil {
+		return err
+	}
+	files := []struct {
+		name    string
+		content []byte
+	}{
+		{name + ".tex", tex.Bytes()},
+		{"latexmkrc", latexmkrc(r.Engine, containsString(r.usedPackages(doc, source), Minted.String()))},
+		{"Makefile", []byte(fmt.Sprintf("%s.pdf: %[1]s.tex\n\tlatexmk %[1]s.tex\n\nclean:\n\tlatexmk -C %[1]s.tex\n\n.PHONY: clean\n", name))},
+	}
+	for _, f := range files {
+		if err := writeFile(create, f.name, bytes.NewReader(f.content)); err != nil {
+			return err
+		}
+	}
+	var assets []string
+	bibliography, _ := metaBibliography(r.metadata(doc.(*ast.Document)))
+	for _, bib := range bibliography {
+		assets = append(assets, bib+".bib")
+	}
+	addImage := func(destination string) {
+		destination, _, _ = strings.Cut(destination, "?")
+		if destination != "" && !strings.Contains(destination, "://") {
+			if found, ok := findImage(fsys, destination); ok {
+				destination = found
+			}
+			assets = append(assets, destination)
+		}
+	}
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		var html []byte
+		switch n := n.(type) {
+		case *ast.Image:
+			addImage(string(n.Destination))
+		case *ast.RawHTML:
+			for i := 0; i < n.Segments.Len(); i++ {
+				segment := n.Segments.At(i)
+				html = append(html, segment.Value(source)...)
+			}
+		case *ast.HTMLBlock:
+			html = blockContent(n, source)
+		}
+		for _, t := range htmlTokens(string(html)) {
+			if t.isTag && t.tag.name == "img" && !t.tag.end {
+				addImage(t.tag.attributes["src"])
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	copied := make(map[string]bool)
+	for _, asset := range assets {
+		asset = path.Clean(asset)
+		if copied[asset] {
+			continue
+		}
+		copied[asset] = true
+		if !fs.ValidPath(asset) {
+			return fmt.Errorf("latex: project: %s is out of the project", asset)
+		}
+		f, err := fsys.Open(asset)
+		if err != nil {
+			return fmt.Errorf("latex: project: %w", err)
+		}
+		err = writeFile(create, asset, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// latexmkrc returns the latexmk configuration compiling with engine.
+func latexmkrc(engine Engine, shellEscape bool) []byte {
+	mode := map[Engine]int{PDFLaTeX: 1, LuaLaTeX: 4, XeLaTeX: 5}[engine]
+	rc := fmt.Sprintf("# Compile with %s.\n$pdf_mode = %d;\n", engine, mode)
+	if shellEscape {
+		rc += "# minted runs Pygments.\nset_tex_cmds('-shell-escape %O %S');\n"
+	}
+	return []byte(rc)
+}
+
+// writeFile creates a file and copies r into it.
+func writeFile(create func(name string) (io.WriteCloser, error), name string, r io.Reader) error {
+	w, err := create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// ZipFiles returns a function creating the files in a zip archive, to be
+// used with WriteProject or Split. The archive must be closed afterwards.
+func ZipFiles(zw *zip.Writer) func(name string) (io.WriteCloser, error) {
+	return func(name string) (io.WriteCloser, error) {
+		w, err := zw.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		return nopCloser{w}, nil
+	}
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,153 @@
+package latex
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// KindAttribution is the NodeKind of Attribution nodes.
+var KindAttribution = ast.NewNodeKind("Attribution")
+
+// Attribution is the source of a quote, written on the last line of a
+// blockquote after an em dash: — Hamlet, *Act III*.
+type Attribution struct {
+	ast.BaseBlock
+}
+
+// Kind implements ast.Node.Kind.
+func (n *Attribution) Kind() ast.NodeKind {
+	return KindAttribution
+}
+
+// Dump implements ast.Node.Dump.
+func (n *Attribution) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// NewAttribution returns a new Attribution node.
+func NewAttribution() *Attribution {
+	return &Attribution{}
+}
+
+// attributionDashes introduce attributions: em dashes, horizontal bars and
+// the --- and -- ligatures.
+var attributionDashes = [][]byte{[]byte("—"), []byte("―"), []byte("---"), []byte("--")}
+
+// attributionDash returns the length of the dash and spaces introducing an attribution.
+func attributionDash(line []byte) int {
+	for _, dash := range attributionDashes {
+		if rest := bytes.TrimPrefix(line, dash); len(rest) < len(line) {
+			name := util.TrimLeftSpace(rest)
+			if len(name) == 0 || len(name) == len(rest) && dash[0] == '-' {
+				return 0 // No name or a longer dash.
+			}
+			return len(line) - len(name)
+		}
+	}
+	return 0
+}
+
+// firstTextSegment returns the segment of the first text of n.
+func firstTextSegment(n ast.Node) (text.Segment, bool) {
+	if t, ok := n.(*ast.Text); ok {
+		return t.Segment, true
+	}
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if s, ok := firstTextSegment(c); ok {
+			return s, true
+		}
+	}
+	return text.Segment{}, false
+}
+
+// splitAttribution moves the attribution ending a blockquote, if any, to an
+// Attribution node appended to the blockquote.
+func splitAttribution(bq ast.Node, source []byte) {
+	p, ok := bq.LastChild().(*ast.Paragraph)
+	if !ok || p.Lines().Len() == 0 {
+		return
+	}
+	lines := p.Lines()
+	line := lines.At(lines.Len() - 1)
+	value := util.TrimLeftSpace(line.Value(source))
+	dash := attributionDash(value)
+	if dash == 0 {
+		return
+	}
+	start := line.Start + len(line.Value(source)) - len(value)
+	var first ast.Node
+	for c := p.FirstChild(); c != nil; c = c.NextSibling() {
+		if s, ok := firstTextSegment(c); ok && s.Start >= start {
+			first = c
+			break
+		}
+	}
+	t, ok := first.(*ast.Text)
+	if !ok || t.Segment.Start != start || t.Segment.Len() < dash {
+		return // The dash is not plain text.
+	}
+	attribution := NewAttribution()
+	if prev, ok := first.PreviousSibling().(*ast.Text); ok {
+		prev.SetSoftLineBreak(false)
+	}
+	for c := first; c != nil; {
+		next := c.NextSibling()
+		attribution.AppendChild(attribution, c)
+		c = next
+	}
+	t.Segment = t.Segment.WithStart(t.Segment.Start + dash)
+	if p.ChildCount() == 0 {
+		bq.RemoveChild(bq, p)
+	} else {
+		lines.SetSliced(0, lines.Len()-1)
+	}
+	bq.AppendChild(bq, attribution)
+}
+
+type attributionTransformer struct{}
+
+// NewAttributionTransformer returns a transformer moving the attributions
+// ending blockquotes, other than callouts, to Attribution nodes.
+func NewAttributionTransformer() parser.ASTTransformer {
+	return &attributionTransformer{}
+}
+
+func (t *attributionTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	var quotes []ast.Node
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if _, callout := blockquoteCallout(n, source); entering && n.Kind() == ast.KindBlockquote && !callout {
+			quotes = append(quotes, n)
+		}
+		return ast.WalkContinue, nil
+	})
+	for _, n := range quotes {
+		splitAttribution(n, source)
+	}
+}
+
+type attributionsExtension struct{}
+
+// Attributions is a goldmark extension typesetting the attribution ending a
+// blockquote, written after a dash on its last line, right aligned below it.
+var Attributions goldmark.Extender = &attributionsExtension{}
+
+func (e *attributionsExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithASTTransformers(util.Prioritized(NewAttributionTransformer(), 500)))
+}
+
+// renderAttribution writes a quote's attribution right aligned below it, after
+// the end of the quote environment.
+func (r *Renderer) renderAttribution(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString("\\end{quote}\n\\begin{flushright}\n---~")
+	} else {
+		_, _ = w.WriteString("\n\\end{flushright}\n")
+	}
+	return ast.WalkContinue, nil
+}
@@ -0,0 +1,192 @@
+package latex
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// defaultRawLaTeXPrefix introduces raw LaTeX in HTML comments.
+const defaultRawLaTeXPrefix = "latex:"
+
+// WithRawLaTeXPrefix sets the prefix of the HTML comments holding raw LaTeX,
+// "latex:" by default, as in <!-- latex: \newpage -->. Raw LaTeX is only
+// written when trusted, see UnsafeOptions.
+func WithRawLaTeXPrefix(prefix string) Option {
+	return func(r *Renderer) {
+		r.RawLaTeXPrefix = prefix
+	}
+}
+
+// rawLaTeXComment returns the raw LaTeX of an HTML comment directive starting
+// with the raw LaTeX prefix.
+func (r *Renderer) rawLaTeXComment(directive string) (string, bool) {
+	prefix := r.RawLaTeXPrefix
+	if prefix == "" {
+		prefix = defaultRawLaTeXPrefix
+	}
+	if !strings.HasPrefix(directive, prefix) {
+		return "", false
+	}
+	return strings.TrimSpace(directive[len(prefix):]), true
+}
+
+// inlineComment returns the trimmed content of an inline HTML comment.
+func inlineComment(raw []byte) (string, bool) {
+	raw = bytes.TrimSpace(raw)
+	if !bytes.HasPrefix(raw, []byte("<!--")) || !bytes.HasSuffix(raw, []byte("-->")) || len(raw) < 7 {
+		return "", false
+	}
+	return string(bytes.TrimSpace(raw[4 : len(raw)-3])), true
+}
+
+// writeRawLaTeX writes raw LaTeX verbatim, or a comment when raw LaTeX is not
+// trusted and has commands which are not allowed. Blocks are written
+// on their own lines.
+func (r *Renderer) writeRawLaTeX(w util.BufWriter, latex string, block bool) {
+	if !r.UnsafeRawLaTeX && !r.isAllowedLaTeX(latex) {
+		r.warnAt(nil, nil, -1, DiagnosticUnsafeContent, "raw LaTeX skipped: %s", strings.Join(strings.Fields(latex), " "))
+		_ = w.WriteByte('\n')
+		r.warnComment(w, "raw LaTeX skipped, unsafe rendering disabled")
+		return
+	}
+	if block {
+		_ = w.WriteByte('\n')
+		r.Writer.RawWrite(w, []byte(latex))
+		if latex != "" && !strings.HasSuffix(latex, "\n") {
+			_ = w.WriteByte('\n')
+		}
+		return
+	}
+	r.Writer.RawWrite(w, []byte(latex))
+}
+
+// KindRawInline is the NodeKind of RawInline nodes.
+var KindRawInline = ast.NewNodeKind("RawInline")
+
+// RawInline is raw content for a given output format, written with Pandoc's raw
+// attribute syntax: `\newline`{=latex}.
+type RawInline struct {
+	ast.BaseInline
+	Format  string
+	Segment text.Segment
+}
+
+// Kind implements ast.Node.Kind.
+func (n *RawInline) Kind() ast.NodeKind {
+	return KindRawInline
+}
+
+// Dump implements ast.Node.Dump.
+func (n *RawInline) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Format": n.Format, "Content": string(n.Segment.Value(source))}, nil)
+}
+
+// NewRawInline returns a new RawInline node.
+func NewRawInline(format string, segment text.Segment) *RawInline {
+	return &RawInline{Format: format, Segment: segment}
+}
+
+// rawAttribute matches a Pandoc raw attribute such as {=latex}.
+var rawAttribute = regexp.MustCompile(`^\{=([A-Za-z0-9_-]+)\}`)
+
+// rawFormat returns the format of a raw attribute, such as the info string of a
+// ```{=latex} code block.
+func rawFormat(info []byte) (string, bool) {
+	m := rawAttribute.FindSubmatch(bytes.TrimSpace(info))
+	if m == nil || len(m[0]) != len(bytes.TrimSpace(info)) {
+		return "", false
+	}
+	return strings.ToLower(string(m[1])), true
+}
+
+// isLaTeXFormat reports whether raw content of the format is LaTeX.
+func isLaTeXFormat(format string) bool {
+	return format == "latex" || format == "tex"
+}
+
+type rawInlineParser struct{}
+
+// NewRawInlineParser returns a parser of code spans followed by a raw attribute,
+// such as `\newline`{=latex}. It must run before the code span parser.
+func NewRawInlineParser() parser.InlineParser {
+	return &rawInlineParser{}
+}
+
+func (p *rawInlineParser) Trigger() []byte {
+	return []byte{'`'}
+}
+
+func (p *rawInlineParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, segment := block.PeekLine()
+	fence := 0
+	for fence < len(line) && line[fence] == '`' {
+		fence++
+	}
+	for i := fence; i < len(line); {
+		if line[i] != '`' {
+			i++
+			continue
+		}
+		end := i
+		for end < len(line) && line[end] == '`' {
+			end++
+		}
+		if end-i != fence {
+			i = end
+			continue
+		}
+		m := rawAttribute.FindSubmatch(line[end:])
+		if m == nil {
+			return nil
+		}
+		start, stop := fence, i
+		// Like code spans, content is stripped of one space on both sides.
+		if stop-start > 2 && line[start] == ' ' && line[stop-1] == ' ' {
+			start, stop = start+1, stop-1
+		}
+		block.Advance(end + len(m[0]))
+		return NewRawInline(strings.ToLower(string(m[1])), text.NewSegment(segment.Start+start, segment.Start+stop))
+	}
+	return nil
+}
+
+// renderRawInline writes raw LaTeX and drops raw content in other formats.
+func (r *Renderer) renderRawInline(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*RawInline)
+	if entering && isLaTeXFormat(n.Format) {
+		r.writeRawLaTeX(w, string(n.Segment.Value(source)), false)
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+// writeRawBlock writes the lines of a raw block in LaTeX format.
+func (r *Renderer) writeRawBlock(w util.BufWriter, source []byte, format string, lines *text.Segments) {
+	if !isLaTeXFormat(format) {
+		return
+	}
+	var b []byte
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		b = append(b, line.Value(source)...)
+	}
+	r.writeRawLaTeX(w, string(b), true)
+}
+
+type rawAttributesExtension struct{}
+
+// RawAttributes is a goldmark extension parsing Pandoc's inline raw attribute
+// syntax, `\command{}`{=latex}. Raw blocks, ```{=latex} code blocks and
+// ::: {=latex} fenced divs, need no extension. Raw LaTeX is only written
+// when trusted, see UnsafeOptions; content in other formats is dropped.
+var RawAttributes goldmark.Extender = &rawAttributesExtension{}
+
+func (e *rawAttributesExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithInlineParsers(util.Prioritized(NewRawInlineParser(), 50)))
+}
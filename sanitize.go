@@ -0,0 +1,77 @@
+package latex
+
+import (
+	"regexp"
+	"strings"
+)
+
+// labelID returns an ID usable in \label and \ref, keeping only letters,
+// digits and :_.- so that IDs from the document cannot close the command.
+func labelID(id string) string {
+	return strings.Map(func(c rune) rune {
+		if c < 128 && (isLetter(byte(c)) || c >= '0' && c <= '9' || strings.ContainsRune(":_.-", c)) {
+			return c
+		}
+		return -1
+	}, id)
+}
+
+// isOptionName reports whether name can be written as the key of a key=value
+// option: it holds only letters and dashes.
+func isOptionName(name string) bool {
+	for i := 0; i < len(name); i++ {
+		if !isLetter(name[i]) && name[i] != '-' {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// classOption matches a document class option, such as review or natbib=false.
+var classOption = regexp.MustCompile(`^[A-Za-z0-9.-]+(=[A-Za-z0-9.-]+)?$`)
+
+// isClassOption reports whether s can be written as a document class option.
+func isClassOption(s string) bool {
+	return classOption.MatchString(s)
+}
+
+// isLanguageName reports whether name can be a babel or polyglossia language
+// name, such as german: it holds only lowercase letters.
+func isLanguageName(name string) bool {
+	for i := 0; i < len(name); i++ {
+		if name[i] < 'a' || name[i] > 'z' {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// bcp47Tag matches a BCP 47 language tag, such as en or pt-BR.
+var bcp47Tag = regexp.MustCompile(`^[A-Za-z]{2,8}(-[A-Za-z0-9]{1,8})*$`)
+
+// isLanguageTag reports whether s is made like a BCP 47 language tag.
+func isLanguageTag(s string) bool {
+	return bcp47Tag.MatchString(s)
+}
+
+var decimalNumber = regexp.MustCompile(`^[0-9]*\.?[0-9]+$`)
+
+// texLength matches a TeX length: a number with a unit, or a factor of the
+// text, line or column width.
+var texLength = regexp.MustCompile(`^[0-9]*\.?[0-9]+\s*(pt|px|bp|cm|mm|in|em|ex|pc|\\(textwidth|linewidth|columnwidth))$`)
+
+// isNumber reports whether s is a non-negative decimal number, such as 0.5.
+func isNumber(s string) bool {
+	return decimalNumber.MatchString(s)
+}
+
+// isLength reports whether s is a TeX length, such as 5cm or 0.5\linewidth.
+func isLength(s string) bool {
+	return texLength.MatchString(s)
+}
+
+// isSafePath reports whether a file path can be written in a command argument:
+// it holds no braces, backslash, comment character nor line break.
+func isSafePath(path string) bool {
+	return !strings.ContainsAny(path, "{}\\%\n\r")
+}
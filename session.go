@@ -0,0 +1,67 @@
+package latex
+
+import (
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// funcTable records node rendering functions by kind.
+type funcTable struct {
+	funcs []renderer.NodeRendererFunc
+}
+
+func (t *funcTable) Register(kind ast.NodeKind, fn renderer.NodeRendererFunc) {
+	for int(kind) >= len(t.funcs) {
+		t.funcs = append(t.funcs, nil)
+	}
+	t.funcs[kind] = fn
+}
+
+// newSession returns a renderer with the configuration of r and a state of
+// its own, rendering a document.
+func (r *Renderer) newSession() *Renderer {
+	r.mu.Lock()
+	// The preamble file is read once, errors being reported by the sessions.
+	_ = r.loadPreamble()
+	s := &Renderer{Config: r.Config, unshared: true}
+	r.mu.Unlock()
+	s.ctx = r.ctx
+	table := &funcTable{}
+	s.registerFuncs(table)
+	s.funcs = table.funcs
+	return s
+}
+
+// dispatch returns the function rendering the nodes of a kind with the session
+// of the document being rendered to w, started with the document. Nodes
+// rendered out of documents are rendered by fn, bound to r.
+func (r *Renderer) dispatch(kind ast.NodeKind, fn renderer.NodeRendererFunc) renderer.NodeRendererFunc {
+	return func(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+		v, ok := r.sessions.Load(w)
+		if !ok {
+			if kind != ast.KindDocument || !entering {
+				return fn(w, source, n, entering)
+			}
+			v = r.newSession()
+			r.sessions.Store(w, v)
+		}
+		s := v.(*Renderer)
+		status, err := s.funcs[kind](w, source, n, entering)
+		if (kind == ast.KindDocument && !entering) || status == ast.WalkStop || err != nil {
+			r.sessions.Delete(w)
+			r.setDiagnostics(s.diagnostics)
+			if _, ok := r.results.Load(w); ok {
+				r.results.Store(w, s.diagnostics)
+			}
+		}
+		return status, err
+	}
+}
+
+// setDiagnostics sets the diagnostics of the last rendering of r.
+func (r *Renderer) setDiagnostics(diagnostics []Diagnostic) {
+	r.mu.Lock()
+	r.diagnostics = diagnostics
+	r.mu.Unlock()
+}
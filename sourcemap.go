@@ -0,0 +1,82 @@
+package latex
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// lineMarker starts the lines marking the markdown line of the following top
+// level block, followed by the line number.
+const lineMarker = "% goldmark-latex line: "
+
+// WithSourceLines marks each top level block in the output with a comment
+// holding its markdown line number, written regardless of WithComments, so
+// that LaTeX errors can be traced back to the markdown source. See SourceMap.
+func WithSourceLines(lines bool) Option {
+	return func(r *Renderer) {
+		r.SourceLines = lines
+	}
+}
+
+// SourceLine maps a line of the LaTeX output to the markdown line it was
+// rendered from. Lines are numbered from 1.
+type SourceLine struct {
+	TeX      int `json:"tex"`
+	Markdown int `json:"markdown"`
+}
+
+// SourceMap returns the map of the lines of LaTeX output rendered
+// WithSourceLines to the markdown lines of their blocks, which can be written
+// to a sidecar JSON file. Each entry is the first line of a block.
+func SourceMap(output []byte) []SourceLine {
+	var lines []SourceLine
+	for n := 1; len(output) > 0; n++ {
+		line := output
+		if i := bytes.IndexByte(output, '\n'); i >= 0 {
+			line = output[:i]
+			output = output[i+1:]
+		} else {
+			output = nil
+		}
+		if number, ok := bytes.CutPrefix(line, []byte(lineMarker)); ok {
+			if markdown, err := strconv.Atoi(string(number)); err == nil {
+				lines = append(lines, SourceLine{TeX: n + 1, Markdown: markdown})
+			}
+		}
+	}
+	return lines
+}
+
+// sourceLine returns the line number of a source offset, counting from the
+// last offset asked for when possible as blocks come in order.
+func (r *Renderer) sourceLine(source []byte, offset int) int {
+	if offset < r.lineOffset {
+		r.lineOffset, r.line = 0, 1
+	}
+	r.line += bytes.Count(source[r.lineOffset:offset], []byte{'\n'})
+	r.lineOffset = offset
+	return r.line
+}
+
+// lineRegisterer registers node rendering functions writing the markdown line
+// of top level blocks first.
+type lineRegisterer struct {
+	renderer.NodeRendererFuncRegisterer
+	r *Renderer
+}
+
+func (l *lineRegisterer) Register(kind ast.NodeKind, fn renderer.NodeRendererFunc) {
+	l.NodeRendererFuncRegisterer.Register(kind, func(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering && n.Type() == ast.TypeBlock && n.Parent() != nil && n.Parent().Kind() == ast.KindDocument {
+			if offset := nodeOffset(n); offset >= 0 && offset <= len(source) {
+				fmt.Fprintf(w, "\n%s%d\n", lineMarker, l.r.sourceLine(source, offset))
+			}
+		}
+		return fn(w, source, n, entering)
+	})
+}
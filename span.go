@@ -0,0 +1,274 @@
+package latex
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// KindSpan is the NodeKind of Span nodes.
+var KindSpan = ast.NewNodeKind("Span")
+
+// Span is a Pandoc style bracketed span, inline content with attributes:
+// [small caps]{.smallcaps} or [warning]{color=red}.
+type Span struct {
+	ast.BaseInline
+}
+
+// Kind implements ast.Node.Kind.
+func (n *Span) Kind() ast.NodeKind {
+	return KindSpan
+}
+
+// Dump implements ast.Node.Dump.
+func (n *Span) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// NewSpan returns a new Span node.
+func NewSpan() *Span {
+	return &Span{}
+}
+
+type spanTransformer struct{}
+
+// NewSpanTransformer returns a transformer turning bracketed text followed by
+// attributes, which the link parser leaves as text, into spans.
+func NewSpanTransformer() parser.ASTTransformer {
+	return &spanTransformer{}
+}
+
+func (t *spanTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	var containers []ast.Node
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering && n.FirstChild() != nil && n.FirstChild().Type() == ast.TypeInline {
+			containers = append(containers, n)
+		}
+		return ast.WalkContinue, nil
+	})
+	for _, n := range containers {
+		transformSpans(n, source)
+	}
+}
+
+// transformSpans replaces the bracketed spans among the children of parent.
+func transformSpans(parent ast.Node, source []byte) {
+	type bracket struct {
+		node   *ast.Text
+		offset int
+	}
+	var open []bracket
+	for c := parent.FirstChild(); c != nil; c = c.NextSibling() {
+		t, ok := c.(*ast.Text)
+		if !ok {
+			continue
+		}
+		for i := t.Segment.Start; i < t.Segment.Stop; i++ {
+			if i > t.Segment.Start && source[i-1] == '\\' {
+				continue // Escaped.
+			}
+			if source[i] == '[' {
+				open = append(open, bracket{t, i})
+				continue
+			}
+			if source[i] != ']' || len(open) == 0 {
+				continue
+			}
+			o := open[len(open)-1]
+			open = open[:len(open)-1]
+			if i+1 >= t.Segment.Stop || source[i+1] != '{' {
+				continue
+			}
+			attributesReader := text.NewReader(source[i+1 : t.Segment.Stop])
+			attributes, ok := parser.ParseAttributes(attributesReader)
+			if !ok {
+				continue
+			}
+			_, position := attributesReader.Position()
+			span := NewSpan()
+			for _, a := range attributes {
+				span.SetAttribute(a.Name, a.Value)
+			}
+			// Split the text around "]{...}" and after "[".
+			closing := splitText(parent, t, i)
+			after := splitText(parent, closing, i+1+position.Start)
+			parent.RemoveChild(parent, closing)
+			first := splitText(parent, o.node, o.offset)
+			first.Segment = first.Segment.WithStart(first.Segment.Start + 1)
+			parent.InsertBefore(parent, first, span)
+			for n := ast.Node(first); n != after; {
+				next := n.NextSibling()
+				span.AppendChild(span, n)
+				n = next
+			}
+			c = after
+			t = after
+			i = after.Segment.Start - 1
+		}
+	}
+}
+
+// splitText splits a text node at the given source offset and returns the new
+// node holding the text from there, which keeps the line breaks.
+func splitText(parent ast.Node, t *ast.Text, at int) *ast.Text {
+	next := ast.NewTextSegment(text.NewSegment(at, t.Segment.Stop))
+	next.SetSoftLineBreak(t.SoftLineBreak())
+	next.SetHardLineBreak(t.HardLineBreak())
+	next.SetRaw(t.IsRaw())
+	t.Segment = t.Segment.WithStop(at)
+	t.SetSoftLineBreak(false)
+	t.SetHardLineBreak(false)
+	parent.InsertAfter(parent, t, next)
+	return next
+}
+
+// spanClasses maps span classes to the LaTeX commands opening them.
+var spanClasses = map[string]string{
+	"smallcaps": "\\textsc{",
+	"underline": "\\underline{",
+	"ul":        "\\underline{",
+	"mark":      "\\colorbox{yellow}{",
+	"bold":      "\\textbf{",
+	"italic":    "\\textit{",
+	"sans":      "\\textsf{",
+	"mono":      "\\texttt{",
+	// Margin notes, set in the margin next to the line they are written in.
+	"margin":    "\\marginnote{",
+	"marginpar": "\\marginpar{",
+	"sidenote":  "\\sidenote{",
+}
+
+// colorValue matches the color names and hexadecimal colors accepted in spans.
+var colorValue = regexp.MustCompile(`^(?:#([0-9A-Fa-f]{6})|#([0-9A-Fa-f]{3})|([A-Za-z][A-Za-z0-9!.]*))$`)
+
+// latexColor returns the xcolor arguments for a CSS color name or hexadecimal value,
+// e.g. {red} or [HTML]{FF0000}.
+func latexColor(value string) (string, bool) {
+	m := colorValue.FindStringSubmatch(strings.TrimSpace(value))
+	switch {
+	case m == nil:
+		return "", false
+	case m[1] != "":
+		return "[HTML]{" + strings.ToUpper(m[1]) + "}", true
+	case m[2] != "":
+		var hex []byte
+		for _, c := range []byte(strings.ToUpper(m[2])) {
+			hex = append(hex, c, c)
+		}
+		return "[HTML]{" + string(hex) + "}", true
+	}
+	return "{" + m[3] + "}", true
+}
+
+// spanStyle returns the properties of a CSS style attribute, lower cased.
+func spanStyle(style string) map[string]string {
+	properties := make(map[string]string)
+	for _, declaration := range strings.Split(style, ";") {
+		if property, value, ok := strings.Cut(declaration, ":"); ok {
+			properties[strings.ToLower(strings.TrimSpace(property))] = strings.ToLower(strings.TrimSpace(value))
+		}
+	}
+	return properties
+}
+
+// spanCommands returns the LaTeX commands opening a span with the given classes
+// and color, background-color and style attributes, each closed by a brace.
+func spanCommands(classes []string, attributes map[string]string) []string {
+	var commands []string
+	for _, class := range classes {
+		if command, ok := spanClasses[class]; ok {
+			commands = append(commands, command)
+		}
+	}
+	style := spanStyle(attributes["style"])
+	for _, key := range []string{"color", "background-color"} {
+		value, ok := attributes[key]
+		if !ok {
+			value = style[key]
+		}
+		if color, ok := latexColor(value); ok {
+			if key == "color" {
+				commands = append(commands, "\\textcolor"+color+"{")
+			} else {
+				commands = append(commands, "\\colorbox"+color+"{")
+			}
+		}
+	}
+	switch style["font-weight"] {
+	case "bold", "bolder", "600", "700", "800", "900":
+		commands = append(commands, "\\textbf{")
+	}
+	if style["font-style"] == "italic" || style["font-style"] == "oblique" {
+		commands = append(commands, "\\textit{")
+	}
+	if style["font-variant"] == "small-caps" {
+		commands = append(commands, "\\textsc{")
+	}
+	if style["text-decoration"] == "underline" {
+		commands = append(commands, "\\underline{")
+	} else if style["text-decoration"] == "line-through" {
+		commands = append(commands, "\\sout{")
+	}
+	return commands
+}
+
+// spanAttributes returns the attributes of a node as strings.
+func spanAttributes(n ast.Node) map[string]string {
+	attributes := make(map[string]string)
+	for _, a := range n.Attributes() {
+		if value, ok := attributeString(n, string(a.Name)); ok {
+			attributes[string(a.Name)] = value
+		}
+	}
+	return attributes
+}
+
+// renderSpan writes the commands styling a span around its content.
+func (r *Renderer) renderSpan(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	commands := spanCommands(divClasses(node), spanAttributes(node))
+	if entering {
+		_, _ = w.WriteString(strings.Join(commands, ""))
+	} else {
+		_, _ = w.WriteString(strings.Repeat("}", len(commands)))
+	}
+	return ast.WalkContinue, nil
+}
+
+// spanCommandPackages maps the span commands to the packages defining them.
+var spanCommandPackages = map[string]string{
+	"\\textcolor":  "xcolor",
+	"\\colorbox":   "xcolor",
+	"\\marginnote": "marginnote",
+	"\\sidenote":   "sidenotes",
+	"\\sout":       "ulem",
+}
+
+// useSpanPackages adds the packages needed by the span classes and attributes to used.
+func useSpanPackages(classes []string, attributes map[string]string, used map[string]bool) {
+	for _, command := range spanCommands(classes, attributes) {
+		name, _, _ := strings.Cut(command, "{")
+		name, _, _ = strings.Cut(name, "[")
+		if p, ok := spanCommandPackages[name]; ok {
+			used[p] = true
+		}
+	}
+}
+
+type spansExtension struct{}
+
+// Spans is a goldmark extension parsing Pandoc style bracketed spans,
+// [text]{.smallcaps} or [text]{color=blue}, typeset with \textsc, \textcolor
+// and the like, and margin notes: [note]{.margin} with \marginnote, or
+// [note]{.sidenote} with \sidenote. HTML <span> elements are styled alike
+// without the extension.
+var Spans goldmark.Extender = &spansExtension{}
+
+func (e *spansExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithASTTransformers(util.Prioritized(NewSpanTransformer(), 500)))
+}
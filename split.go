@@ -0,0 +1,101 @@
+package latex
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/yuin/goldmark/util"
+)
+
+// splitMarker starts the lines marking the beginning of a top level section, followed by
+// the name of the file holding it, or the end of the last one, followed by nothing.
+const splitMarker = "% goldmark-latex split: "
+
+// WithSplitSections marks the beginning of every top level section in the output,
+// so that Split can move each of them to a file of its own.
+func WithSplitSections(split bool) Option {
+	return func(r *Renderer) {
+		r.SplitSections = split
+	}
+}
+
+// writeSplitMarker marks the beginning of the next top level section.
+func (r *Renderer) writeSplitMarker(w util.BufWriter) {
+	if !r.SplitSections {
+		return
+	}
+	r.sections++
+	fmt.Fprintf(w, "\n%ssection-%02d\n", splitMarker, r.sections)
+}
+
+// writeSplitEnd marks the end of the last top level section.
+func (r *Renderer) writeSplitEnd(w util.BufWriter) {
+	if r.SplitSections && r.sections > 0 {
+		_, _ = w.WriteString("\n" + splitMarker + "\n")
+	}
+}
+
+// Split moves each top level section of a document rendered with WithSplitSections
+// to its own file, created by create with the section name prefixed with prefix
+// and a .tex extension, e.g. paper-section-01.tex for the prefix "paper-", and
+// returns the master document, which includes the sections with \input. The
+// prefix, such as the name of the master document, keeps the files of documents
+// split in the same directory apart. Splitting large documents speeds up their
+// incremental compilation.
+func Split(output []byte, prefix string, create func(name string) (io.WriteCloser, error)) ([]byte, error) {
+	var master bytes.Buffer
+	var section io.WriteCloser
+	for len(output) > 0 {
+		line := output
+		if i := bytes.IndexByte(output, '\n'); i >= 0 {
+			line = output[:i+1]
+		}
+		output = output[len(line):]
+		name, ok := bytes.CutPrefix(bytes.TrimRight(line, "\n"), []byte(splitMarker))
+		if !ok {
+			w := io.Writer(&master)
+			if section != nil {
+				w = section
+			}
+			if _, err := w.Write(line); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if section != nil {
+			if err := section.Close(); err != nil {
+				return nil, err
+			}
+			section = nil
+		}
+		if len(name) == 0 {
+			continue
+		}
+		var err error
+		if section, err = create(prefix + string(name) + ".tex"); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&master, "\\input{%s%s}\n", prefix, name)
+	}
+	if section != nil {
+		if err := section.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return master.Bytes(), nil
+}
+
+// CreateFiles returns a function creating the files in dir, and the
+// directories holding them, to be used with Split or WriteProject.
+func CreateFiles(dir string) func(name string) (io.WriteCloser, error) {
+	return func(name string) (io.WriteCloser, error) {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+			return nil, err
+		}
+		return os.Create(name)
+	}
+}
@@ -0,0 +1,47 @@
+package latex
+
+import (
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// maxStreamingDiagnostics is the number of diagnostics kept in streaming mode,
+// the Logger receiving all of them.
+const maxStreamingDiagnostics = 100
+
+// WithStreaming flushes the output after each top level block, so that very
+// large documents, such as concatenated corpora, are written as they are
+// rendered, and drops the state of the blocks once rendered: only the first
+// diagnostics are kept, see WithLogger to receive all of them. The writers of
+// WithTee are flushed too.
+func WithStreaming(streaming bool) Option {
+	return func(r *Renderer) {
+		r.Streaming = streaming
+	}
+}
+
+// streamRegisterer registers node rendering functions flushing the output
+// after top level blocks.
+type streamRegisterer struct {
+	renderer.NodeRendererFuncRegisterer
+	r *Renderer
+}
+
+func (s *streamRegisterer) Register(kind ast.NodeKind, fn renderer.NodeRendererFunc) {
+	s.NodeRendererFuncRegisterer.Register(kind, func(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+		status, err := fn(w, source, n, entering)
+		if err != nil || entering || n.Type() != ast.TypeBlock || n.Parent() == nil || n.Parent().Kind() != ast.KindDocument {
+			return status, err
+		}
+		r := s.r
+		r.skipText = r.skipText[:0]
+		if r.tee != nil {
+			err = r.tee.flushOthers()
+		}
+		if e := w.Flush(); err == nil {
+			err = e
+		}
+		return status, err
+	})
+}
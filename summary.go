@@ -0,0 +1,80 @@
+package latex
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// ReadSummary reads an mdBook SUMMARY.md file and returns the contents of the book
+// it describes, to be used as Book.Contents. Level 1 headings, except the summary
+// title, start parts; list items become chapters and the items nested in them
+// become sections. Links without destination, i.e. draft chapters, are skipped.
+// Paths are relative to the directory of the summary, as in mdBook.
+func ReadSummary(fsys fs.FS, name string) ([]Chapter, error) {
+	source, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	doc := goldmark.DefaultParser().Parse(text.NewReader(source))
+	dir := path.Dir(name)
+	var chapters []Chapter
+	part, titled := "", false
+	for n := doc.FirstChild(); n != nil; n = n.NextSibling() {
+		switch n := n.(type) {
+		case *ast.Heading:
+			if n.Level != 1 {
+				continue
+			}
+			if !titled && len(chapters) == 0 {
+				titled = true // # Summary
+				continue
+			}
+			part = strings.TrimSpace(string(plainText(n, source)))
+		case *ast.List:
+			list := summaryList(n, source, dir)
+			if len(list) > 0 && part != "" {
+				list[0].Part, part = part, ""
+			}
+			chapters = append(chapters, list...)
+		case *ast.Paragraph:
+			// Prefix and suffix chapters.
+			for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+				if l, ok := c.(*ast.Link); ok && len(l.Destination) > 0 {
+					chapters = append(chapters, Chapter{Path: path.Join(dir, string(l.Destination)), Part: part})
+					part = ""
+				}
+			}
+		}
+	}
+	return chapters, nil
+}
+
+// summaryList returns the chapters listed in a SUMMARY.md list.
+func summaryList(list *ast.List, source []byte, dir string) []Chapter {
+	var chapters []Chapter
+	for item := list.FirstChild(); item != nil; item = item.NextSibling() {
+		var chapter Chapter
+		for c := item.FirstChild(); c != nil; c = c.NextSibling() {
+			switch c := c.(type) {
+			case *ast.List:
+				chapter.Sections = append(chapter.Sections, summaryList(c, source, dir)...)
+			default:
+				if l, ok := c.FirstChild().(*ast.Link); ok && len(l.Destination) > 0 {
+					chapter.Path = path.Join(dir, string(l.Destination))
+				}
+			}
+		}
+		if chapter.Path == "" {
+			// Draft chapter: keep the sections, if any.
+			chapters = append(chapters, chapter.Sections...)
+			continue
+		}
+		chapters = append(chapters, chapter)
+	}
+	return chapters
+}
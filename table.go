@@ -0,0 +1,296 @@
+package latex
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark/util"
+)
+
+// htmlTable is a table parsed from an HTML block.
+type htmlTable struct {
+	caption []htmlToken
+	rows    [][]*htmlCell
+	// Computed by layout.
+	columns int
+	grid    map[[2]int]*htmlCell
+}
+
+// htmlCell is a cell of an HTML table.
+type htmlCell struct {
+	header           bool
+	colspan, rowspan int
+	align            string // l, c or r; empty when unset.
+	content          []htmlToken
+	row, col         int
+}
+
+// parseHTMLTable parses the first table of an HTML block. Nested tables are
+// not supported.
+func parseHTMLTable(block []byte) (*htmlTable, bool) {
+	var (
+		t       *htmlTable
+		content *[]htmlToken
+	)
+	for _, token := range htmlTokens(string(block)) {
+		tag := token.tag
+		if !token.isTag || (tag.name != "table" && t == nil) {
+			if t != nil && content != nil {
+				*content = append(*content, token)
+			}
+			continue
+		}
+		switch tag.name {
+		case "table":
+			if tag.end {
+				return t, t != nil && len(t.rows) > 0
+			}
+			if t != nil {
+				return nil, false
+			}
+			t = &htmlTable{}
+		case "caption":
+			content = nil
+			if !tag.end {
+				content = &t.caption
+			}
+		case "tr":
+			content = nil
+			if !tag.end {
+				t.rows = append(t.rows, nil)
+			}
+		case "td", "th":
+			content = nil
+			if tag.end {
+				continue
+			}
+			if len(t.rows) == 0 {
+				t.rows = append(t.rows, nil)
+			}
+			cell := &htmlCell{
+				header:  tag.name == "th",
+				colspan: htmlSpan(tag.attributes["colspan"]),
+				rowspan: htmlSpan(tag.attributes["rowspan"]),
+				align:   htmlAlign(tag.attributes),
+			}
+			row := &t.rows[len(t.rows)-1]
+			*row = append(*row, cell)
+			content = &cell.content
+		case "thead", "tbody", "tfoot", "colgroup", "col":
+		default:
+			if content != nil {
+				*content = append(*content, token)
+			}
+		}
+	}
+	return nil, false
+}
+
+// htmlSpan parses a colspan or rowspan attribute.
+func htmlSpan(value string) int {
+	if n, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && n > 1 {
+		return n
+	}
+	return 1
+}
+
+// htmlAlign returns the column type matching the align attribute or the
+// text-align style of a cell.
+func htmlAlign(attributes map[string]string) string {
+	align := attributes["align"]
+	for _, declaration := range strings.Split(attributes["style"], ";") {
+		if property, value, ok := strings.Cut(declaration, ":"); ok && strings.TrimSpace(property) == "text-align" {
+			align = value
+		}
+	}
+	switch strings.ToLower(strings.TrimSpace(align)) {
+	case "left":
+		return "l"
+	case "center":
+		return "c"
+	case "right":
+		return "r"
+	}
+	return ""
+}
+
+// maxHTMLTableColumns bounds the columns of HTML tables, which cells spanning
+// many columns could otherwise make as large as the memory.
+const maxHTMLTableColumns = 1000
+
+// layout places the cells on the table grid, accounting for spans, which are
+// bounded by the rows and columns of the table.
+func (t *htmlTable) layout() {
+	t.grid = make(map[[2]int]*htmlCell)
+	for i, row := range t.rows {
+		col := 0
+		for _, cell := range row {
+			for t.grid[[2]int{i, col}] != nil {
+				col++
+			}
+			cell.row, cell.col = i, col
+			if cell.rowspan > len(t.rows)-i {
+				cell.rowspan = len(t.rows) - i
+			}
+			cell.colspan = max(1, min(cell.colspan, maxHTMLTableColumns-col))
+			for r := i; r < i+cell.rowspan; r++ {
+				for c := col; c < col+cell.colspan; c++ {
+					t.grid[[2]int{r, c}] = cell
+				}
+			}
+			col += cell.colspan
+			if col > t.columns {
+				t.columns = col
+			}
+		}
+	}
+	for position := range t.grid {
+		if position[1]+1 > t.columns {
+			t.columns = position[1] + 1
+		}
+	}
+}
+
+// alignments returns the column types, taken from the first cell of each column
+// setting the alignment.
+func (t *htmlTable) alignments() []string {
+	aligns := make([]string, t.columns)
+	for i := range t.rows {
+		for c := range aligns {
+			if cell := t.grid[[2]int{i, c}]; aligns[c] == "" && cell != nil && cell.colspan == 1 {
+				aligns[c] = cell.align
+			}
+		}
+	}
+	for c := range aligns {
+		if aligns[c] == "" {
+			aligns[c] = "l"
+		}
+	}
+	return aligns
+}
+
+// hasRowspan reports whether a cell of the table spans rows.
+func (t *htmlTable) hasRowspan() bool {
+	for _, row := range t.rows {
+		for _, cell := range row {
+			if cell.rowspan > 1 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// writeHTMLTable writes a table parsed from HTML as a ruled tabular, in a table
+// float when it has a caption. Spanning cells use \multicolumn and \multirow.
+func (r *Renderer) writeHTMLTable(w util.BufWriter, t *htmlTable) {
+	t.layout()
+	aligns := t.alignments()
+	if len(t.caption) > 0 {
+		placement := r.FloatPlacement
+		if placement == "" {
+			placement = "htbp"
+		}
+		_, _ = w.WriteString("\n\\begin{table}[" + placement + "]\n\\centering\n")
+		if r.TableCaptionsAbove {
+			r.writeTableCaption(w, t)
+		}
+	} else {
+		_, _ = w.WriteString("\n\\begin{center}\n")
+	}
+	_, _ = w.WriteString("\\begin{tabular}{|" + strings.Join(aligns, "|") + "|}\n\\hline\n")
+	for i := range t.rows {
+		for c := 0; c < t.columns; {
+			if c > 0 {
+				_, _ = w.WriteString(" & ")
+			}
+			cell := t.grid[[2]int{i, c}]
+			if cell == nil {
+				c++
+				continue
+			}
+			content := ""
+			if cell.row == i {
+				content = r.htmlCellContent(cell)
+			}
+			if cell.rowspan > 1 && cell.row == i {
+				content = "\\multirow{" + strconv.Itoa(cell.rowspan) + "}{*}{" + content + "}"
+			}
+			align := cell.align
+			if align == "" {
+				align = aligns[c]
+			}
+			if cell.colspan > 1 || align != aligns[c] {
+				spec := align + "|"
+				if c == 0 {
+					spec = "|" + spec
+				}
+				content = "\\multicolumn{" + strconv.Itoa(cell.colspan) + "}{" + spec + "}{" + content + "}"
+			}
+			_, _ = w.WriteString(content)
+			c += cell.colspan
+		}
+		_, _ = w.WriteString(" \\\\\n")
+		t.writeRule(w, i)
+	}
+	_, _ = w.WriteString("\\end{tabular}\n")
+	if len(t.caption) > 0 {
+		if !r.TableCaptionsAbove {
+			r.writeTableCaption(w, t)
+		}
+		_, _ = w.WriteString("\\end{table}\n")
+	} else {
+		_, _ = w.WriteString("\\end{center}\n")
+	}
+}
+
+// writeTableCaption writes the caption of a table.
+func (r *Renderer) writeTableCaption(w util.BufWriter, t *htmlTable) {
+	_, _ = w.WriteString("\\caption{")
+	r.writeHTMLText(w, t.caption, false)
+	_, _ = w.WriteString("}\n")
+}
+
+// htmlCellContent returns the LaTeX content of a cell.
+func (r *Renderer) htmlCellContent(cell *htmlCell) string {
+	b := getBuffer()
+	defer putBuffer(b)
+	bw := getBufWriter(b)
+	r.writeHTMLText(bw, cell.content, false)
+	_ = bw.Flush()
+	putBufWriter(bw)
+	content := strings.TrimSpace(b.String())
+	if cell.header && content != "" {
+		content = "\\textbf{" + content + "}"
+	}
+	return content
+}
+
+// writeRule writes the rule below row i: \hline, or \cline around the cells
+// spanning into the next row.
+func (t *htmlTable) writeRule(w util.BufWriter, i int) {
+	spanning := make([]bool, t.columns)
+	spans := false
+	for c := range spanning {
+		cell := t.grid[[2]int{i, c}]
+		spanning[c] = cell != nil && t.grid[[2]int{i + 1, c}] == cell
+		spans = spans || spanning[c]
+	}
+	if !spans {
+		_, _ = w.WriteString("\\hline\n")
+		return
+	}
+	for c := 0; c < t.columns; c++ {
+		if spanning[c] {
+			continue
+		}
+		end := c
+		for end+1 < t.columns && !spanning[end+1] {
+			end++
+		}
+		_, _ = w.WriteString("\\cline{" + strconv.Itoa(c+1) + "-" + strconv.Itoa(end+1) + "}")
+		c = end
+	}
+	_, _ = w.WriteString("\n")
+}
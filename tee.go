@@ -0,0 +1,104 @@
+package latex
+
+import (
+	"bytes"
+	"io"
+	"sync"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// WithTee also writes the output to writers, e.g. to keep an in-memory copy
+// of the document written to a file for post-processing. The writers receive
+// the whole document once it is rendered without error, and nothing
+// otherwise; the documents rendered concurrently are written one at a time.
+// In streaming mode, see WithStreaming, they receive the output as it is
+// flushed instead, and partial documents on errors.
+func WithTee(writers ...io.Writer) Option {
+	return func(r *Renderer) {
+		r.TeeWriters = append(r.TeeWriters, writers...)
+		if r.teeLock == nil {
+			r.teeLock = new(sync.Mutex)
+		}
+	}
+}
+
+// teeWriter is a util.BufWriter writing to w and buffering the output for
+// other writers.
+type teeWriter struct {
+	util.BufWriter
+	buffer bytes.Buffer
+	others []io.Writer
+	lock   *sync.Mutex
+}
+
+func newTeeWriter(w util.BufWriter, writers []io.Writer, lock *sync.Mutex) *teeWriter {
+	return &teeWriter{BufWriter: w, others: writers, lock: lock}
+}
+
+func (t *teeWriter) Write(p []byte) (int, error) {
+	t.buffer.Write(p)
+	return t.BufWriter.Write(p)
+}
+
+func (t *teeWriter) WriteByte(c byte) error {
+	t.buffer.WriteByte(c)
+	return t.BufWriter.WriteByte(c)
+}
+
+func (t *teeWriter) WriteRune(r rune) (int, error) {
+	t.buffer.WriteRune(r)
+	return t.BufWriter.WriteRune(r)
+}
+
+func (t *teeWriter) WriteString(s string) (int, error) {
+	t.buffer.WriteString(s)
+	return t.BufWriter.WriteString(s)
+}
+
+// flushOthers writes the buffered output to the other writers, returning the
+// first error met writing to them.
+func (t *teeWriter) flushOthers() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	var err error
+	for _, o := range t.others {
+		if _, e := o.Write(t.buffer.Bytes()); err == nil {
+			err = e
+		}
+	}
+	t.buffer.Reset()
+	return err
+}
+
+// teeRegisterer registers node rendering functions writing to the tee writers
+// of the renderer as well.
+type teeRegisterer struct {
+	renderer.NodeRendererFuncRegisterer
+	r *Renderer
+}
+
+func (t *teeRegisterer) Register(kind ast.NodeKind, fn renderer.NodeRendererFunc) {
+	t.NodeRendererFuncRegisterer.Register(kind, func(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+		tw, ok := w.(*teeWriter)
+		if !ok {
+			if t.r.tee == nil || t.r.tee.BufWriter != w {
+				t.r.tee = newTeeWriter(w, t.r.TeeWriters, t.r.teeLock)
+			}
+			tw = t.r.tee
+		}
+		if kind == ast.KindDocument && entering {
+			tw.buffer.Reset()
+		}
+		status, err := fn(tw, source, n, entering)
+		switch {
+		case err != nil:
+			tw.buffer.Reset()
+		case kind == ast.KindDocument && !entering:
+			err = tw.flushOthers()
+		}
+		return status, err
+	})
+}
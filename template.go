@@ -0,0 +1,189 @@
+package latex
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+)
+
+// WithPreambleTemplate makes the renderer execute the preamble, default or
+// custom, as a Go text/template with a PreambleData value, so that a single
+// preamble file can serve many documents. Actions are delimited by ((* and
+// *)), which do not occur in LaTeX, instead of braces, e.g.
+//
+//	\usepackage[((* .Language *))]{babel}
+//	\hypersetup{pdftitle={((* .Title *))}}
+//
+// The escape function escapes arbitrary values for LaTeX.
+func WithPreambleTemplate(enabled bool) Option {
+	return func(r *Renderer) {
+		r.PreambleTemplate = enabled
+	}
+}
+
+// PreambleData holds the document variables available to preamble templates.
+// String fields are escaped for LaTeX.
+type PreambleData struct {
+	Title    string
+	Subtitle string
+	Date     string
+	// Author is the list of authors' names, separated by \and.
+	Author  string
+	Authors []string
+	// Language is the main language name, such as english.
+	Language  string
+	Languages []string
+	// Engine is the TeX engine name: pdflatex, xelatex or lualatex.
+	Engine string
+	// Packages lists the packages needed by the document content, sorted by name.
+	Packages []string
+	// Metadata holds the raw, unescaped, document metadata.
+	Metadata map[string]any
+}
+
+// HasPackage reports whether the document content needs the named package.
+func (d PreambleData) HasPackage(name string) bool {
+	for _, p := range d.Packages {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// preambleData returns the template variables of the document.
+func (r *Renderer) preambleData(doc ast.Node, source []byte, meta map[string]any) PreambleData {
+	d := PreambleData{
+		Engine:    r.Engine.String(),
+		Packages:  r.usedPackages(doc, source),
+		Metadata:  meta,
+		Languages: r.documentLanguages(meta),
+	}
+	d.Title, _ = metaString(meta, "title")
+	d.Subtitle, _ = metaString(meta, "subtitle")
+	d.Date, _ = metaString(meta, "date")
+	d.Title, d.Subtitle, d.Date = r.Writer.EscapeString(d.Title), r.Writer.EscapeString(d.Subtitle), r.Writer.EscapeString(d.Date)
+	for _, a := range metaAuthors(meta) {
+		d.Authors = append(d.Authors, r.Writer.EscapeString(a.Name))
+	}
+	d.Author = strings.Join(d.Authors, " \\and ")
+	if len(d.Languages) > 0 {
+		d.Language = d.Languages[0]
+	}
+	return d
+}
+
+// Template delimiters of preambles, see WithPreambleTemplate.
+const (
+	preambleLeftDelim  = "((*"
+	preambleRightDelim = "*))"
+)
+
+// executePreamble executes the preamble as a template, whose escape function
+// escapes with writer.
+func executePreamble(preamble []byte, data PreambleData, writer Writer) ([]byte, error) {
+	tpl, err := template.New("preamble").Delims(preambleLeftDelim, preambleRightDelim).Funcs(template.FuncMap{
+		"escape": func(v any) string { return writer.EscapeString(toString(v)) },
+	}).Parse(string(preamble))
+	if err != nil {
+		return nil, fmt.Errorf("parsing preamble template: %w", err)
+	}
+	var b bytes.Buffer
+	if err := tpl.Execute(&b, data); err != nil {
+		return nil, fmt.Errorf("executing preamble template: %w", err)
+	}
+	return b.Bytes(), nil
+}
+
+// usedPackages returns the packages needed to typeset the content of the documents.
+func usedPackages(docs ...document) []string {
+	used := make(map[string]bool)
+	for _, doc := range docs {
+		usePackages(doc, used)
+	}
+	packages := make([]string, 0, len(used))
+	for p := range used {
+		packages = append(packages, p)
+	}
+	sort.Strings(packages)
+	return packages
+}
+
+// usePackages adds the packages needed to typeset the content of doc to used.
+func usePackages(doc document, used map[string]bool) {
+	_ = ast.Walk(doc.node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n.Kind() {
+		case ast.KindImage:
+			used["graphicx"] = true
+		case ast.KindLink, ast.KindAutoLink:
+			used["hyperref"] = true
+		case ast.KindCodeBlock:
+			used["minted"] = true
+		case ast.KindFencedCodeBlock:
+			if n := n.(*ast.FencedCodeBlock); n.Info != nil {
+				if _, ok := rawFormat(n.Info.Segment.Value(doc.source)); ok {
+					break
+				}
+			}
+			if isMathCodeBlock(n.(*ast.FencedCodeBlock), doc.source) {
+				used["amsmath"] = true // For align and the environments found in display math.
+			} else {
+				used["minted"] = true
+			}
+		case KindDisplayMath:
+			used["amsmath"] = true
+		case ast.KindBlockquote:
+			if _, ok := blockquoteCallout(n, doc.source); !ok {
+				used["framed"] = true
+			}
+		case ast.KindHeading:
+			if hasFragileContent(n) {
+				used["hyperref"] = true // For \texorpdfstring.
+			}
+		case east.KindStrikethrough:
+			used["ulem"] = true
+		case KindQuantity:
+			used["siunitx"] = true
+		case KindChemistry, KindChemistryBlock:
+			used["mhchem"] = true
+		case ast.KindHTMLBlock:
+			if t, ok := parseHTMLTable(blockContent(n, doc.source)); ok && t.hasRowspan() {
+				used["multirow"] = true
+			}
+			if isHTMLDetails(blockContent(n, doc.source)) {
+				used["tcolorbox"] = true
+			}
+			if _, ok := htmlImages(blockContent(n, doc.source)); ok {
+				used["graphicx"] = true
+			}
+		case KindDiv:
+			if containsString(divClasses(n), "epigraph") {
+				used["epigraph"] = true
+			}
+		case KindSpan:
+			useSpanPackages(divClasses(n), spanAttributes(n), used)
+		case ast.KindRawHTML:
+			if t, ok := parseHTMLTag(rawHTML(n.(*ast.RawHTML), doc.source)); ok && !t.end {
+				switch t.name {
+				case "s", "del", "strike":
+					used["ulem"] = true
+				case "a":
+					used["hyperref"] = true
+				case "img":
+					used["graphicx"] = true
+				case "span":
+					useSpanPackages(strings.Fields(t.attributes["class"]), t.attributes, used)
+				}
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+}
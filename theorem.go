@@ -0,0 +1,89 @@
+package latex
+
+import (
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/util"
+)
+
+// theorems lists the theorem-like environments typeset from divs with the same class,
+// in the order they are declared, with their amsthm style.
+var theorems = []struct {
+	name, title, style string
+}{
+	{"theorem", "Theorem", "plain"},
+	{"lemma", "Lemma", "plain"},
+	{"corollary", "Corollary", "plain"},
+	{"proposition", "Proposition", "plain"},
+	{"conjecture", "Conjecture", "plain"},
+	{"definition", "Definition", "definition"},
+	{"example", "Example", "definition"},
+	{"remark", "Remark", "remark"},
+	{"proof", "", ""}, // Provided by amsthm.
+}
+
+// theoremTitles maps theorem-like environment names to their titles.
+var theoremTitles = func() map[string]string {
+	m := make(map[string]string, len(theorems))
+	for _, t := range theorems {
+		m[t.name] = t.title
+	}
+	return m
+}()
+
+// writeTheorem writes a theorem-like environment, with the optional title found in the
+// div's title attribute and a label from its id.
+func writeTheorem(w util.BufWriter, writer Writer, n ast.Node, name string, entering bool) {
+	if !entering {
+		_, _ = w.WriteString("\\end{" + name + "}\n")
+		return
+	}
+	_, _ = w.WriteString("\n\\begin{" + name + "}")
+	if title, ok := attributeString(n, "title"); ok && title != "" {
+		_ = w.WriteByte('[')
+		writer.Write(w, []byte(title))
+		_ = w.WriteByte(']')
+	}
+	_ = w.WriteByte('\n')
+	if id, ok := attributeString(n, "id"); ok && labelID(id) != "" {
+		_, _ = w.WriteString("\\label{" + labelID(id) + "}\n")
+	}
+}
+
+// usedTheorems returns the names of the theorem-like environments used in the documents.
+func usedTheorems(docs ...document) map[string]bool {
+	used := make(map[string]bool)
+	for _, doc := range docs {
+		_ = ast.Walk(doc.node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+			if entering && n.Kind() == KindDiv {
+				for _, class := range divClasses(n) {
+					if _, ok := theoremTitles[class]; ok {
+						used[class] = true
+						break
+					}
+				}
+			}
+			return ast.WalkContinue, nil
+		})
+	}
+	return used
+}
+
+// addTheorems loads amsthm and declares the theorem-like environments used in the documents.
+func addTheorems(b *PreambleBuilder, docs ...document) {
+	used := usedTheorems(docs...)
+	if len(used) == 0 {
+		return
+	}
+	b.AddPackage("amsthm")
+	style := ""
+	for _, t := range theorems {
+		if !used[t.name] || t.title == "" {
+			continue
+		}
+		if t.style != style {
+			b.AddCommand("\\theoremstyle{" + t.style + "}")
+			style = t.style
+		}
+		b.AddCommand("\\newtheorem{" + t.name + "}{" + t.title + "}")
+	}
+}
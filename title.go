@@ -0,0 +1,207 @@
+package latex
+
+import (
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/util"
+)
+
+// TitlePage customizes how the document title is typeset.
+type TitlePage struct {
+	// Environment sets the title, subtitle, authors and date on a page of
+	// their own, using a titlepage environment instead of \maketitle.
+	Environment bool
+	// Template is raw LaTeX written in place of \maketitle. The {title},
+	// {subtitle}, {author} and {date} placeholders are replaced by the
+	// escaped metadata values; the other placeholders supported by
+	// Headers are also available.
+	Template string
+}
+
+// WithTitlePage enables the title and customizes how it is typeset.
+func WithTitlePage(page TitlePage) Option {
+	return func(r *Renderer) {
+		r.TitlePage = &page
+		r.makeTitle = true
+	}
+}
+
+// WithTableOfContents inserts a table of contents at the beginning of the document.
+func WithTableOfContents(toc bool) Option {
+	return func(r *Renderer) {
+		r.TableOfContents = toc
+	}
+}
+
+// FrontMatterItem is an element written at the beginning of the document body.
+type FrontMatterItem int
+
+const (
+	// FrontMatterTitle is the document title, see WithMakeTitle and WithTitlePage,
+	// followed by the revision history, the dedication, the declaration and the
+	// acknowledgements found in metadata.
+	FrontMatterTitle FrontMatterItem = iota
+	// FrontMatterAbstract is the abstract, followed by the keywords.
+	FrontMatterAbstract
+	// FrontMatterContents is the table of contents, see WithTableOfContents.
+	FrontMatterContents
+)
+
+var defaultFrontMatterOrder = []FrontMatterItem{FrontMatterTitle, FrontMatterAbstract, FrontMatterContents}
+
+// WithFrontMatterOrder sets the order of the title, abstract and table of contents
+// at the beginning of the document. Items left out are not written.
+func WithFrontMatterOrder(items ...FrontMatterItem) Option {
+	return func(r *Renderer) {
+		r.FrontMatterOrder = items
+	}
+}
+
+// writeFrontMatter writes the title, abstract and table of contents in the configured order.
+// With WithAbstractSection, the abstract section of the document is written as
+// the abstract when the metadata has none.
+func (r *Renderer) writeFrontMatter(w util.BufWriter, doc ast.Node, source []byte, meta map[string]any) error {
+	order := r.FrontMatterOrder
+	if order == nil {
+		order = defaultFrontMatterOrder
+	}
+	keywords := metaKeywords(meta)
+	r.writeCover(w, meta)
+	for _, item := range order {
+		switch item {
+		case FrontMatterTitle:
+			if r.makeTitle {
+				r.writeTitle(w, meta)
+				r.writePageNumberingTitle(w)
+				writeRaw(w, r.AfterTitle)
+			}
+			r.writeRevisions(w, meta)
+			r.writeDedication(w, meta)
+			r.writeMatterPages(w, meta)
+		case FrontMatterAbstract:
+			if r.writeMetaAbstract(w, meta) {
+				// The metadata abstract takes precedence over the section.
+			} else if abstract := documentAbstract(doc); abstract != nil && r.AbstractSection {
+				if err := r.writeSectionAbstract(w, source, abstract); err != nil {
+					return err
+				}
+			}
+			r.writeKeywords(w, keywords)
+		case FrontMatterContents:
+			if r.TableOfContents && r.Beamer {
+				writeFrame(w, "", "\\tableofcontents")
+			} else if r.TableOfContents {
+				_, _ = w.WriteString("\\tableofcontents\n")
+			}
+		}
+	}
+	return nil
+}
+
+// writeTitle writes the title with \maketitle, a titlepage environment or the custom template.
+func (r *Renderer) writeTitle(w util.BufWriter, meta map[string]any) {
+	page := r.TitlePage
+	switch {
+	case page != nil && page.Template != "":
+		_, _ = w.WriteString(r.titleReplacer(meta).Replace(page.Template))
+		_ = w.WriteByte('\n')
+	case page != nil && page.Environment:
+		_, _ = w.WriteString(r.titleReplacer(meta).Replace(titlePageTemplate))
+	case r.Beamer:
+		writeFrame(w, "", "\\titlepage")
+	default:
+		_, _ = w.WriteString("\\maketitle\n")
+	}
+}
+
+// titleReplacer replaces the placeholders supported in headers and footers, such as
+// {title}, {author} or {version}, with their values.
+func (r *Renderer) titleReplacer(meta map[string]any) *strings.Replacer {
+	var oldnew []string
+	for name, value := range r.placeholders(meta) {
+		oldnew = append(oldnew, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(oldnew...)
+}
+
+const titlePageTemplate = `\begin{titlepage}
+\centering
+\vspace*{\fill}
+{\Huge\bfseries {title}\par}
+\vspace{1em}
+{\Large {subtitle}\par}
+\vspace{3em}
+{\large {author}\par}
+\vspace{1em}
+{\large {date}\par}
+\vspace*{\fill}
+\end{titlepage}
+`
+
+// writeCover writes a cover page with the image found under the "cover-image"
+// metadata key stretched over the whole page and the title on top of it. An
+// image whose path cannot be written is left out.
+func (r *Renderer) writeCover(w util.BufWriter, meta map[string]any) {
+	image, ok := metaString(meta, "cover-image")
+	if !ok || image == "" {
+		return
+	}
+	_, _ = w.WriteString("\\begin{titlepage}\n")
+	if isSafePath(image) {
+		_, _ = w.WriteString("\\AddToShipoutPictureBG*{\\AtPageLowerLeft{\\includegraphics[width=\\paperwidth,height=\\paperheight]{")
+		_, _ = w.WriteString(image)
+		_, _ = w.WriteString("}}}\n")
+	} else {
+		r.warnAt(nil, nil, -1, DiagnosticUnsafeContent, "cover image %s skipped, its path cannot be written", image)
+		r.warnComment(w, "cover image skipped, its path has braces, backslashes or %% signs")
+	}
+	if title, ok := metaString(meta, "title"); ok {
+		_, _ = w.WriteString("\\centering\n\\vspace*{0.3\\textheight}\n")
+		_, _ = w.WriteString("\\colorbox{white}{\\parbox{0.8\\textwidth}{\\centering\\Huge\\bfseries ")
+		r.Writer.Write(w, []byte(title))
+		_, _ = w.WriteString("}}\n")
+	} else {
+		_, _ = w.WriteString("\\null\n")
+	}
+	_, _ = w.WriteString("\\end{titlepage}\n")
+}
+
+// writeDedication writes the text found under the "dedication" metadata key
+// centered on a page of its own.
+func (r *Renderer) writeDedication(w util.BufWriter, meta map[string]any) {
+	dedication, ok := metaString(meta, "dedication")
+	if !ok || dedication == "" {
+		return
+	}
+	_, _ = w.WriteString("\\clearpage\n\\thispagestyle{empty}\n\\vspace*{\\fill}\n\\begin{center}\n\\itshape ")
+	r.Writer.Write(w, []byte(strings.TrimSpace(dedication)))
+	_, _ = w.WriteString("\n\\end{center}\n\\vspace*{\\fill}\n\\clearpage\n")
+}
+
+// writeRevisions writes a revision history table from the list found under the
+// "revisions" metadata key, each revision having version, date, author and
+// description keys.
+func (r *Renderer) writeRevisions(w util.BufWriter, meta map[string]any) {
+	revisions := metaList(meta["revisions"])
+	if len(revisions) == 0 {
+		return
+	}
+	_, _ = w.WriteString("\\section*{Revision History}\n\\begin{center}\n\\begin{tabular}{|l|l|l|p{0.45\\textwidth}|}\n\\hline\n")
+	_, _ = w.WriteString("\\textbf{Version} & \\textbf{Date} & \\textbf{Author} & \\textbf{Description} \\\\\n\\hline\n")
+	for _, revision := range revisions {
+		m, ok := metaMap(revision)
+		if !ok {
+			continue
+		}
+		for i, key := range []string{"version", "date", "author", "description"} {
+			if i > 0 {
+				_, _ = w.WriteString(" & ")
+			}
+			value, _ := metaString(m, key)
+			r.Writer.Write(w, []byte(value))
+		}
+		_, _ = w.WriteString(" \\\\\n\\hline\n")
+	}
+	_, _ = w.WriteString("\\end{tabular}\n\\end{center}\n")
+}
@@ -0,0 +1,146 @@
+package latex
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// TodoNotes configures the typesetting of open items written as HTML comments,
+// <!-- TODO: ... --> or <!-- FIXME: ... -->, see WithTodoNotes.
+type TodoNotes struct {
+	// Markers also typesets the paragraphs starting with TODO: or FIXME: as notes.
+	Markers bool
+	// Strip removes the notes, for final builds.
+	Strip bool
+}
+
+// WithTodoNotes typesets TODO and FIXME comments as todonotes notes, in the
+// margin for inline comments and in the text for block comments, so that draft
+// documents show their open items. FIXME notes are colored red.
+func WithTodoNotes(notes TodoNotes) Option {
+	return func(r *Renderer) {
+		r.TodoNotes = &notes
+	}
+}
+
+// todoMarker matches the text of a TODO or FIXME note.
+var todoMarker = regexp.MustCompile(`^(TODO|FIXME):[ \t]*`)
+
+// todoNote returns the kind and the text of a TODO or FIXME note, with spaces collapsed.
+func todoNote(s string) (kind, note string, ok bool) {
+	m := todoMarker.FindStringSubmatch(s)
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], strings.Join(strings.Fields(s[len(m[0]):]), " "), true
+}
+
+// todoParagraph returns the marker starting a paragraph typeset as a note, if any.
+func (r *Renderer) todoParagraph(n ast.Node, source []byte) (kind string, marker text.Segment, ok bool) {
+	t, isText := n.FirstChild().(*ast.Text)
+	if r.TodoNotes == nil || !r.TodoNotes.Markers || n.Kind() != ast.KindParagraph || !isText {
+		return "", text.Segment{}, false
+	}
+	m := todoMarker.FindSubmatch(t.Segment.Value(source))
+	if m == nil {
+		return "", text.Segment{}, false
+	}
+	return string(m[1]), t.Segment.WithStop(t.Segment.Start + len(m[0])), true
+}
+
+// writeTodoStart opens a note, inline notes being set in the text.
+func writeTodoStart(w util.BufWriter, kind string, inline bool) {
+	var options []string
+	if inline {
+		options = append(options, "inline")
+	}
+	if kind == "FIXME" {
+		options = append(options, "color=red!40")
+	}
+	_, _ = w.WriteString("\\todo")
+	if len(options) > 0 {
+		_, _ = w.WriteString("[" + strings.Join(options, ", ") + "]")
+	}
+	_ = w.WriteByte('{')
+}
+
+// renderTodoComment writes a TODO or FIXME comment as a note, in the margin
+// unless block, and reports whether the comment is one.
+func (r *Renderer) renderTodoComment(w util.BufWriter, directive string, block bool) bool {
+	kind, note, ok := todoNote(directive)
+	if r.TodoNotes == nil || !ok {
+		return false
+	}
+	if r.TodoNotes.Strip {
+		return true
+	}
+	if block {
+		_ = w.WriteByte('\n')
+	}
+	writeTodoStart(w, kind, block)
+	r.Writer.Write(w, []byte(note))
+	_ = w.WriteByte('}')
+	if block {
+		_ = w.WriteByte('\n')
+	}
+	return true
+}
+
+// renderTodoParagraph writes a paragraph starting with a TODO or FIXME marker
+// as an inline note, without the marker.
+func (r *Renderer) renderTodoParagraph(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if r.TodoNotes.Strip {
+		return ast.WalkSkipChildren, nil
+	}
+	if !entering {
+		_, _ = w.WriteString("}\n")
+		return ast.WalkContinue, nil
+	}
+	kind, marker, _ := r.todoParagraph(n, source)
+	if t := n.FirstChild().(*ast.Text); t.Segment.Stop > marker.Stop {
+		splitText(n, t, marker.Stop)
+	}
+	r.skipText = append(r.skipText, marker)
+	_ = w.WriteByte('\n')
+	writeTodoStart(w, kind, true)
+	return ast.WalkContinue, nil
+}
+
+// usesTodoNotes reports whether the documents have notes to typeset.
+func (r *Renderer) usesTodoNotes(docs ...document) bool {
+	if r.TodoNotes == nil || r.TodoNotes.Strip {
+		return false
+	}
+	used := false
+	for _, doc := range docs {
+		_ = ast.Walk(doc.node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+			if !entering {
+				return ast.WalkContinue, nil
+			}
+			var directive string
+			switch n := n.(type) {
+			case *ast.HTMLBlock:
+				directive, _ = htmlCommentDirective(n, doc.source)
+			case *ast.RawHTML:
+				directive, _ = inlineComment(rawHTML(n, doc.source))
+			case *ast.Paragraph:
+				_, _, used = r.todoParagraph(n, doc.source)
+			}
+			if _, _, ok := todoNote(directive); ok {
+				used = true
+			}
+			if used {
+				return ast.WalkStop, nil
+			}
+			return ast.WalkContinue, nil
+		})
+		if used {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package latex
+
+import (
+	"strings"
+
+	"github.com/yuin/goldmark/util"
+)
+
+// tuftePreamble is the base preamble of the Tufte presets, given the class.
+func tuftePreamble(class string) []byte {
+	return []byte(`\documentclass{` + class + `}
+
+\usepackage{graphicx}
+\usepackage{amsmath}
+\usepackage{booktabs}
+`)
+}
+
+// tufteConflicts lists the packages clashing with the Tufte classes, which
+// set the page layout and define \sidenote and \marginnote themselves.
+var tufteConflicts = []string{"geometry", "sidenotes", "marginnote", "titlesec", "helvet"}
+
+// writeTufteAuthors writes the authors' names, as the Tufte title pages show
+// neither affiliations nor contact details.
+func writeTufteAuthors(w util.BufWriter, writer Writer, authors []Author) {
+	names := make([]string, len(authors))
+	for i, a := range authors {
+		names[i] = writer.EscapeString(a.Name)
+	}
+	_, _ = w.WriteString("\\author{" + strings.Join(names, " \\and ") + "}\n")
+}
+
+// TufteHandout is the preset of Tufte handouts, typeset with the tufte-handout
+// class: footnotes are sidenotes, images of the margin class, ?class=margin,
+// are margin figures and those of the fullwidth class span the text and the
+// margin. Headings are limited to subsections.
+var TufteHandout = Preset{
+	Name:      "tufte-handout",
+	Preamble:  tuftePreamble("tufte-handout"),
+	Conflicts: tufteConflicts,
+	Authors:   writeTufteAuthors,
+	Options: []Option{
+		WithMakeTitle(true),
+		WithFootnoteCommand("sidenote"),
+		WithMarginFigures(true),
+		WithMaxHeadingLevel(2),
+	},
+}
+
+// TufteBook is the preset of Tufte books, typeset with the tufte-book class
+// like TufteHandout, top level headings being chapters.
+var TufteBook = Preset{
+	Name:      "tufte-book",
+	Preamble:  tuftePreamble("tufte-book"),
+	Conflicts: tufteConflicts,
+	Authors:   writeTufteAuthors,
+	Options: []Option{
+		WithMakeTitle(true),
+		WithFootnoteCommand("sidenote"),
+		WithMarginFigures(true),
+		WithChapters(true),
+		WithMaxHeadingLevel(3),
+	},
+}
+
+// WithMarginFigures typesets the images of the margin class, ?class=margin, in
+// the margin with the marginfigure environment, which the Tufte classes define.
+func WithMarginFigures(enabled bool) Option {
+	return func(r *Renderer) {
+		r.MarginFigures = enabled
+	}
+}
@@ -0,0 +1,124 @@
+package latex
+
+import (
+	"bytes"
+	"hash/maphash"
+	"io"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark/util"
+)
+
+// UnicodeCache caches the \DeclareUnicodeCharacter declarations of sources,
+// so that rendering a source again, e.g. in a service or in watch mode, does
+// not scan it again. It is safe for concurrent use, by renderers with the same
+// unicode characters mapping. See WithUnicodeCache.
+type UnicodeCache struct {
+	mu      sync.Mutex
+	seed    maphash.Seed
+	size    int
+	entries map[unicodeKey][]byte
+	keys    []unicodeKey // Oldest first.
+}
+
+// unicodeKey identifies a source by its hash and length.
+type unicodeKey struct {
+	hash   uint64
+	length int
+}
+
+// NewUnicodeCache returns a cache of the declarations of the last size sources.
+func NewUnicodeCache(size int) *UnicodeCache {
+	return &UnicodeCache{
+		seed:    maphash.MakeSeed(),
+		size:    size,
+		entries: make(map[unicodeKey][]byte),
+	}
+}
+
+// WithUnicodeCache caches the declarations of the characters mapped with
+// WithUnicodeCharactersMapping in cache, which can be shared by renderers.
+func WithUnicodeCache(cache *UnicodeCache) Option {
+	return func(r *Renderer) {
+		r.UnicodeCache = cache
+	}
+}
+
+// declarations returns the declarations of text, computed with declare if
+// they are not cached.
+func (c *UnicodeCache) declarations(text []byte, declare func([]byte) []byte) []byte {
+	key := unicodeKey{maphash.Bytes(c.seed, text), len(text)}
+	c.mu.Lock()
+	decls, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok {
+		return decls
+	}
+	decls = declare(text)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; ok || c.size <= 0 {
+		return decls
+	}
+	if len(c.keys) >= c.size {
+		delete(c.entries, c.keys[0])
+		c.keys = c.keys[1:]
+	}
+	c.entries[key] = decls
+	c.keys = append(c.keys, key)
+	return decls
+}
+
+// writeUnicodeDeclarations declares the non-ASCII characters of text mapped
+// by DeclareUnicode. Nothing is scanned when there is no mapping or the
+// engine reads unicode.
+func (r *Renderer) writeUnicodeDeclarations(w util.BufWriter, text []byte) {
+	if r.DeclareUnicode == nil || r.Engine.IsUnicode() {
+		return
+	}
+	_ = w.WriteByte('\n')
+	if r.UnicodeCache == nil {
+		r.declareUnicode(w, text)
+		return
+	}
+	_, _ = w.Write(r.UnicodeCache.declarations(text, func(text []byte) []byte {
+		var b bytes.Buffer
+		r.declareUnicode(&b, text)
+		return b.Bytes()
+	}))
+}
+
+// declareUnicode writes the declarations of the characters of text, each
+// once, skipping ASCII runs without decoding them.
+func (r *Renderer) declareUnicode(w io.StringWriter, text []byte) {
+	declared := getRuneSet()
+	defer putRuneSet(declared)
+	for i := 0; i < len(text); {
+		if text[i] < utf8.RuneSelf {
+			i++
+			continue
+		}
+		char, lchar := utf8.DecodeRune(text[i:])
+		i += lchar
+		if lchar == 1 {
+			continue // Invalid UTF-8, decoded as U+FFFD.
+		}
+		if _, ok := declared[char]; ok {
+			continue
+		}
+		declared[char] = struct{}{}
+		replace, ok := r.DeclareUnicode(char)
+		if !ok {
+			continue
+		}
+		num := strconv.FormatUint(uint64(char), 16)
+		_, _ = w.WriteString("\\DeclareUnicodeCharacter{")
+		_, _ = w.WriteString("0000"[:max(0, 4-len(num))])
+		_, _ = w.WriteString(num)
+		_, _ = w.WriteString("}{")
+		_, _ = w.WriteString(replace)
+		_, _ = w.WriteString("}\n")
+	}
+}
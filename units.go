@@ -0,0 +1,100 @@
+package latex
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// KindQuantity is the NodeKind of Quantity nodes.
+var KindQuantity = ast.NewNodeKind("Quantity")
+
+// Quantity is a number with an optional unit, typeset with siunitx.
+type Quantity struct {
+	ast.BaseInline
+	Number, Unit []byte
+}
+
+// Kind implements ast.Node.Kind.
+func (n *Quantity) Kind() ast.NodeKind {
+	return KindQuantity
+}
+
+// Dump implements ast.Node.Dump.
+func (n *Quantity) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Number": string(n.Number), "Unit": string(n.Unit)}, nil)
+}
+
+// NewQuantity returns a new Quantity node.
+func NewQuantity(number, unit []byte) *Quantity {
+	return &Quantity{Number: number, Unit: unit}
+}
+
+// quantity matches the content of `qty:...` code spans: a number followed by a unit
+// written with . or · for products, / for quotients and ^ for powers.
+var quantity = regexp.MustCompile(`^qty:\s*([-+]?[0-9][0-9.,]*(?:[eE][-+]?[0-9]+)?(?:\s*(?:±|\+-)\s*[0-9][0-9.,]*)?)(?:\s+([A-Za-z%][A-Za-z0-9%.·/^\-]*))?$`)
+
+type quantityParser struct{}
+
+// NewQuantityParser returns a parser of quantities written as code spans with the qty:
+// marker, e.g. `qty:9.81 m/s^2` or `qty:5 kg·m/s^2`. Other code spans are left to
+// the code span parser, which must have a lower priority, i.e. a greater number.
+func NewQuantityParser() parser.InlineParser {
+	return &quantityParser{}
+}
+
+func (p *quantityParser) Trigger() []byte {
+	return []byte{'`'}
+}
+
+func (p *quantityParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	if len(line) < 2 || line[1] == '`' {
+		return nil
+	}
+	end := bytes.IndexByte(line[1:], '`')
+	if end < 0 {
+		return nil
+	}
+	m := quantity.FindSubmatch(line[1 : end+1])
+	if m == nil {
+		return nil
+	}
+	block.Advance(end + 2)
+	return NewQuantity(m[1], m[2])
+}
+
+// quantityReplacer turns quantities into siunitx input.
+var quantityReplacer = strings.NewReplacer("·", ".", "%", "\\%", "±", "+-")
+
+// renderQuantity writes a quantity with \qty, or a number without unit with \num.
+func (r *Renderer) renderQuantity(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*Quantity)
+	number := quantityReplacer.Replace(string(n.Number))
+	if len(n.Unit) == 0 {
+		_, _ = w.WriteString("\\num{" + number + "}")
+	} else {
+		_, _ = w.WriteString("\\qty{" + number + "}{" + quantityReplacer.Replace(string(n.Unit)) + "}")
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+type unitsExtension struct{}
+
+// Units is a goldmark extension parsing quantities written as `qty:9.81 m/s^2`
+// code spans, which are typeset with the siunitx package. The package is loaded
+// in the preamble when the document contains quantities.
+var Units goldmark.Extender = &unitsExtension{}
+
+func (e *unitsExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithInlineParsers(util.Prioritized(NewQuantityParser(), 50)))
+}
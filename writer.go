@@ -0,0 +1,70 @@
+package latex
+
+import (
+	"strings"
+
+	"github.com/yuin/goldmark/util"
+)
+
+// Writer writes the text of documents to the LaTeX output, as goldmark's
+// html.Writer does for HTML, so that escaping and sanitization can be
+// customized without reimplementing the node renderers. See WithWriter.
+type Writer interface {
+	// Write writes text, escaping the characters special to LaTeX.
+	Write(writer util.BufWriter, source []byte)
+	// RawWrite writes raw LaTeX as is.
+	RawWrite(writer util.BufWriter, source []byte)
+	// SecureWrite writes verbatim content, such as code block lines, without
+	// escaping but replacing the characters TeX cannot read, such as NUL.
+	SecureWrite(writer util.BufWriter, source []byte)
+	// EscapeString returns the text escaped as Write writes it, for command
+	// arguments built as strings.
+	EscapeString(s string) string
+}
+
+type defaultWriter struct{}
+
+// DefaultWriter is the Writer used by default.
+var DefaultWriter Writer = &defaultWriter{}
+
+// NewWriter returns a new Writer escaping the characters special to LaTeX.
+func NewWriter() Writer {
+	return &defaultWriter{}
+}
+
+// WithWriter sets the Writer of the text of documents, DefaultWriter by default.
+func WithWriter(writer Writer) Option {
+	return func(r *Renderer) {
+		r.Writer = writer
+	}
+}
+
+func (d *defaultWriter) Write(writer util.BufWriter, source []byte) {
+	escapeLaTeX(writer, source)
+}
+
+func (d *defaultWriter) RawWrite(writer util.BufWriter, source []byte) {
+	_, _ = writer.Write(source)
+}
+
+func (d *defaultWriter) SecureWrite(writer util.BufWriter, source []byte) {
+	start := 0
+	for i, c := range source {
+		if c == 0 {
+			_, _ = writer.Write(source[start:i])
+			_, _ = writer.WriteString("�")
+			start = i + 1
+		}
+	}
+	_, _ = writer.Write(source[start:])
+}
+
+func (d *defaultWriter) EscapeString(s string) string {
+	if !needsEscape(s) {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s) + 16)
+	escapeLaTeXString(&b, s)
+	return b.String()
+}